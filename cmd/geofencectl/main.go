@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const usage = `usage:
+  %[1]s import <file.geojson|file.kml>
+  %[1]s export <geojson|kml> [file]
+  %[1]s report <geofence_id> <start RFC3339> <end RFC3339>
+
+FLEET_API_URL sets the server address (default http://localhost:8080).
+`
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Fprintf(os.Stderr, usage, os.Args[0])
+		os.Exit(1)
+	}
+
+	apiURL := "http://localhost:8080"
+	if v := os.Getenv("FLEET_API_URL"); v != "" {
+		apiURL = strings.TrimRight(v, "/")
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = importFile(client, apiURL, os.Args[2])
+	case "export":
+		out := ""
+		if len(os.Args) > 3 {
+			out = os.Args[3]
+		}
+		err = exportFile(client, apiURL, os.Args[2], out)
+	case "report":
+		if len(os.Args) < 5 {
+			fmt.Fprintf(os.Stderr, usage, os.Args[0])
+			os.Exit(1)
+		}
+		err = runReport(client, apiURL, os.Args[2], os.Args[3], os.Args[4])
+	default:
+		fmt.Fprintf(os.Stderr, usage, os.Args[0])
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatalf("%s: %v", os.Args[1], err)
+	}
+}
+
+func importFile(client *http.Client, apiURL, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	format := "geojson"
+	if strings.EqualFold(filepath.Ext(path), ".kml") {
+		format = "kml"
+	}
+
+	resp, err := client.Post(apiURL+"/geofences/import?format="+format, "application/octet-stream", f)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server responded %s: %s", resp.Status, body)
+	}
+
+	log.Printf("imported %s: %s", path, body)
+	return nil
+}
+
+func exportFile(client *http.Client, apiURL, format, path string) error {
+	resp, err := client.Get(apiURL + "/geofences/export?format=" + format)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server responded %s: %s", resp.Status, body)
+	}
+
+	if path == "" {
+		_, err = io.Copy(os.Stdout, resp.Body)
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("exported geofences to %s", path)
+	return nil
+}
+
+func runReport(client *http.Client, apiURL, geofenceID, startArg, endArg string) error {
+	start, err := time.Parse(time.RFC3339, startArg)
+	if err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, endArg)
+	if err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"geofence_id": geofenceID,
+		"start":       start.Unix(),
+		"end":         end.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(apiURL+"/admin/visit-reports", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("server responded %s: %s", resp.Status, body)
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	_, err = out.WriteTo(os.Stdout)
+	return err
+}
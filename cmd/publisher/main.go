@@ -10,13 +10,54 @@ import (
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nandanugg/tj-test/proto/fleetv1"
 )
 
 type locationMessage struct {
-	VehicleID string  `json:"vehicle_id"`
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
-	Timestamp int64   `json:"timestamp"`
+	VehicleID  string  `json:"vehicle_id"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	Timestamp  int64   `json:"timestamp"`
+	Speed      float64 `json:"speed"`
+	Heading    float64 `json:"heading"`
+	Satellites int     `json:"satellites"`
+	Ignition   bool    `json:"ignition"`
+}
+
+// encoders maps a PAYLOAD_FORMAT to its encoder. Every format except JSON is
+// published on the location topic with the format appended.
+var encoders = map[string]func(msg *locationMessage) ([]byte, error){
+	"json":     encodeJSON,
+	"protobuf": encodeProtobuf,
+	"cbor":     encodeCBOR,
+}
+
+func encodeJSON(msg *locationMessage) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// encodeProtobuf encodes msg as the Location message of proto/location.proto.
+func encodeProtobuf(msg *locationMessage) ([]byte, error) {
+	return proto.Marshal(&fleetv1.Location{
+		VehicleId:  msg.VehicleID,
+		Latitude:   msg.Latitude,
+		Longitude:  msg.Longitude,
+		Timestamp:  msg.Timestamp,
+		Speed:      &msg.Speed,
+		Heading:    &msg.Heading,
+		Satellites: proto.Int32(int32(msg.Satellites)),
+		Ignition:   &msg.Ignition,
+	})
+}
+
+func encodeCBOR(msg *locationMessage) ([]byte, error) {
+	var b []byte
+	var h codec.CborHandle
+	err := codec.NewEncoderBytes(&b, &h).Encode(msg)
+	return b, err
 }
 
 const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
@@ -53,6 +94,16 @@ func main() {
 		broker = v
 	}
 
+	format := "json"
+	if v := os.Getenv("PAYLOAD_FORMAT"); v != "" {
+		format = v
+	}
+	encode, ok := encoders[format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "error: PAYLOAD_FORMAT must be one of json, protobuf, cbor\n")
+		os.Exit(1)
+	}
+
 	opts := mqtt.NewClientOptions().
 		AddBroker(broker).
 		SetClientID("fleet-mock-publisher")
@@ -68,7 +119,7 @@ func main() {
 		vehiclePool[i] = randomVehicleID()
 	}
 
-	log.Printf("connected to %s, publishing every %ds...", broker, intervalSec)
+	log.Printf("connected to %s, publishing %s every %ds...", broker, format, intervalSec)
 	log.Printf("vehicle pool: %v", vehiclePool)
 
 	ticker := time.NewTicker(time.Duration(intervalSec) * time.Second)
@@ -88,18 +139,32 @@ func main() {
 		}
 
 		msg := locationMessage{
-			VehicleID: vid,
-			Latitude:  lat,
-			Longitude: lon,
-			Timestamp: time.Now().Unix(),
+			VehicleID:  vid,
+			Latitude:   lat,
+			Longitude:  lon,
+			Timestamp:  time.Now().Unix(),
+			Speed:      float64(rand.Intn(800)) / 10,
+			Heading:    float64(rand.Intn(3600)) / 10,
+			Satellites: 4 + rand.Intn(9),
+			Ignition:   rand.Float64() < 0.9,
 		}
 
-		payload, _ := json.Marshal(msg)
+		payload, err := encode(&msg)
+		if err != nil {
+			log.Fatalf("encode %s: %v", format, err)
+		}
 		topic := fmt.Sprintf("/fleet/vehicle/%s/location", vid)
+		if format != "json" {
+			topic += "/" + format
+		}
 
 		token := client.Publish(topic, 1, false, payload)
 		token.Wait()
 
-		log.Printf("published to %s: %s", topic, payload)
+		if format == "json" {
+			log.Printf("published to %s: %s", topic, payload)
+		} else {
+			log.Printf("published to %s: %d bytes", topic, len(payload))
+		}
 	}
 }
@@ -1,15 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"expvar"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // geofence schedules need time zones; the runtime image has no zoneinfo
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/nandanugg/tj-test/config"
 	"github.com/nandanugg/tj-test/module/core"
-	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/service"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	cfg := config.Load()
 
@@ -31,11 +42,29 @@ func main() {
 	}
 	defer mqttClient.Disconnect(250)
 
-	geofences := []domain.GeoPoint{
-		{Lat: -6.2088, Lon: 106.8456, Radius: 50},
-	}
-
-	coreModule, err := core.Build(db, amqpConn, mqttClient, geofences)
+	coreModule, err := core.Build(db, amqpConn, mqttClient, core.Options{
+		Geofence: service.GeofenceOptions{
+			EnterBuffer: cfg.GeofenceEnterBuffer,
+			ExitBuffer:  cfg.GeofenceExitBuffer,
+			MinFixes:    cfg.GeofenceMinFixes,
+			MinDuration: cfg.GeofenceMinDuration,
+		},
+		Location: service.LocationOptions{
+			BatchSize:     cfg.LocationBatchSize,
+			FlushInterval: cfg.LocationFlushInterval,
+			QueueSize:     cfg.LocationQueueSize,
+			LateTolerance: cfg.LocationLateTolerance,
+		},
+		Ingest: core.IngestOptions{
+			Workers:   cfg.IngestWorkers,
+			QueueSize: cfg.IngestQueueSize,
+			Overflow:  cfg.IngestOverflow,
+		},
+		Tracker: core.TrackerOptions{
+			GT06Addr:      cfg.TrackerGT06Addr,
+			TeltonikaAddr: cfg.TrackerTeltonikaAddr,
+		},
+	})
 	if err != nil {
 		log.Fatalf("core module: %v", err)
 	}
@@ -48,11 +77,29 @@ func main() {
 
 	health := config.NewHealthChecker(db, amqpConn, mqttClient)
 	health.Register(r)
+	r.GET("/debug/vars", gin.WrapH(expvar.Handler()))
 
 	coreModule.RegisterRoutes(&r.RouterGroup)
 
-	log.Printf("listening on :%s", cfg.HTTPPort)
-	if err := r.Run(":" + cfg.HTTPPort); err != nil {
-		log.Fatalf("server: %v", err)
+	srv := &http.Server{Addr: ":" + cfg.HTTPPort, Handler: r}
+	go func() {
+		log.Printf("listening on :%s", cfg.HTTPPort)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("server: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	log.Printf("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("http shutdown: %v", err)
+	}
+	if err := coreModule.Close(shutdownCtx); err != nil {
+		log.Printf("core module shutdown: %v", err)
 	}
 }
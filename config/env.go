@@ -1,6 +1,11 @@
 package config
 
-import "os"
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
 
 type Config struct {
 	PostgresDSN  string
@@ -8,6 +13,23 @@ type Config struct {
 	MQTTBroker   string
 	MQTTClientID string
 	HTTPPort     string
+
+	GeofenceEnterBuffer float64
+	GeofenceExitBuffer  float64
+	GeofenceMinFixes    int
+	GeofenceMinDuration time.Duration
+
+	LocationBatchSize     int
+	LocationFlushInterval time.Duration
+	LocationQueueSize     int
+	LocationLateTolerance time.Duration
+
+	IngestWorkers   int
+	IngestQueueSize int
+	IngestOverflow  string
+
+	TrackerGT06Addr      string
+	TrackerTeltonikaAddr string
 }
 
 func Load() *Config {
@@ -17,6 +39,23 @@ func Load() *Config {
 		MQTTBroker:   getEnv("MQTT_BROKER", "tcp://localhost:1883"),
 		MQTTClientID: getEnv("MQTT_CLIENT_ID", "fleet-server"),
 		HTTPPort:     getEnv("HTTP_PORT", "8080"),
+
+		GeofenceEnterBuffer: getEnvFloat("GEOFENCE_ENTER_BUFFER_METERS", 0),
+		GeofenceExitBuffer:  getEnvFloat("GEOFENCE_EXIT_BUFFER_METERS", 10),
+		GeofenceMinFixes:    getEnvInt("GEOFENCE_MIN_FIXES", 1),
+		GeofenceMinDuration: getEnvDuration("GEOFENCE_MIN_DURATION", 0),
+
+		LocationBatchSize:     getEnvInt("LOCATION_BATCH_SIZE", 500),
+		LocationFlushInterval: getEnvDuration("LOCATION_FLUSH_INTERVAL", 200*time.Millisecond),
+		LocationQueueSize:     getEnvInt("LOCATION_QUEUE_SIZE", 10000),
+		LocationLateTolerance: getEnvDuration("LOCATION_LATE_TOLERANCE", 0),
+
+		IngestWorkers:   getEnvInt("INGEST_WORKERS", 8),
+		IngestQueueSize: getEnvInt("INGEST_QUEUE_SIZE", 256),
+		IngestOverflow:  getEnv("INGEST_OVERFLOW", "block"),
+
+		TrackerGT06Addr:      getEnv("TRACKER_GT06_ADDR", ""),
+		TrackerTeltonikaAddr: getEnv("TRACKER_TELTONIKA_ADDR", ""),
 	}
 }
 
@@ -26,3 +65,42 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvFloat(key string, fallback float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("invalid %s %q, using %v", key, v, fallback)
+		return fallback
+	}
+	return f
+}
+
+func getEnvInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("invalid %s %q, using %v", key, v, fallback)
+		return fallback
+	}
+	return n
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("invalid %s %q, using %v", key, v, fallback)
+		return fallback
+	}
+	return d
+}
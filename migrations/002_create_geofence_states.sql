@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS geofence_states (
+    vehicle_id VARCHAR(50) NOT NULL,
+    geofence_id VARCHAR(64) NOT NULL,
+    inside BOOLEAN NOT NULL,
+    entered_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (vehicle_id, geofence_id)
+);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS geofences (
+    id VARCHAR(64) PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    shape VARCHAR(16) NOT NULL,
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    radius DOUBLE PRECISION,
+    polygons JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO geofences (id, shape, latitude, longitude, radius)
+VALUES ('jakarta-center', 'circle', -6.2088, 106.8456, 50)
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE geofence_states
+    ADD CONSTRAINT fk_geofence_states_geofence_id
+    FOREIGN KEY (geofence_id) REFERENCES geofences (id) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+ALTER TABLE geofences
+    ADD COLUMN IF NOT EXISTS name VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE geofences SET name = 'Jakarta Center' WHERE id = 'jakarta-center' AND name = '';
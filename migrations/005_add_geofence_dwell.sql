@@ -0,0 +1,5 @@
+ALTER TABLE geofences
+    ADD COLUMN IF NOT EXISTS dwell_seconds INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE geofence_states
+    ADD COLUMN IF NOT EXISTS dwell_alerted BOOLEAN NOT NULL DEFAULT FALSE;
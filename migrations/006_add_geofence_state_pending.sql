@@ -0,0 +1,4 @@
+ALTER TABLE geofence_states
+    ALTER COLUMN entered_at DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS pending_fixes INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS pending_since TIMESTAMPTZ;
@@ -0,0 +1,11 @@
+ALTER TABLE geofences
+    ADD COLUMN IF NOT EXISTS vehicle_ids TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS group_ids TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS route_ids TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS vehicle_assignments (
+    vehicle_id VARCHAR(50) PRIMARY KEY,
+    group_ids TEXT[] NOT NULL DEFAULT '{}',
+    route_ids TEXT[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+ALTER TABLE geofences
+    ADD COLUMN IF NOT EXISTS schedule JSONB;
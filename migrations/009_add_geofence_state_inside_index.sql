@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_geofence_states_geofence_id_inside
+    ON geofence_states (geofence_id, entered_at) WHERE inside;
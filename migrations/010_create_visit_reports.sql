@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS visit_reports (
+    id BIGSERIAL PRIMARY KEY,
+    geofence_id VARCHAR(64) NOT NULL REFERENCES geofences (id) ON DELETE CASCADE,
+    range_start TIMESTAMPTZ NOT NULL,
+    range_end TIMESTAMPTZ NOT NULL,
+    vehicles_scanned INTEGER NOT NULL,
+    locations_scanned INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS visit_report_visits (
+    report_id BIGINT NOT NULL REFERENCES visit_reports (id) ON DELETE CASCADE,
+    vehicle_id VARCHAR(50) NOT NULL,
+    entered_at TIMESTAMPTZ NOT NULL,
+    exited_at TIMESTAMPTZ,
+    duration_seconds BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_visit_report_visits_report_id
+    ON visit_report_visits (report_id, entered_at);
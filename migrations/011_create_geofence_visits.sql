@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS geofence_visits (
+    id BIGSERIAL PRIMARY KEY,
+    vehicle_id VARCHAR(50) NOT NULL,
+    geofence_id VARCHAR(64) NOT NULL,
+    entered_at TIMESTAMPTZ NOT NULL,
+    exited_at TIMESTAMPTZ NOT NULL,
+    duration_seconds BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_geofence_visits_geofence_id_entered_at
+    ON geofence_visits (geofence_id, entered_at);
+
+CREATE INDEX IF NOT EXISTS idx_geofence_visits_vehicle_id_entered_at
+    ON geofence_visits (vehicle_id, entered_at);
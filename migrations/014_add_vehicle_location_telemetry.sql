@@ -0,0 +1,8 @@
+ALTER TABLE vehicle_locations
+    ADD COLUMN IF NOT EXISTS speed DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS heading DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS altitude DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS accuracy DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS satellites SMALLINT,
+    ADD COLUMN IF NOT EXISTS ignition BOOLEAN,
+    ADD COLUMN IF NOT EXISTS odometer DOUBLE PRECISION;
@@ -0,0 +1,10 @@
+DELETE FROM vehicle_locations a
+    USING vehicle_locations b
+    WHERE a.vehicle_id = b.vehicle_id
+      AND a.timestamp = b.timestamp
+      AND a.id > b.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vehicle_locations_vehicle_id_timestamp_unique
+    ON vehicle_locations (vehicle_id, timestamp DESC);
+
+DROP INDEX IF EXISTS idx_vehicle_locations_vehicle_id_timestamp;
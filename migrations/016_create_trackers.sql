@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS trackers (
+    imei TEXT PRIMARY KEY,
+    vehicle_id VARCHAR(50) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,11 @@
+DELETE FROM geofence_visits a
+    USING geofence_visits b
+    WHERE a.vehicle_id = b.vehicle_id
+      AND a.geofence_id = b.geofence_id
+      AND a.entered_at = b.entered_at
+      AND a.id > b.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_geofence_visits_vehicle_id_entered_at_unique
+    ON geofence_visits (vehicle_id, entered_at, geofence_id);
+
+DROP INDEX IF EXISTS idx_geofence_visits_vehicle_id_entered_at;
@@ -1,54 +1,149 @@
 package core
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/gin-gonic/gin"
 	amqp "github.com/rabbitmq/amqp091-go"
 
-	"github.com/nandanugg/tj-test/module/core/domain"
 	handler "github.com/nandanugg/tj-test/module/core/internal/handler/http"
 	"github.com/nandanugg/tj-test/module/core/internal/handler/subscriber"
+	"github.com/nandanugg/tj-test/module/core/internal/handler/tracker"
 	"github.com/nandanugg/tj-test/module/core/internal/repository/database/postgres"
 	"github.com/nandanugg/tj-test/module/core/internal/repository/publisher/rabbitmq"
 	"github.com/nandanugg/tj-test/module/core/service"
 )
 
+type Options struct {
+	Geofence service.GeofenceOptions
+	Location service.LocationOptions
+	Ingest   IngestOptions
+	Tracker  TrackerOptions
+}
+
+// IngestOptions controls the worker pool that processes MQTT messages.
+type IngestOptions struct {
+	Workers   int
+	QueueSize int
+	// Overflow is "block" or "drop_oldest"; empty means block.
+	Overflow string
+}
+
+// TrackerOptions sets the TCP addresses of the GPS tracker gateway, one per
+// protocol. An empty address disables that protocol.
+type TrackerOptions struct {
+	GT06Addr      string
+	TeltonikaAddr string
+}
+
 type Module struct {
-	LocationSvc *service.LocationService
-	GeofenceSvc *service.GeofenceService
-	handler     *handler.VehicleHandler
-	subscriber  *subscriber.LocationSubscriber
+	LocationSvc       *service.LocationService
+	GeofenceSvc       *service.GeofenceService
+	ReportSvc         *service.ReportService
+	DeadLetterSvc     *service.DeadLetterService
+	TrackerSvc        *service.TrackerService
+	handler           *handler.VehicleHandler
+	geofenceHandler   *handler.GeofenceHandler
+	reportHandler     *handler.ReportHandler
+	deadLetterHandler *handler.DeadLetterHandler
+	trackerHandler    *handler.TrackerHandler
+	subscriber        *subscriber.LocationSubscriber
+	trackerServer     *tracker.Server
 }
 
-func Build(db *sql.DB, amqpConn *amqp.Connection, mqttClient mqtt.Client, geofences []domain.GeoPoint) (*Module, error) {
+func Build(db *sql.DB, amqpConn *amqp.Connection, mqttClient mqtt.Client, opts Options) (*Module, error) {
 	locationRepo := postgres.NewLocationRepo(db)
+	geofenceRepo := postgres.NewGeofenceRepo(db)
+	geofenceStateRepo := postgres.NewGeofenceStateRepo(db)
+	assignmentRepo := postgres.NewVehicleAssignmentRepo(db)
+	visitRepo := postgres.NewGeofenceVisitRepo(db)
+	reportRepo := postgres.NewVisitReportRepo(db)
+	deadLetterRepo := postgres.NewDeadLetterRepo(db)
+	trackerRepo := postgres.NewTrackerRepo(db)
+
+	overflow, err := subscriber.ParseOverflowPolicy(opts.Ingest.Overflow)
+	if err != nil {
+		return nil, fmt.Errorf("ingest options: %w", err)
+	}
 
 	geofencePub, err := rabbitmq.NewGeofencePublisher(amqpConn)
 	if err != nil {
 		return nil, fmt.Errorf("geofence publisher: %w", err)
 	}
 
-	locationSvc := service.NewLocationService(locationRepo)
-	geofenceSvc := service.NewGeofenceService(geofencePub, geofences)
+	locationSvc := service.NewLocationService(locationRepo, opts.Location)
+	geofenceSvc := service.NewGeofenceService(geofencePub, geofenceRepo, geofenceStateRepo, assignmentRepo, visitRepo, opts.Geofence)
+	if err := geofenceSvc.Reload(context.Background()); err != nil {
+		return nil, fmt.Errorf("geofence service: %w", err)
+	}
+	reportSvc := service.NewReportService(locationRepo, geofenceRepo, assignmentRepo, reportRepo, opts.Geofence)
+	deadLetterSvc := service.NewDeadLetterService(deadLetterRepo)
+	trackerSvc := service.NewTrackerService(trackerRepo)
 
 	h := handler.NewVehicleHandler(locationSvc)
-	sub := subscriber.NewLocationSubscriber(mqttClient, locationSvc, geofenceSvc)
+	gh := handler.NewGeofenceHandler(geofenceSvc)
+	rh := handler.NewReportHandler(reportSvc)
+	sub := subscriber.NewLocationSubscriber(mqttClient, locationSvc, geofenceSvc, deadLetterSvc, subscriber.PoolOptions{
+		Workers:   opts.Ingest.Workers,
+		QueueSize: opts.Ingest.QueueSize,
+		Overflow:  overflow,
+	})
+	dh := handler.NewDeadLetterHandler(deadLetterSvc, sub)
+	th := handler.NewTrackerHandler(trackerSvc)
+	ts := tracker.NewServer(locationSvc, geofenceSvc, trackerSvc, deadLetterSvc, tracker.Options{
+		GT06Addr:      opts.Tracker.GT06Addr,
+		TeltonikaAddr: opts.Tracker.TeltonikaAddr,
+	})
 
 	return &Module{
-		LocationSvc: locationSvc,
-		GeofenceSvc: geofenceSvc,
-		handler:     h,
-		subscriber:  sub,
+		LocationSvc:       locationSvc,
+		GeofenceSvc:       geofenceSvc,
+		ReportSvc:         reportSvc,
+		DeadLetterSvc:     deadLetterSvc,
+		TrackerSvc:        trackerSvc,
+		handler:           h,
+		geofenceHandler:   gh,
+		reportHandler:     rh,
+		deadLetterHandler: dh,
+		trackerHandler:    th,
+		subscriber:        sub,
+		trackerServer:     ts,
 	}, nil
 }
 
 func (m *Module) RegisterRoutes(r *gin.RouterGroup) {
 	m.handler.Register(r)
+	m.geofenceHandler.Register(r)
+	m.reportHandler.Register(r)
+	m.deadLetterHandler.Register(r)
+	m.trackerHandler.Register(r)
 }
 
+// StartSubscribers subscribes to MQTT and opens the tracker gateway.
 func (m *Module) StartSubscribers() error {
-	return m.subscriber.Start()
+	if err := m.subscriber.Start(); err != nil {
+		return err
+	}
+	return m.trackerServer.Start()
+}
+
+// Close stops consuming locations, lets the workers finish the queued ones
+// and writes any that are still buffered. Every step runs even if an earlier
+// one fails, so buffered locations are still written.
+func (m *Module) Close(ctx context.Context) error {
+	var errs []error
+	if err := m.subscriber.Stop(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("stop subscribers: %w", err))
+	}
+	if err := m.trackerServer.Stop(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("stop tracker gateway: %w", err))
+	}
+	if err := m.LocationSvc.Close(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("close location writer: %w", err))
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// ErrMessageRejected wraps the reason an inbound message could not be decoded
+// or failed validation.
+var ErrMessageRejected = errors.New("message rejected")
+
+// DeadLetter is an inbound message that was rejected, kept with its raw
+// payload so it can be inspected and reprocessed.
+type DeadLetter struct {
+	ID         int64     `json:"id"`
+	Topic      string    `json:"topic"`
+	Payload    []byte    `json:"payload"`
+	Reason     string    `json:"reason"`
+	ReceivedAt time.Time `json:"received_at"`
+}
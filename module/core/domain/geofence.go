@@ -1,21 +1,166 @@
 package domain
 
+import (
+	"errors"
+	"time"
+)
+
+var ErrGeofenceNotFound = errors.New("geofence not found")
+
+// ErrGeofenceExists is returned when a geofence is created with an ID that is
+// already taken.
+var ErrGeofenceExists = errors.New("geofence already exists")
+
 type GeoPoint struct {
 	Lat    float64 `json:"latitude"`
 	Lon    float64 `json:"longitude"`
 	Radius float64 `json:"radius"`
 }
 
+type Coordinate struct {
+	Lat float64 `json:"latitude"`
+	Lon float64 `json:"longitude"`
+}
+
+// Corridor is the area within Buffer meters of a polyline, used for bus
+// routes. Line needs at least two points.
+type Corridor struct {
+	Line   []Coordinate `json:"line"`
+	Buffer float64      `json:"buffer"`
+}
+
+// Polygon is a list of linear rings. The first ring is the outer boundary and
+// any following rings are holes cut out of it.
+type Polygon [][]Coordinate
+
+type GeofenceShape string
+
+const (
+	ShapeCircle   GeofenceShape = "circle"
+	ShapePolygon  GeofenceShape = "polygon"
+	ShapeCorridor GeofenceShape = "corridor"
+)
+
+// Geofence is a named area vehicles are checked against. A circle fence uses
+// Circle; a polygon fence uses Polygons, where more than one entry makes it a
+// multi-polygon; a corridor fence uses Corridor. A non-zero DwellThreshold
+// enables geofence_dwell alerts and a non-nil Schedule limits the fence to
+// recurring time windows.
+type Geofence struct {
+	ID             string             `json:"id"`
+	Name           string             `json:"name"`
+	Tags           []string           `json:"tags,omitempty"`
+	Shape          GeofenceShape      `json:"shape"`
+	Circle         *GeoPoint          `json:"circle,omitempty"`
+	Polygons       []Polygon          `json:"polygons,omitempty"`
+	Corridor       *Corridor          `json:"corridor,omitempty"`
+	DwellThreshold time.Duration      `json:"dwell_threshold,omitempty"`
+	Assignment     GeofenceAssignment `json:"assignment"`
+	Schedule       *Schedule          `json:"schedule,omitempty"`
+}
+
+// Schedule makes a geofence active only during its windows, interpreted in
+// the IANA Timezone (UTC when empty). Outside every window the geofence
+// behaves as if the vehicle were outside it.
+type Schedule struct {
+	Timezone string           `json:"timezone"`
+	Windows  []ScheduleWindow `json:"windows"`
+}
+
+// ScheduleWindow is active from Start to End, both offsets from local
+// midnight, on each of Days (every day when empty). A window whose End is not
+// after its Start runs past midnight into the following day.
+type ScheduleWindow struct {
+	Days  []time.Weekday `json:"days,omitempty"`
+	Start time.Duration  `json:"start"`
+	End   time.Duration  `json:"end"`
+}
+
+// GeofenceAssignment scopes a geofence to the listed vehicles, vehicle groups
+// and routes. A vehicle matching any of them is checked against the fence; an
+// empty assignment applies the fence to every vehicle.
+type GeofenceAssignment struct {
+	VehicleIDs []string `json:"vehicle_ids,omitempty"`
+	GroupIDs   []string `json:"group_ids,omitempty"`
+	RouteIDs   []string `json:"route_ids,omitempty"`
+}
+
+func (a GeofenceAssignment) IsGlobal() bool {
+	return len(a.VehicleIDs) == 0 && len(a.GroupIDs) == 0 && len(a.RouteIDs) == 0
+}
+
 type GeofenceEventType string
 
 const (
 	GeofenceEntry GeofenceEventType = "geofence_entry"
 	GeofenceExit  GeofenceEventType = "geofence_exit"
+	GeofenceDwell GeofenceEventType = "geofence_dwell"
+	// RouteDeviation replaces geofence_exit when a vehicle leaves a corridor
+	// it is assigned to.
+	RouteDeviation GeofenceEventType = "route_deviation"
 )
 
+// GeofenceAlert carries the geofence that fired and the vehicle's distance in
+// meters from its centre (the centreline for corridors) at the time of the
+// event. Dwell is only set for geofence_dwell events.
 type GeofenceAlert struct {
 	VehicleID string            `json:"vehicle_id"`
 	Event     GeofenceEventType `json:"event"`
+	Geofence  Geofence          `json:"geofence"`
+	Distance  float64           `json:"distance"`
+	Dwell     time.Duration     `json:"dwell,omitempty"`
 	Location  Location          `json:"location"`
 	Timestamp int64             `json:"timestamp"`
 }
+
+// GeofenceState is the last known inside/outside status of a vehicle
+// relative to a single geofence. Alerts are only published when it flips.
+// PendingFixes and PendingSince track a transition that has been observed but
+// not yet confirmed by the debounce rules.
+type GeofenceState struct {
+	VehicleID    string    `json:"vehicle_id"`
+	GeofenceID   string    `json:"geofence_id"`
+	Inside       bool      `json:"inside"`
+	EnteredAt    time.Time `json:"entered_at"`
+	DwellAlerted bool      `json:"dwell_alerted"`
+	PendingFixes int       `json:"pending_fixes"`
+	PendingSince time.Time `json:"pending_since"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// GeofenceVisit is one stay of a vehicle inside a geofence, from a confirmed
+// entry to a confirmed exit. ExitedAt is zero for a visit that had not ended,
+// in which case Duration runs to the last location seen.
+type GeofenceVisit struct {
+	VehicleID  string        `json:"vehicle_id"`
+	GeofenceID string        `json:"geofence_id"`
+	EnteredAt  time.Time     `json:"entered_at"`
+	ExitedAt   time.Time     `json:"exited_at"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// VisitQuery selects stored visits that started between Start and End. An
+// empty VehicleID or GeofenceID matches every vehicle or geofence.
+type VisitQuery struct {
+	VehicleID  string
+	GeofenceID string
+	Start      time.Time
+	End        time.Time
+}
+
+// GeofencePresence is a vehicle currently inside a geofence and when it
+// entered.
+type GeofencePresence struct {
+	VehicleID string    `json:"vehicle_id"`
+	Geofence  Geofence  `json:"geofence"`
+	EnteredAt time.Time `json:"entered_at"`
+}
+
+// SimulationStep is what the geofence service would do for one point of a
+// simulated track: the geofences containing the point and the alerts it would
+// publish.
+type SimulationStep struct {
+	Location Location        `json:"location"`
+	Inside   []string        `json:"inside"`
+	Alerts   []GeofenceAlert `json:"alerts"`
+}
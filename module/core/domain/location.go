@@ -1,6 +1,18 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrDuplicateLocation is returned when a vehicle's location for the same
+// timestamp has already been saved, typically an MQTT QoS 1 redelivery.
+var ErrDuplicateLocation = errors.New("duplicate location")
+
+// ErrLocationRejected is returned when the database refuses a location for
+// its data, such as a value out of range or a violated constraint, rather
+// than because it is unavailable. Retrying cannot store it.
+var ErrLocationRejected = errors.New("location rejected")
 
 type Location struct {
 	Lat       float64   `json:"latitude"`
@@ -8,9 +20,22 @@ type Location struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// Telemetry holds the optional readings a device may report along with a
+// fix. A nil field was not reported.
+type Telemetry struct {
+	Speed      *float64 `json:"speed,omitempty"`    // km/h
+	Heading    *float64 `json:"heading,omitempty"`  // degrees clockwise from true north
+	Altitude   *float64 `json:"altitude,omitempty"` // meters above sea level
+	Accuracy   *float64 `json:"accuracy,omitempty"` // estimated horizontal error in meters
+	Satellites *int     `json:"satellites,omitempty"`
+	Ignition   *bool    `json:"ignition,omitempty"`
+	Odometer   *float64 `json:"odometer,omitempty"` // km
+}
+
 type VehicleLocation struct {
-	VehicleID string   `json:"vehicle_id"`
-	Location  Location `json:"location"`
+	VehicleID string    `json:"vehicle_id"`
+	Location  Location  `json:"location"`
+	Telemetry Telemetry `json:"telemetry"`
 }
 
 type HistoryQuery struct {
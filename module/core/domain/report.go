@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrReportNotFound = errors.New("visit report not found")
+
+// VisitReport holds the visits to a geofence reconstructed from stored
+// location history between Start and End.
+type VisitReport struct {
+	ID               int64           `json:"id"`
+	GeofenceID       string          `json:"geofence_id"`
+	Start            time.Time       `json:"start"`
+	End              time.Time       `json:"end"`
+	VehiclesScanned  int             `json:"vehicles_scanned"`
+	LocationsScanned int             `json:"locations_scanned"`
+	Visits           []GeofenceVisit `json:"visits"`
+	CreatedAt        time.Time       `json:"created_at"`
+}
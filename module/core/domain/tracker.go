@@ -0,0 +1,12 @@
+package domain
+
+import "errors"
+
+var ErrTrackerNotFound = errors.New("tracker not found")
+
+// Tracker maps a GPS tracker that connects over TCP, identified by its IMEI,
+// to the vehicle it is installed in.
+type Tracker struct {
+	IMEI      string `json:"imei"`
+	VehicleID string `json:"vehicle_id"`
+}
@@ -1,5 +1,39 @@
 package domain
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrVehicleNotFound = errors.New("vehicle not found")
+
+// MaxVehicleIDLength is the limit of the VARCHAR(50) vehicle_id columns, in
+// characters.
+const MaxVehicleIDLength = 50
+
+// ValidateVehicleID checks that id fits the vehicle_id columns and can name
+// the vehicle's MQTT topic, so it must not contain '/', '+' or '#'. An empty
+// id passes; callers that require one check for it themselves.
+func ValidateVehicleID(id string) error {
+	if utf8.RuneCountInString(id) > MaxVehicleIDLength {
+		return fmt.Errorf("must be at most %d characters", MaxVehicleIDLength)
+	}
+	if strings.ContainsAny(id, "/+#") {
+		return fmt.Errorf("must not contain '/', '+' or '#'")
+	}
+	return nil
+}
+
 type Vehicle struct {
 	VehicleID string `json:"vehicle_id"`
 }
+
+// VehicleAssignment lists the groups and routes a vehicle belongs to, which
+// decide the scoped geofences it is checked against.
+type VehicleAssignment struct {
+	VehicleID string   `json:"vehicle_id"`
+	GroupIDs  []string `json:"group_ids"`
+	RouteIDs  []string `json:"route_ids"`
+}
@@ -0,0 +1,78 @@
+// Package geoformat converts geofence definitions to and from the GeoJSON and
+// KML files maintained by GIS tools.
+//
+// Feature properties map onto geofences as follows: id, name, tags, radius
+// (meters, turns a point into a circle), buffer (meters, turns a line into a
+// corridor) and dwell_seconds. Assignments and schedules have no
+// representation in either format.
+package geoformat
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+type Format string
+
+const (
+	GeoJSON Format = "geojson"
+	KML     Format = "kml"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case GeoJSON, KML:
+		return Format(s), nil
+	default:
+		return "", fmt.Errorf("unsupported format %q, must be one of geojson, kml", s)
+	}
+}
+
+func (f Format) ContentType() string {
+	if f == KML {
+		return "application/vnd.google-earth.kml+xml"
+	}
+	return "application/geo+json"
+}
+
+func (f Format) Extension() string {
+	if f == KML {
+		return ".kml"
+	}
+	return ".geojson"
+}
+
+// Decode reads every geofence in r. Features are not validated beyond what is
+// needed to build the geofence; callers apply the usual geofence validation.
+func Decode(f Format, r io.Reader) ([]domain.Geofence, error) {
+	if f == KML {
+		return decodeKML(r)
+	}
+	return decodeGeoJSON(r)
+}
+
+func Encode(f Format, w io.Writer, geofences []domain.Geofence) error {
+	if f == KML {
+		return encodeKML(w, geofences)
+	}
+	return encodeGeoJSON(w, geofences)
+}
+
+// openRing drops the closing vertex both formats repeat at the end of a ring.
+func openRing(ring []domain.Coordinate) []domain.Coordinate {
+	if n := len(ring); n > 1 && ring[0] == ring[n-1] {
+		return ring[:n-1]
+	}
+	return ring
+}
+
+// closeRing repeats the first vertex at the end of a ring if it is not there
+// already.
+func closeRing(ring []domain.Coordinate) []domain.Coordinate {
+	if n := len(ring); n == 0 || ring[0] == ring[n-1] {
+		return ring
+	}
+	return append(ring[:len(ring):len(ring)], ring[0])
+}
@@ -0,0 +1,268 @@
+package geoformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string         `json:"type"`
+	ID         any            `json:"id,omitempty"`
+	Geometry   *geometry      `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+func decodeGeoJSON(r io.Reader) ([]domain.Geofence, error) {
+	var fc featureCollection
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return nil, fmt.Errorf("decode geojson: %w", err)
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("decode geojson: expected a FeatureCollection, got %q", fc.Type)
+	}
+
+	geofences := make([]domain.Geofence, len(fc.Features))
+	for i := range fc.Features {
+		gf, err := featureToGeofence(&fc.Features[i])
+		if err != nil {
+			return nil, fmt.Errorf("features[%d]: %w", i, err)
+		}
+		geofences[i] = *gf
+	}
+	return geofences, nil
+}
+
+func featureToGeofence(f *feature) (*domain.Geofence, error) {
+	props := f.Properties
+	gf := &domain.Geofence{
+		ID:   stringProperty(f.ID),
+		Name: stringProperty(props["name"]),
+	}
+	if gf.ID == "" {
+		gf.ID = stringProperty(props["id"])
+	}
+
+	tags, err := tagsProperty(props["tags"])
+	if err != nil {
+		return nil, err
+	}
+	gf.Tags = tags
+
+	if v, ok := props["dwell_seconds"]; ok {
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("dwell_seconds: must be a number")
+		}
+		gf.DwellThreshold = time.Duration(n) * time.Second
+	}
+
+	if f.Geometry == nil {
+		return nil, fmt.Errorf("geometry: required")
+	}
+	switch f.Geometry.Type {
+	case "Point":
+		var pt []float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &pt); err != nil || len(pt) < 2 {
+			return nil, fmt.Errorf("geometry: invalid Point coordinates")
+		}
+		radius, ok := props["radius"].(float64)
+		if !ok {
+			return nil, fmt.Errorf("radius: a numeric radius property is required for Point features")
+		}
+		gf.Shape = domain.ShapeCircle
+		gf.Circle = &domain.GeoPoint{Lat: pt[1], Lon: pt[0], Radius: radius}
+	case "LineString":
+		var line [][]float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &line); err != nil {
+			return nil, fmt.Errorf("geometry: invalid LineString coordinates")
+		}
+		buffer, ok := props["buffer"].(float64)
+		if !ok {
+			return nil, fmt.Errorf("buffer: a numeric buffer property is required for LineString features")
+		}
+		coords, err := toCoordinates(line)
+		if err != nil {
+			return nil, err
+		}
+		gf.Shape = domain.ShapeCorridor
+		gf.Corridor = &domain.Corridor{Line: coords, Buffer: buffer}
+	case "Polygon":
+		var rings [][][]float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &rings); err != nil {
+			return nil, fmt.Errorf("geometry: invalid Polygon coordinates")
+		}
+		polygon, err := toPolygon(rings)
+		if err != nil {
+			return nil, err
+		}
+		gf.Shape = domain.ShapePolygon
+		gf.Polygons = []domain.Polygon{polygon}
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &polygons); err != nil {
+			return nil, fmt.Errorf("geometry: invalid MultiPolygon coordinates")
+		}
+		gf.Shape = domain.ShapePolygon
+		for _, rings := range polygons {
+			polygon, err := toPolygon(rings)
+			if err != nil {
+				return nil, err
+			}
+			gf.Polygons = append(gf.Polygons, polygon)
+		}
+	default:
+		return nil, fmt.Errorf("geometry: unsupported type %q, must be Point, LineString, Polygon or MultiPolygon", f.Geometry.Type)
+	}
+	return gf, nil
+}
+
+func toPolygon(rings [][][]float64) (domain.Polygon, error) {
+	polygon := make(domain.Polygon, len(rings))
+	for i, ring := range rings {
+		coords, err := toCoordinates(ring)
+		if err != nil {
+			return nil, err
+		}
+		polygon[i] = openRing(coords)
+	}
+	return polygon, nil
+}
+
+func toCoordinates(positions [][]float64) ([]domain.Coordinate, error) {
+	coords := make([]domain.Coordinate, len(positions))
+	for i, pt := range positions {
+		if len(pt) < 2 {
+			return nil, fmt.Errorf("geometry: position needs longitude and latitude")
+		}
+		coords[i] = domain.Coordinate{Lat: pt[1], Lon: pt[0]}
+	}
+	return coords, nil
+}
+
+func encodeGeoJSON(w io.Writer, geofences []domain.Geofence) error {
+	fc := featureCollection{Type: "FeatureCollection", Features: make([]feature, len(geofences))}
+	for i := range geofences {
+		gf := &geofences[i]
+		props := map[string]any{
+			"name": gf.Name,
+			"tags": nonNilTags(gf.Tags),
+		}
+		if gf.DwellThreshold > 0 {
+			props["dwell_seconds"] = int64(gf.DwellThreshold / time.Second)
+		}
+
+		f := feature{Type: "Feature", ID: gf.ID, Properties: props}
+		switch {
+		case gf.Circle != nil:
+			props["radius"] = gf.Circle.Radius
+			f.Geometry = &geometry{Type: "Point", Coordinates: mustMarshal([]float64{gf.Circle.Lon, gf.Circle.Lat})}
+		case gf.Corridor != nil:
+			props["buffer"] = gf.Corridor.Buffer
+			line := make([][]float64, len(gf.Corridor.Line))
+			for j, pt := range gf.Corridor.Line {
+				line[j] = []float64{pt.Lon, pt.Lat}
+			}
+			f.Geometry = &geometry{Type: "LineString", Coordinates: mustMarshal(line)}
+		case len(gf.Polygons) == 1:
+			f.Geometry = &geometry{Type: "Polygon", Coordinates: mustMarshal(fromPolygon(gf.Polygons[0]))}
+		default:
+			polygons := make([][][][]float64, len(gf.Polygons))
+			for j, polygon := range gf.Polygons {
+				polygons[j] = fromPolygon(polygon)
+			}
+			f.Geometry = &geometry{Type: "MultiPolygon", Coordinates: mustMarshal(polygons)}
+		}
+		fc.Features[i] = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(fc)
+}
+
+func fromPolygon(polygon domain.Polygon) [][][]float64 {
+	rings := make([][][]float64, len(polygon))
+	for i, ring := range polygon {
+		for _, pt := range closeRing(ring) {
+			rings[i] = append(rings[i], []float64{pt.Lon, pt.Lat})
+		}
+	}
+	return rings
+}
+
+// mustMarshal encodes coordinate slices, which cannot fail to marshal.
+func mustMarshal(v any) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// stringProperty accepts the string or numeric identifiers GeoJSON allows.
+func stringProperty(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// tagsProperty accepts tags as a list of strings or a comma-separated string,
+// the form QGIS attribute tables produce.
+func tagsProperty(v any) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return splitTags(v), nil
+	case []any:
+		tags := make([]string, 0, len(v))
+		for _, t := range v {
+			s, ok := t.(string)
+			if !ok {
+				return nil, fmt.Errorf("tags: must be strings")
+			}
+			tags = append(tags, s)
+		}
+		return tags, nil
+	default:
+		return nil, fmt.Errorf("tags: must be a list or a comma-separated string")
+	}
+}
+
+func splitTags(s string) []string {
+	var tags []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
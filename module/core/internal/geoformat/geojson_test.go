@@ -0,0 +1,168 @@
+package geoformat
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+const sampleGeoJSON = `{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "id": "blok-m",
+      "geometry": {"type": "Point", "coordinates": [106.8017, -6.2443]},
+      "properties": {"name": "Blok M", "tags": "terminal, south", "radius": 80, "dwell_seconds": 600}
+    },
+    {
+      "type": "Feature",
+      "geometry": {
+        "type": "Polygon",
+        "coordinates": [
+          [[106.84, -6.21], [106.85, -6.21], [106.85, -6.20], [106.84, -6.20], [106.84, -6.21]],
+          [[106.844, -6.206], [106.846, -6.206], [106.846, -6.204], [106.844, -6.206]]
+        ]
+      },
+      "properties": {"id": 42, "name": "Depot", "tags": ["depot"]}
+    },
+    {
+      "type": "Feature",
+      "geometry": {
+        "type": "MultiPolygon",
+        "coordinates": [
+          [[[106.80, -6.10], [106.81, -6.10], [106.81, -6.11], [106.80, -6.10]]],
+          [[[106.90, -6.10], [106.91, -6.10], [106.91, -6.11], [106.90, -6.10]]]
+        ]
+      },
+      "properties": {"name": "Workshops"}
+    },
+    {
+      "type": "Feature",
+      "id": "koridor-1",
+      "geometry": {"type": "LineString", "coordinates": [[106.8228, -6.1754], [106.8229, -6.1862], [106.8017, -6.2443]]},
+      "properties": {"name": "Koridor 1", "buffer": 25}
+    }
+  ]
+}`
+
+func TestDecodeGeoJSON(t *testing.T) {
+	geofences, err := Decode(GeoJSON, strings.NewReader(sampleGeoJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(geofences) != 4 {
+		t.Fatalf("expected 4 geofences, got %d", len(geofences))
+	}
+
+	circle := geofences[0]
+	if circle.ID != "blok-m" || circle.Name != "Blok M" || circle.Shape != domain.ShapeCircle {
+		t.Errorf("unexpected circle: %+v", circle)
+	}
+	if circle.Circle == nil || circle.Circle.Lat != -6.2443 || circle.Circle.Lon != 106.8017 || circle.Circle.Radius != 80 {
+		t.Errorf("unexpected circle geometry: %+v", circle.Circle)
+	}
+	if len(circle.Tags) != 2 || circle.Tags[1] != "south" || circle.DwellThreshold != 10*time.Minute {
+		t.Errorf("unexpected properties: %+v", circle)
+	}
+
+	polygon := geofences[1]
+	if polygon.ID != "42" || len(polygon.Polygons) != 1 || len(polygon.Polygons[0]) != 2 {
+		t.Fatalf("unexpected polygon: %+v", polygon)
+	}
+	if len(polygon.Polygons[0][0]) != 4 || polygon.Polygons[0][0][1] != (domain.Coordinate{Lat: -6.21, Lon: 106.85}) {
+		t.Errorf("expected closed ring to be opened and lon/lat swapped, got %+v", polygon.Polygons[0][0])
+	}
+
+	if multi := geofences[2]; multi.ID != "" || len(multi.Polygons) != 2 {
+		t.Errorf("unexpected multi-polygon: %+v", multi)
+	}
+
+	corridor := geofences[3]
+	if corridor.Shape != domain.ShapeCorridor || corridor.Corridor == nil || corridor.Corridor.Buffer != 25 || len(corridor.Corridor.Line) != 3 {
+		t.Fatalf("unexpected corridor: %+v", corridor)
+	}
+	if corridor.Corridor.Line[0] != (domain.Coordinate{Lat: -6.1754, Lon: 106.8228}) {
+		t.Errorf("expected lon/lat swapped, got %+v", corridor.Corridor.Line[0])
+	}
+}
+
+func TestDecodeGeoJSON_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"not a collection", `{"type": "Feature"}`},
+		{"point without radius", `{"type": "FeatureCollection", "features": [{"type": "Feature", "geometry": {"type": "Point", "coordinates": [106.8, -6.2]}, "properties": {}}]}`},
+		{"line without buffer", `{"type": "FeatureCollection", "features": [{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[106.8, -6.2], [106.9, -6.2]]}}]}`},
+		{"unsupported geometry", `{"type": "FeatureCollection", "features": [{"type": "Feature", "geometry": {"type": "MultiLineString", "coordinates": [[[106.8, -6.2], [106.9, -6.2]]]}}]}`},
+		{"missing geometry", `{"type": "FeatureCollection", "features": [{"type": "Feature", "properties": {"name": "x"}}]}`},
+		{"bad tags", `{"type": "FeatureCollection", "features": [{"type": "Feature", "geometry": {"type": "Point", "coordinates": [106.8, -6.2]}, "properties": {"radius": 5, "tags": [1]}}]}`},
+		{"invalid json", `{"type": `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(GeoJSON, strings.NewReader(tt.doc)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestGeoJSON_RoundTrip(t *testing.T) {
+	geofences, err := Decode(GeoJSON, strings.NewReader(sampleGeoJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(GeoJSON, &buf, geofences); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"type": "MultiPolygon"`) {
+		t.Errorf("expected MultiPolygon geometry in output:\n%s", buf.String())
+	}
+
+	again, err := Decode(GeoJSON, &buf)
+	if err != nil {
+		t.Fatalf("decode exported: %v", err)
+	}
+	assertSameGeofences(t, geofences, again)
+}
+
+func assertSameGeofences(t *testing.T, want, got []domain.Geofence) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d geofences, got %d", len(want), len(got))
+	}
+	for i := range want {
+		w, g := want[i], got[i]
+		if g.ID != w.ID || g.Name != w.Name || g.Shape != w.Shape || g.DwellThreshold != w.DwellThreshold {
+			t.Errorf("[%d] expected %+v, got %+v", i, w, g)
+		}
+		if strings.Join(g.Tags, ",") != strings.Join(w.Tags, ",") {
+			t.Errorf("[%d] expected tags %v, got %v", i, w.Tags, g.Tags)
+		}
+		if (w.Circle == nil) != (g.Circle == nil) || (w.Circle != nil && *w.Circle != *g.Circle) {
+			t.Errorf("[%d] expected circle %+v, got %+v", i, w.Circle, g.Circle)
+		}
+		if (w.Corridor == nil) != (g.Corridor == nil) ||
+			(w.Corridor != nil && (w.Corridor.Buffer != g.Corridor.Buffer || !slices.Equal(w.Corridor.Line, g.Corridor.Line))) {
+			t.Errorf("[%d] expected corridor %+v, got %+v", i, w.Corridor, g.Corridor)
+		}
+		if len(g.Polygons) != len(w.Polygons) {
+			t.Fatalf("[%d] expected %d polygons, got %d", i, len(w.Polygons), len(g.Polygons))
+		}
+		for j := range w.Polygons {
+			for k := range w.Polygons[j] {
+				if len(g.Polygons[j][k]) != len(w.Polygons[j][k]) {
+					t.Errorf("[%d] polygon %d ring %d: expected %d vertices, got %d", i, j, k, len(w.Polygons[j][k]), len(g.Polygons[j][k]))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package geoformat
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+const kmlNamespace = "http://www.opengis.net/kml/2.2"
+
+// kmlContainer matches the kml root, Document and Folder elements, any of
+// which can hold placemarks.
+type kmlContainer struct {
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+	Folders    []kmlContainer `xml:"Folder"`
+	Documents  []kmlContainer `xml:"Document"`
+}
+
+type kmlPlacemark struct {
+	ID            string            `xml:"id,attr,omitempty"`
+	Name          string            `xml:"name"`
+	ExtendedData  *kmlExtendedData  `xml:"ExtendedData,omitempty"`
+	Point         *kmlPoint         `xml:"Point,omitempty"`
+	LineString    *kmlLineString    `xml:"LineString,omitempty"`
+	Polygon       *kmlPolygon       `xml:"Polygon,omitempty"`
+	MultiGeometry *kmlMultiGeometry `xml:"MultiGeometry,omitempty"`
+}
+
+// kmlExtendedData holds a placemark's attributes, as Data elements the way
+// Google Earth writes them or as SchemaData the way QGIS and OGR do.
+type kmlExtendedData struct {
+	Data       []kmlData       `xml:"Data"`
+	SchemaData []kmlSchemaData `xml:"SchemaData"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlSchemaData struct {
+	SimpleData []kmlSimpleData `xml:"SimpleData"`
+}
+
+type kmlSimpleData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlLineString struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlPolygon struct {
+	Outer kmlBoundary   `xml:"outerBoundaryIs"`
+	Inner []kmlBoundary `xml:"innerBoundaryIs"`
+}
+
+type kmlBoundary struct {
+	Coordinates string `xml:"LinearRing>coordinates"`
+}
+
+type kmlMultiGeometry struct {
+	Polygons []kmlPolygon `xml:"Polygon"`
+}
+
+type kmlDocument struct {
+	XMLName  xml.Name `xml:"kml"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Document struct {
+		Placemarks []kmlPlacemark `xml:"Placemark"`
+	} `xml:"Document"`
+}
+
+func decodeKML(r io.Reader) ([]domain.Geofence, error) {
+	var root kmlContainer
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, fmt.Errorf("decode kml: %w", err)
+	}
+
+	var geofences []domain.Geofence
+	for i, pm := range collectPlacemarks(&root, nil) {
+		gf, err := placemarkToGeofence(&pm)
+		if err != nil {
+			return nil, fmt.Errorf("placemarks[%d]: %w", i, err)
+		}
+		geofences = append(geofences, *gf)
+	}
+	return geofences, nil
+}
+
+// collectPlacemarks flattens nested documents and folders in document order.
+func collectPlacemarks(c *kmlContainer, dst []kmlPlacemark) []kmlPlacemark {
+	dst = append(dst, c.Placemarks...)
+	for i := range c.Documents {
+		dst = collectPlacemarks(&c.Documents[i], dst)
+	}
+	for i := range c.Folders {
+		dst = collectPlacemarks(&c.Folders[i], dst)
+	}
+	return dst
+}
+
+func placemarkToGeofence(pm *kmlPlacemark) (*domain.Geofence, error) {
+	data := make(map[string]string)
+	if pm.ExtendedData != nil {
+		for _, d := range pm.ExtendedData.Data {
+			data[d.Name] = strings.TrimSpace(d.Value)
+		}
+		for _, sd := range pm.ExtendedData.SchemaData {
+			for _, d := range sd.SimpleData {
+				data[d.Name] = strings.TrimSpace(d.Value)
+			}
+		}
+	}
+
+	gf := &domain.Geofence{
+		ID:   pm.ID,
+		Name: strings.TrimSpace(pm.Name),
+		Tags: splitTags(data["tags"]),
+	}
+	if id := data["id"]; id != "" {
+		gf.ID = id
+	}
+	if v, ok := data["dwell_seconds"]; ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("dwell_seconds: must be an integer")
+		}
+		gf.DwellThreshold = time.Duration(n) * time.Second
+	}
+
+	switch {
+	case pm.Point != nil:
+		coords, err := parseKMLCoordinates(pm.Point.Coordinates)
+		if err != nil || len(coords) != 1 {
+			return nil, fmt.Errorf("Point: invalid coordinates")
+		}
+		radius, err := strconv.ParseFloat(data["radius"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("radius: a numeric radius data field is required for Point placemarks")
+		}
+		gf.Shape = domain.ShapeCircle
+		gf.Circle = &domain.GeoPoint{Lat: coords[0].Lat, Lon: coords[0].Lon, Radius: radius}
+	case pm.LineString != nil:
+		coords, err := parseKMLCoordinates(pm.LineString.Coordinates)
+		if err != nil {
+			return nil, fmt.Errorf("LineString: %w", err)
+		}
+		buffer, err := strconv.ParseFloat(data["buffer"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("buffer: a numeric buffer data field is required for LineString placemarks")
+		}
+		gf.Shape = domain.ShapeCorridor
+		gf.Corridor = &domain.Corridor{Line: coords, Buffer: buffer}
+	case pm.Polygon != nil:
+		polygon, err := fromKMLPolygon(pm.Polygon)
+		if err != nil {
+			return nil, err
+		}
+		gf.Shape = domain.ShapePolygon
+		gf.Polygons = []domain.Polygon{polygon}
+	case pm.MultiGeometry != nil && len(pm.MultiGeometry.Polygons) > 0:
+		gf.Shape = domain.ShapePolygon
+		for i := range pm.MultiGeometry.Polygons {
+			polygon, err := fromKMLPolygon(&pm.MultiGeometry.Polygons[i])
+			if err != nil {
+				return nil, err
+			}
+			gf.Polygons = append(gf.Polygons, polygon)
+		}
+	default:
+		return nil, fmt.Errorf("geometry: a Point, LineString, Polygon or MultiGeometry of Polygons is required")
+	}
+	return gf, nil
+}
+
+func fromKMLPolygon(p *kmlPolygon) (domain.Polygon, error) {
+	outer, err := parseKMLCoordinates(p.Outer.Coordinates)
+	if err != nil {
+		return nil, fmt.Errorf("Polygon: %w", err)
+	}
+	polygon := domain.Polygon{openRing(outer)}
+	for _, b := range p.Inner {
+		hole, err := parseKMLCoordinates(b.Coordinates)
+		if err != nil {
+			return nil, fmt.Errorf("Polygon: %w", err)
+		}
+		polygon = append(polygon, openRing(hole))
+	}
+	return polygon, nil
+}
+
+// parseKMLCoordinates parses whitespace-separated lon,lat[,alt] tuples.
+func parseKMLCoordinates(s string) ([]domain.Coordinate, error) {
+	var coords []domain.Coordinate
+	for _, tuple := range strings.Fields(s) {
+		parts := strings.Split(tuple, ",")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid coordinate %q", tuple)
+		}
+		lon, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coordinate %q", tuple)
+		}
+		lat, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coordinate %q", tuple)
+		}
+		coords = append(coords, domain.Coordinate{Lat: lat, Lon: lon})
+	}
+	return coords, nil
+}
+
+func encodeKML(w io.Writer, geofences []domain.Geofence) error {
+	doc := kmlDocument{Xmlns: kmlNamespace}
+	doc.Document.Placemarks = make([]kmlPlacemark, len(geofences))
+	for i := range geofences {
+		gf := &geofences[i]
+		pm := kmlPlacemark{
+			ID:           gf.ID,
+			Name:         gf.Name,
+			ExtendedData: &kmlExtendedData{},
+		}
+		data := &pm.ExtendedData.Data
+		*data = append(*data, kmlData{Name: "tags", Value: strings.Join(gf.Tags, ",")})
+		if gf.DwellThreshold > 0 {
+			*data = append(*data, kmlData{Name: "dwell_seconds", Value: strconv.FormatInt(int64(gf.DwellThreshold/time.Second), 10)})
+		}
+
+		switch {
+		case gf.Circle != nil:
+			*data = append(*data, kmlData{Name: "radius", Value: strconv.FormatFloat(gf.Circle.Radius, 'f', -1, 64)})
+			pm.Point = &kmlPoint{Coordinates: formatKMLCoordinates([]domain.Coordinate{{Lat: gf.Circle.Lat, Lon: gf.Circle.Lon}})}
+		case gf.Corridor != nil:
+			*data = append(*data, kmlData{Name: "buffer", Value: strconv.FormatFloat(gf.Corridor.Buffer, 'f', -1, 64)})
+			pm.LineString = &kmlLineString{Coordinates: formatKMLCoordinates(gf.Corridor.Line)}
+		case len(gf.Polygons) == 1:
+			pm.Polygon = toKMLPolygon(gf.Polygons[0])
+		default:
+			pm.MultiGeometry = &kmlMultiGeometry{}
+			for _, polygon := range gf.Polygons {
+				pm.MultiGeometry.Polygons = append(pm.MultiGeometry.Polygons, *toKMLPolygon(polygon))
+			}
+		}
+		doc.Document.Placemarks[i] = pm
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func toKMLPolygon(polygon domain.Polygon) *kmlPolygon {
+	p := &kmlPolygon{}
+	for i, ring := range polygon {
+		b := kmlBoundary{Coordinates: formatKMLCoordinates(closeRing(ring))}
+		if i == 0 {
+			p.Outer = b
+			continue
+		}
+		p.Inner = append(p.Inner, b)
+	}
+	return p
+}
+
+func formatKMLCoordinates(coords []domain.Coordinate) string {
+	tuples := make([]string, len(coords))
+	for i, c := range coords {
+		tuples[i] = strconv.FormatFloat(c.Lon, 'f', -1, 64) + "," + strconv.FormatFloat(c.Lat, 'f', -1, 64)
+	}
+	return strings.Join(tuples, " ")
+}
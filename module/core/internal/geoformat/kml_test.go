@@ -0,0 +1,191 @@
+package geoformat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+const sampleKML = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+  <Document>
+    <name>Fences</name>
+    <Placemark id="blok-m">
+      <name>Blok M</name>
+      <ExtendedData>
+        <Data name="tags"><value>terminal,south</value></Data>
+        <Data name="radius"><value>80</value></Data>
+        <Data name="dwell_seconds"><value>600</value></Data>
+      </ExtendedData>
+      <Point><coordinates>106.8017,-6.2443,0</coordinates></Point>
+    </Placemark>
+    <Folder>
+      <name>Depots</name>
+      <Placemark>
+        <name>Depot</name>
+        <Polygon>
+          <outerBoundaryIs><LinearRing><coordinates>
+            106.84,-6.21,0 106.85,-6.21,0 106.85,-6.20,0 106.84,-6.20,0 106.84,-6.21,0
+          </coordinates></LinearRing></outerBoundaryIs>
+          <innerBoundaryIs><LinearRing><coordinates>
+            106.844,-6.206 106.846,-6.206 106.846,-6.204 106.844,-6.206
+          </coordinates></LinearRing></innerBoundaryIs>
+        </Polygon>
+      </Placemark>
+      <Placemark>
+        <name>Workshops</name>
+        <MultiGeometry>
+          <Polygon><outerBoundaryIs><LinearRing><coordinates>106.80,-6.10 106.81,-6.10 106.81,-6.11</coordinates></LinearRing></outerBoundaryIs></Polygon>
+          <Polygon><outerBoundaryIs><LinearRing><coordinates>106.90,-6.10 106.91,-6.10 106.91,-6.11</coordinates></LinearRing></outerBoundaryIs></Polygon>
+        </MultiGeometry>
+      </Placemark>
+    </Folder>
+    <Placemark id="koridor-1">
+      <name>Koridor 1</name>
+      <ExtendedData><Data name="buffer"><value>25</value></Data></ExtendedData>
+      <LineString><coordinates>106.8228,-6.1754 106.8229,-6.1862 106.8017,-6.2443</coordinates></LineString>
+    </Placemark>
+  </Document>
+</kml>`
+
+func TestDecodeKML(t *testing.T) {
+	geofences, err := Decode(KML, strings.NewReader(sampleKML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(geofences) != 4 {
+		t.Fatalf("expected 4 geofences, got %d", len(geofences))
+	}
+
+	circle := geofences[0]
+	if circle.ID != "blok-m" || circle.Name != "Blok M" || circle.Circle == nil || circle.Circle.Radius != 80 || circle.Circle.Lat != -6.2443 {
+		t.Errorf("unexpected circle: %+v", circle)
+	}
+	if len(circle.Tags) != 2 || circle.DwellThreshold != 10*time.Minute {
+		t.Errorf("unexpected properties: %+v", circle)
+	}
+
+	depot := geofences[2]
+	if depot.Shape != domain.ShapePolygon || len(depot.Polygons) != 1 || len(depot.Polygons[0]) != 2 || len(depot.Polygons[0][0]) != 4 {
+		t.Errorf("unexpected depot polygon: %+v", depot.Polygons)
+	}
+	if len(geofences[3].Polygons) != 2 {
+		t.Errorf("expected multi-polygon, got %+v", geofences[3].Polygons)
+	}
+
+	// placemarks directly in the document come before those in folders
+	corridor := geofences[1]
+	if corridor.ID != "koridor-1" || corridor.Corridor == nil || corridor.Corridor.Buffer != 25 || len(corridor.Corridor.Line) != 3 {
+		t.Errorf("unexpected corridor: %+v", corridor)
+	}
+}
+
+// ogrKML is laid out the way QGIS and ogr2ogr export a layer, with the
+// attributes as SchemaData.
+const ogrKML = `<?xml version="1.0" encoding="utf-8" ?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document id="root_doc">
+<Schema name="geofences" id="geofences">
+	<SimpleField name="id" type="string"></SimpleField>
+	<SimpleField name="tags" type="string"></SimpleField>
+	<SimpleField name="radius" type="float"></SimpleField>
+	<SimpleField name="buffer" type="float"></SimpleField>
+	<SimpleField name="dwell_seconds" type="int"></SimpleField>
+</Schema>
+<Folder><name>geofences</name>
+  <Placemark>
+	<name>Blok M</name>
+	<ExtendedData><SchemaData schemaUrl="#geofences">
+		<SimpleData name="id">blok-m</SimpleData>
+		<SimpleData name="tags">terminal,south</SimpleData>
+		<SimpleData name="radius">80</SimpleData>
+		<SimpleData name="dwell_seconds">600</SimpleData>
+	</SchemaData></ExtendedData>
+      <Point><coordinates>106.8017,-6.2443</coordinates></Point>
+  </Placemark>
+  <Placemark>
+	<name>Koridor 1</name>
+	<ExtendedData><SchemaData schemaUrl="#geofences">
+		<SimpleData name="id">koridor-1</SimpleData>
+		<SimpleData name="buffer">25.5</SimpleData>
+	</SchemaData></ExtendedData>
+      <LineString><coordinates>106.8228,-6.1754 106.8229,-6.1862 106.8017,-6.2443</coordinates></LineString>
+  </Placemark>
+</Folder>
+</Document></kml>`
+
+func TestDecodeKML_SchemaData(t *testing.T) {
+	geofences, err := Decode(KML, strings.NewReader(ogrKML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(geofences) != 2 {
+		t.Fatalf("expected 2 geofences, got %d", len(geofences))
+	}
+
+	circle := geofences[0]
+	if circle.ID != "blok-m" || circle.Name != "Blok M" || circle.Circle == nil || circle.Circle.Radius != 80 {
+		t.Errorf("unexpected circle: %+v", circle)
+	}
+	if len(circle.Tags) != 2 || circle.Tags[1] != "south" || circle.DwellThreshold != 10*time.Minute {
+		t.Errorf("unexpected properties: %+v", circle)
+	}
+
+	corridor := geofences[1]
+	if corridor.ID != "koridor-1" || corridor.Corridor == nil || corridor.Corridor.Buffer != 25.5 {
+		t.Errorf("unexpected corridor: %+v", corridor)
+	}
+}
+
+func TestDecodeKML_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"point without radius", `<kml><Placemark><Point><coordinates>106.8,-6.2</coordinates></Point></Placemark></kml>`},
+		{"line without buffer", `<kml><Placemark><LineString><coordinates>106.8,-6.2 106.9,-6.2</coordinates></LineString></Placemark></kml>`},
+		{"no geometry", `<kml><Placemark><name>x</name></Placemark></kml>`},
+		{"bad coordinates", `<kml><Placemark><Polygon><outerBoundaryIs><LinearRing><coordinates>a,b</coordinates></LinearRing></outerBoundaryIs></Polygon></Placemark></kml>`},
+		{"invalid xml", `<kml><Placemark>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(KML, strings.NewReader(tt.doc)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestKML_RoundTrip(t *testing.T) {
+	geofences, err := Decode(KML, strings.NewReader(sampleKML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(KML, &buf, geofences); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if !strings.Contains(buf.String(), `<kml xmlns="http://www.opengis.net/kml/2.2">`) {
+		t.Errorf("expected kml namespace in output:\n%s", buf.String())
+	}
+
+	again, err := Decode(KML, &buf)
+	if err != nil {
+		t.Fatalf("decode exported: %v", err)
+	}
+	assertSameGeofences(t, geofences, again)
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("kml"); err != nil || f != KML {
+		t.Errorf("expected kml, got %q %v", f, err)
+	}
+	if _, err := ParseFormat("shp"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+const (
+	defaultDeadLetterLimit = 100
+	maxDeadLetterLimit     = 1000
+)
+
+type deadLetterService interface {
+	ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id int64) (*domain.DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, id int64) error
+}
+
+// messageProcessor runs a raw inbound message through the ingestion path.
+type messageProcessor interface {
+	Reprocess(ctx context.Context, topic string, payload []byte) error
+}
+
+type deadLetterResponse struct {
+	ID    int64  `json:"id"`
+	Topic string `json:"topic"`
+	// Payload holds the raw message when it is valid UTF-8, PayloadBase64
+	// otherwise.
+	Payload       string `json:"payload,omitempty"`
+	PayloadBase64 []byte `json:"payload_base64,omitempty"`
+	Reason        string `json:"reason"`
+	ReceivedAt    int64  `json:"received_at"`
+}
+
+type DeadLetterHandler struct {
+	deadLetterSvc deadLetterService
+	processor     messageProcessor
+}
+
+func NewDeadLetterHandler(deadLetterSvc deadLetterService, processor messageProcessor) *DeadLetterHandler {
+	return &DeadLetterHandler{deadLetterSvc: deadLetterSvc, processor: processor}
+}
+
+func (h *DeadLetterHandler) Register(r *gin.RouterGroup) {
+	r.GET("/admin/dead-letters", h.ListDeadLetters)
+	r.POST("/admin/dead-letters/:id/reprocess", h.ReprocessDeadLetter)
+	r.DELETE("/admin/dead-letters/:id", h.DeleteDeadLetter)
+}
+
+func (h *DeadLetterHandler) ListDeadLetters(c *gin.Context) {
+	limit := defaultDeadLetterLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxDeadLetterLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit: must be between 1 and 1000"})
+			return
+		}
+		limit = n
+	}
+
+	dls, err := h.deadLetterSvc.ListDeadLetters(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch dead letters"})
+		return
+	}
+
+	results := make([]deadLetterResponse, len(dls))
+	for i := range dls {
+		results[i] = toDeadLetterResponse(&dls[i])
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// ReprocessDeadLetter feeds the stored message through ingestion again and
+// removes it once it is accepted. A message that is still rejected stays
+// stored and the new reason is returned.
+func (h *DeadLetterHandler) ReprocessDeadLetter(c *gin.Context) {
+	id, ok := parseDeadLetterID(c)
+	if !ok {
+		return
+	}
+
+	dl, err := h.deadLetterSvc.GetDeadLetter(c.Request.Context(), id)
+	if err != nil {
+		writeDeadLetterError(c, err, "failed to fetch dead letter")
+		return
+	}
+
+	if err := h.processor.Reprocess(c.Request.Context(), dl.Topic, dl.Payload); err != nil {
+		if errors.Is(err, domain.ErrMessageRejected) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reprocess dead letter"})
+		return
+	}
+
+	if err := h.deadLetterSvc.DeleteDeadLetter(c.Request.Context(), id); err != nil {
+		writeDeadLetterError(c, err, "failed to delete dead letter")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *DeadLetterHandler) DeleteDeadLetter(c *gin.Context) {
+	id, ok := parseDeadLetterID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.deadLetterSvc.DeleteDeadLetter(c.Request.Context(), id); err != nil {
+		writeDeadLetterError(c, err, "failed to delete dead letter")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func parseDeadLetterID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dead letter id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeDeadLetterError(c *gin.Context, err error, msg string) {
+	if errors.Is(err, domain.ErrDeadLetterNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dead letter not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+}
+
+func toDeadLetterResponse(dl *domain.DeadLetter) deadLetterResponse {
+	resp := deadLetterResponse{
+		ID:         dl.ID,
+		Topic:      dl.Topic,
+		Reason:     dl.Reason,
+		ReceivedAt: dl.ReceivedAt.Unix(),
+	}
+	if utf8.Valid(dl.Payload) {
+		resp.Payload = string(dl.Payload)
+	} else {
+		resp.PayloadBase64 = dl.Payload
+	}
+	return resp
+}
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+type mockDeadLetterService struct {
+	listFn   func(ctx context.Context, limit int) ([]domain.DeadLetter, error)
+	getFn    func(ctx context.Context, id int64) (*domain.DeadLetter, error)
+	deleteFn func(ctx context.Context, id int64) error
+}
+
+func (m *mockDeadLetterService) ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	return m.listFn(ctx, limit)
+}
+
+func (m *mockDeadLetterService) GetDeadLetter(ctx context.Context, id int64) (*domain.DeadLetter, error) {
+	return m.getFn(ctx, id)
+}
+
+func (m *mockDeadLetterService) DeleteDeadLetter(ctx context.Context, id int64) error {
+	return m.deleteFn(ctx, id)
+}
+
+type mockMessageProcessor struct {
+	reprocessFn func(ctx context.Context, topic string, payload []byte) error
+}
+
+func (m *mockMessageProcessor) Reprocess(ctx context.Context, topic string, payload []byte) error {
+	return m.reprocessFn(ctx, topic, payload)
+}
+
+func setupDeadLetterRouter(svc deadLetterService, proc messageProcessor) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := NewDeadLetterHandler(svc, proc)
+	h.Register(r.Group(""))
+	return r
+}
+
+func storedDeadLetter(_ context.Context, id int64) (*domain.DeadLetter, error) {
+	return &domain.DeadLetter{ID: id, Topic: "/fleet/vehicle/B1234XYZ/location", Payload: []byte(`{"vehicle_id":""}`), Reason: "vehicle_id: required"}, nil
+}
+
+func TestListDeadLetters_Success(t *testing.T) {
+	var gotLimit int
+	svc := &mockDeadLetterService{
+		listFn: func(_ context.Context, limit int) ([]domain.DeadLetter, error) {
+			gotLimit = limit
+			received := time.Unix(1715003456, 0)
+			return []domain.DeadLetter{
+				{ID: 2, Topic: "/fleet/vehicle/B1/location", Payload: []byte("oops"), Reason: "invalid json", ReceivedAt: received},
+				{ID: 1, Topic: "/fleet/vehicle/B2/location", Payload: []byte{0xff, 0x00}, Reason: "invalid json", ReceivedAt: received},
+			}, nil
+		},
+	}
+
+	r := setupDeadLetterRouter(svc, nil)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin/dead-letters", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if gotLimit != defaultDeadLetterLimit {
+		t.Errorf("expected default limit %d, got %d", defaultDeadLetterLimit, gotLimit)
+	}
+	want := `[{"id":2,"topic":"/fleet/vehicle/B1/location","payload":"oops","reason":"invalid json","received_at":1715003456},` +
+		`{"id":1,"topic":"/fleet/vehicle/B2/location","payload_base64":"/wA=","reason":"invalid json","received_at":1715003456}]`
+	if got := w.Body.String(); got != want {
+		t.Errorf("unexpected response:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestListDeadLetters_InvalidLimit(t *testing.T) {
+	for _, limit := range []string{"abc", "0", "1001"} {
+		r := setupDeadLetterRouter(&mockDeadLetterService{}, nil)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/dead-letters?limit="+limit, nil)
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("limit %s: expected 400, got %d", limit, w.Code)
+		}
+	}
+}
+
+func TestReprocessDeadLetter_Success(t *testing.T) {
+	var deleted int64
+	svc := &mockDeadLetterService{
+		getFn: storedDeadLetter,
+		deleteFn: func(_ context.Context, id int64) error {
+			deleted = id
+			return nil
+		},
+	}
+	var gotTopic string
+	proc := &mockMessageProcessor{
+		reprocessFn: func(_ context.Context, topic string, _ []byte) error {
+			gotTopic = topic
+			return nil
+		},
+	}
+
+	r := setupDeadLetterRouter(svc, proc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/dead-letters/5/reprocess", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if gotTopic != "/fleet/vehicle/B1234XYZ/location" {
+		t.Errorf("expected the stored topic, got %q", gotTopic)
+	}
+	if deleted != 5 {
+		t.Errorf("expected dead letter 5 to be deleted, got %d", deleted)
+	}
+}
+
+func TestReprocessDeadLetter_StillRejected(t *testing.T) {
+	svc := &mockDeadLetterService{
+		getFn: storedDeadLetter,
+		deleteFn: func(context.Context, int64) error {
+			t.Fatal("a rejected message must not be deleted")
+			return nil
+		},
+	}
+	proc := &mockMessageProcessor{
+		reprocessFn: func(context.Context, string, []byte) error {
+			return fmt.Errorf("%w: vehicle_id: required", domain.ErrMessageRejected)
+		},
+	}
+
+	r := setupDeadLetterRouter(svc, proc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/dead-letters/5/reprocess", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", w.Code, w.Body.String())
+	}
+	if want := `{"error":"message rejected: vehicle_id: required"}`; w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestReprocessDeadLetter_ProcessingError(t *testing.T) {
+	svc := &mockDeadLetterService{getFn: storedDeadLetter}
+	proc := &mockMessageProcessor{
+		reprocessFn: func(context.Context, string, []byte) error { return errors.New("db error") },
+	}
+
+	r := setupDeadLetterRouter(svc, proc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/dead-letters/5/reprocess", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+}
+
+func TestReprocessDeadLetter_NotFound(t *testing.T) {
+	svc := &mockDeadLetterService{
+		getFn: func(context.Context, int64) (*domain.DeadLetter, error) {
+			return nil, domain.ErrDeadLetterNotFound
+		},
+	}
+
+	r := setupDeadLetterRouter(svc, &mockMessageProcessor{})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/dead-letters/5/reprocess", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestDeleteDeadLetter_InvalidID(t *testing.T) {
+	r := setupDeadLetterRouter(&mockDeadLetterService{}, nil)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/admin/dead-letters/abc", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
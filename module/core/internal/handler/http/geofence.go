@@ -0,0 +1,706 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/geoformat"
+)
+
+type geofenceService interface {
+	ListGeofences(ctx context.Context) ([]domain.Geofence, error)
+	GetGeofence(ctx context.Context, id string) (*domain.Geofence, error)
+	CreateGeofence(ctx context.Context, gf *domain.Geofence) error
+	UpdateGeofence(ctx context.Context, gf *domain.Geofence) error
+	DeleteGeofence(ctx context.Context, id string) error
+	ImportGeofences(ctx context.Context, geofences []domain.Geofence) (created, updated int, err error)
+	Simulate(ctx context.Context, vehicleID string, track []domain.Location) ([]domain.SimulationStep, error)
+	GetVehicleAssignment(ctx context.Context, vehicleID string) (*domain.VehicleAssignment, error)
+	SetVehicleAssignment(ctx context.Context, va *domain.VehicleAssignment) error
+	DeleteVehicleAssignment(ctx context.Context, vehicleID string) error
+	VehiclesInside(ctx context.Context, geofenceID string) ([]domain.GeofencePresence, error)
+	GeofencesContaining(ctx context.Context, vehicleID string) ([]domain.GeofencePresence, error)
+	GeofenceVisits(ctx context.Context, geofenceID string, start, end time.Time) ([]domain.GeofenceVisit, error)
+	VehicleVisits(ctx context.Context, vehicleID string, start, end time.Time) ([]domain.GeofenceVisit, error)
+}
+
+type coordinateBody struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type circleBody struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Radius    float64 `json:"radius"`
+}
+
+type corridorBody struct {
+	Line   []coordinateBody `json:"line"`
+	Buffer float64          `json:"buffer"`
+}
+
+type assignmentBody struct {
+	VehicleIDs []string `json:"vehicle_ids"`
+	GroupIDs   []string `json:"group_ids"`
+	RouteIDs   []string `json:"route_ids"`
+}
+
+type vehicleAssignmentBody struct {
+	VehicleID string   `json:"vehicle_id"`
+	GroupIDs  []string `json:"group_ids"`
+	RouteIDs  []string `json:"route_ids"`
+}
+
+type scheduleWindowBody struct {
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+type scheduleBody struct {
+	Timezone string               `json:"timezone"`
+	Windows  []scheduleWindowBody `json:"windows"`
+}
+
+type importResponse struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+type occupantResponse struct {
+	VehicleID string `json:"vehicle_id"`
+	EnteredAt int64  `json:"entered_at"`
+}
+
+type presenceResponse struct {
+	GeofenceID string   `json:"geofence_id"`
+	Name       string   `json:"name"`
+	Tags       []string `json:"tags"`
+	EnteredAt  int64    `json:"entered_at"`
+}
+
+type geofenceBody struct {
+	ID           string               `json:"id"`
+	Name         string               `json:"name"`
+	Tags         []string             `json:"tags"`
+	Shape        string               `json:"shape"`
+	Circle       *circleBody          `json:"circle,omitempty"`
+	Polygons     [][][]coordinateBody `json:"polygons,omitempty"`
+	Corridor     *corridorBody        `json:"corridor,omitempty"`
+	DwellSeconds int64                `json:"dwell_seconds,omitempty"`
+	Assignment   assignmentBody       `json:"assignment"`
+	Schedule     *scheduleBody        `json:"schedule,omitempty"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+type GeofenceHandler struct {
+	geofenceSvc geofenceService
+}
+
+func NewGeofenceHandler(geofenceSvc geofenceService) *GeofenceHandler {
+	return &GeofenceHandler{geofenceSvc: geofenceSvc}
+}
+
+func (h *GeofenceHandler) Register(r *gin.RouterGroup) {
+	r.GET("/geofences", h.ListGeofences)
+	r.GET("/geofences/export", h.ExportGeofences)
+	r.POST("/geofences/import", h.ImportGeofences)
+	r.POST("/geofences/simulate", h.SimulateGeofences)
+	r.GET("/geofences/:id", h.GetGeofence)
+	r.POST("/geofences", h.CreateGeofence)
+	r.PUT("/geofences/:id", h.UpdateGeofence)
+	r.DELETE("/geofences/:id", h.DeleteGeofence)
+	r.GET("/geofences/:id/vehicles", h.GetGeofenceVehicles)
+	r.GET("/geofences/:id/visits", h.GetGeofenceVisits)
+	r.GET("/vehicles/:vehicle_id/geofences", h.GetVehicleGeofences)
+	r.GET("/vehicles/:vehicle_id/visits", h.GetVehicleVisits)
+	r.GET("/vehicles/:vehicle_id/assignment", h.GetVehicleAssignment)
+	r.PUT("/vehicles/:vehicle_id/assignment", h.SetVehicleAssignment)
+	r.DELETE("/vehicles/:vehicle_id/assignment", h.DeleteVehicleAssignment)
+}
+
+func (h *GeofenceHandler) ListGeofences(c *gin.Context) {
+	geofences, err := h.geofenceSvc.ListGeofences(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch geofences"})
+		return
+	}
+
+	results := make([]geofenceBody, len(geofences))
+	for i := range geofences {
+		results[i] = toGeofenceBody(&geofences[i])
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+func (h *GeofenceHandler) GetGeofence(c *gin.Context) {
+	gf, err := h.geofenceSvc.GetGeofence(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeGeofenceError(c, err, "failed to fetch geofence")
+		return
+	}
+
+	c.JSON(http.StatusOK, toGeofenceBody(gf))
+}
+
+func (h *GeofenceHandler) CreateGeofence(c *gin.Context) {
+	var body geofenceBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	if err := validateGeofenceBody(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	gf := toGeofence(&body)
+	if err := h.geofenceSvc.CreateGeofence(c.Request.Context(), gf); err != nil {
+		if errors.Is(err, domain.ErrGeofenceExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "geofence already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create geofence"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toGeofenceBody(gf))
+}
+
+func (h *GeofenceHandler) UpdateGeofence(c *gin.Context) {
+	var body geofenceBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	body.ID = c.Param("id")
+
+	if err := validateGeofenceBody(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	gf := toGeofence(&body)
+	if err := h.geofenceSvc.UpdateGeofence(c.Request.Context(), gf); err != nil {
+		writeGeofenceError(c, err, "failed to update geofence")
+		return
+	}
+
+	c.JSON(http.StatusOK, toGeofenceBody(gf))
+}
+
+func (h *GeofenceHandler) DeleteGeofence(c *gin.Context) {
+	if err := h.geofenceSvc.DeleteGeofence(c.Request.Context(), c.Param("id")); err != nil {
+		writeGeofenceError(c, err, "failed to delete geofence")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ImportGeofences creates or updates geofences from a GeoJSON or KML file sent
+// as the request body. Every geofence is validated before any is written.
+func (h *GeofenceHandler) ImportGeofences(c *gin.Context) {
+	format, err := geoformat.ParseFormat(c.DefaultQuery("format", string(geoformat.GeoJSON)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	geofences, err := geoformat.Decode(format, c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seen := make(map[string]bool, len(geofences))
+	for i := range geofences {
+		body := toGeofenceBody(&geofences[i])
+		if err := validateGeofenceBody(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("geofences[%d]: %v", i, err)})
+			return
+		}
+		if body.ID == "" {
+			continue
+		}
+		if seen[body.ID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("geofences[%d]: duplicate id %q", i, body.ID)})
+			return
+		}
+		seen[body.ID] = true
+	}
+
+	created, updated, err := h.geofenceSvc.ImportGeofences(c.Request.Context(), geofences)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import geofences", "created": created, "updated": updated})
+		return
+	}
+
+	c.JSON(http.StatusOK, importResponse{Created: created, Updated: updated})
+}
+
+func (h *GeofenceHandler) ExportGeofences(c *gin.Context) {
+	format, err := geoformat.ParseFormat(c.DefaultQuery("format", string(geoformat.GeoJSON)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	geofences, err := h.geofenceSvc.ListGeofences(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch geofences"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := geoformat.Encode(format, &buf, geofences); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export geofences"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="geofences`+format.Extension()+`"`)
+	c.Data(http.StatusOK, format.ContentType(), buf.Bytes())
+}
+
+func (h *GeofenceHandler) GetGeofenceVehicles(c *gin.Context) {
+	presences, err := h.geofenceSvc.VehiclesInside(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeGeofenceError(c, err, "failed to fetch geofence vehicles")
+		return
+	}
+
+	results := make([]occupantResponse, len(presences))
+	for i, p := range presences {
+		results[i] = occupantResponse{VehicleID: p.VehicleID, EnteredAt: p.EnteredAt.Unix()}
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+func (h *GeofenceHandler) GetVehicleGeofences(c *gin.Context) {
+	presences, err := h.geofenceSvc.GeofencesContaining(c.Request.Context(), c.Param("vehicle_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch vehicle geofences"})
+		return
+	}
+
+	results := make([]presenceResponse, len(presences))
+	for i, p := range presences {
+		results[i] = presenceResponse{
+			GeofenceID: p.Geofence.ID,
+			Name:       p.Geofence.Name,
+			Tags:       nonNil(p.Geofence.Tags),
+			EnteredAt:  p.EnteredAt.Unix(),
+		}
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+func (h *GeofenceHandler) GetGeofenceVisits(c *gin.Context) {
+	start, end, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	visits, err := h.geofenceSvc.GeofenceVisits(c.Request.Context(), c.Param("id"), start, end)
+	if err != nil {
+		writeGeofenceError(c, err, "failed to fetch geofence visits")
+		return
+	}
+	c.JSON(http.StatusOK, toVisitResponses(visits))
+}
+
+func (h *GeofenceHandler) GetVehicleVisits(c *gin.Context) {
+	start, end, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	visits, err := h.geofenceSvc.VehicleVisits(c.Request.Context(), c.Param("vehicle_id"), start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch vehicle visits"})
+		return
+	}
+	c.JSON(http.StatusOK, toVisitResponses(visits))
+}
+
+func (h *GeofenceHandler) GetVehicleAssignment(c *gin.Context) {
+	va, err := h.geofenceSvc.GetVehicleAssignment(c.Request.Context(), c.Param("vehicle_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch vehicle assignment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toVehicleAssignmentBody(va))
+}
+
+func (h *GeofenceHandler) SetVehicleAssignment(c *gin.Context) {
+	var body vehicleAssignmentBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	body.VehicleID = c.Param("vehicle_id")
+
+	if err := domain.ValidateVehicleID(body.VehicleID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "vehicle_id: " + err.Error()})
+		return
+	}
+	if err := validateIDs("group_ids", body.GroupIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateIDs("route_ids", body.RouteIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	va := &domain.VehicleAssignment{VehicleID: body.VehicleID, GroupIDs: body.GroupIDs, RouteIDs: body.RouteIDs}
+	if err := h.geofenceSvc.SetVehicleAssignment(c.Request.Context(), va); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save vehicle assignment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toVehicleAssignmentBody(va))
+}
+
+func (h *GeofenceHandler) DeleteVehicleAssignment(c *gin.Context) {
+	if err := h.geofenceSvc.DeleteVehicleAssignment(c.Request.Context(), c.Param("vehicle_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete vehicle assignment"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// parseTimeRange reads the start and end unix timestamps from the query
+// string, responding 400 when either is missing or invalid.
+func parseTimeRange(c *gin.Context) (start, end time.Time, ok bool) {
+	startUnix, err := strconv.ParseInt(c.Query("start"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start parameter"})
+		return time.Time{}, time.Time{}, false
+	}
+	endUnix, err := strconv.ParseInt(c.Query("end"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end parameter"})
+		return time.Time{}, time.Time{}, false
+	}
+	return time.Unix(startUnix, 0), time.Unix(endUnix, 0), true
+}
+
+func writeGeofenceError(c *gin.Context, err error, msg string) {
+	if errors.Is(err, domain.ErrGeofenceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "geofence not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+}
+
+func validateGeofenceBody(body *geofenceBody) error {
+	if len(body.ID) > 64 {
+		return fmt.Errorf("id: must be at most 64 characters")
+	}
+	if len(body.Name) > 255 {
+		return fmt.Errorf("name: must be at most 255 characters")
+	}
+	if body.DwellSeconds < 0 {
+		return fmt.Errorf("dwell_seconds: must not be negative")
+	}
+	if err := validateIDs("tags", body.Tags); err != nil {
+		return err
+	}
+	if err := validateIDs("assignment.vehicle_ids", body.Assignment.VehicleIDs); err != nil {
+		return err
+	}
+	for i, id := range body.Assignment.VehicleIDs {
+		if err := domain.ValidateVehicleID(id); err != nil {
+			return fmt.Errorf("assignment.vehicle_ids[%d]: %w", i, err)
+		}
+	}
+	if err := validateIDs("assignment.group_ids", body.Assignment.GroupIDs); err != nil {
+		return err
+	}
+	if err := validateIDs("assignment.route_ids", body.Assignment.RouteIDs); err != nil {
+		return err
+	}
+
+	if body.Schedule != nil {
+		if err := validateSchedule(body.Schedule); err != nil {
+			return fmt.Errorf("schedule: %w", err)
+		}
+	}
+
+	switch domain.GeofenceShape(body.Shape) {
+	case domain.ShapeCircle:
+		if body.Circle == nil {
+			return fmt.Errorf("circle: required for circle geofence")
+		}
+		if err := validateCoordinate(body.Circle.Latitude, body.Circle.Longitude); err != nil {
+			return fmt.Errorf("circle: %w", err)
+		}
+		if body.Circle.Radius <= 0 {
+			return fmt.Errorf("circle: radius must be positive")
+		}
+	case domain.ShapePolygon:
+		if len(body.Polygons) == 0 {
+			return fmt.Errorf("polygons: required for polygon geofence")
+		}
+		for i, polygon := range body.Polygons {
+			if len(polygon) == 0 {
+				return fmt.Errorf("polygons[%d]: outer ring required", i)
+			}
+			for j, ring := range polygon {
+				if len(ring) < 3 {
+					return fmt.Errorf("polygons[%d][%d]: ring needs at least 3 vertices", i, j)
+				}
+				for _, pt := range ring {
+					if err := validateCoordinate(pt.Latitude, pt.Longitude); err != nil {
+						return fmt.Errorf("polygons[%d][%d]: %w", i, j, err)
+					}
+				}
+			}
+		}
+	case domain.ShapeCorridor:
+		if body.Corridor == nil {
+			return fmt.Errorf("corridor: required for corridor geofence")
+		}
+		if len(body.Corridor.Line) < 2 {
+			return fmt.Errorf("corridor: line needs at least 2 points")
+		}
+		for _, pt := range body.Corridor.Line {
+			if err := validateCoordinate(pt.Latitude, pt.Longitude); err != nil {
+				return fmt.Errorf("corridor: %w", err)
+			}
+		}
+		if body.Corridor.Buffer <= 0 {
+			return fmt.Errorf("corridor: buffer must be positive")
+		}
+	default:
+		return fmt.Errorf("shape: must be one of circle, polygon, corridor")
+	}
+	return nil
+}
+
+func validateSchedule(body *scheduleBody) error {
+	if _, err := time.LoadLocation(body.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", body.Timezone)
+	}
+	if len(body.Windows) == 0 {
+		return fmt.Errorf("at least one window required")
+	}
+	for i, w := range body.Windows {
+		for _, day := range w.Days {
+			if _, ok := weekdays[day]; !ok {
+				return fmt.Errorf("windows[%d]: unknown day %q", i, day)
+			}
+		}
+		start, err := parseClock(w.Start)
+		if err != nil {
+			return fmt.Errorf("windows[%d]: start %w", i, err)
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			return fmt.Errorf("windows[%d]: end %w", i, err)
+		}
+		// an end before the start wraps past midnight, so an equal end would
+		// read as a whole day; 00:00 to 24:00 says that explicitly
+		if end == start {
+			return fmt.Errorf("windows[%d]: start and end must differ", i)
+		}
+	}
+	return nil
+}
+
+// parseClock parses an HH:MM time of day into an offset from midnight. 24:00
+// is accepted as the end of the day.
+func parseClock(s string) (time.Duration, error) {
+	var h, m int
+	if n, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || n != 2 || len(s) != 5 {
+		return 0, fmt.Errorf("must be HH:MM")
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("must be between 00:00 and 24:00")
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
+
+func validateIDs(field string, ids []string) error {
+	for i, id := range ids {
+		if id == "" {
+			return fmt.Errorf("%s[%d]: must not be empty", field, i)
+		}
+	}
+	return nil
+}
+
+func validateCoordinate(lat, lon float64) error {
+	if lat < -90 || lat > 90 {
+		return fmt.Errorf("latitude must be between -90 and 90")
+	}
+	if lon < -180 || lon > 180 {
+		return fmt.Errorf("longitude must be between -180 and 180")
+	}
+	return nil
+}
+
+func toGeofence(body *geofenceBody) *domain.Geofence {
+	gf := &domain.Geofence{
+		ID:             body.ID,
+		Name:           body.Name,
+		Tags:           body.Tags,
+		Shape:          domain.GeofenceShape(body.Shape),
+		DwellThreshold: time.Duration(body.DwellSeconds) * time.Second,
+		Assignment: domain.GeofenceAssignment{
+			VehicleIDs: body.Assignment.VehicleIDs,
+			GroupIDs:   body.Assignment.GroupIDs,
+			RouteIDs:   body.Assignment.RouteIDs,
+		},
+	}
+	if body.Schedule != nil {
+		gf.Schedule = &domain.Schedule{
+			Timezone: body.Schedule.Timezone,
+			Windows:  make([]domain.ScheduleWindow, len(body.Schedule.Windows)),
+		}
+		for i, w := range body.Schedule.Windows {
+			window := &gf.Schedule.Windows[i]
+			for _, day := range w.Days {
+				window.Days = append(window.Days, weekdays[day])
+			}
+			window.Start, _ = parseClock(w.Start)
+			window.End, _ = parseClock(w.End)
+		}
+	}
+	switch gf.Shape {
+	case domain.ShapeCircle:
+		gf.Circle = &domain.GeoPoint{
+			Lat:    body.Circle.Latitude,
+			Lon:    body.Circle.Longitude,
+			Radius: body.Circle.Radius,
+		}
+	case domain.ShapePolygon:
+		gf.Polygons = make([]domain.Polygon, len(body.Polygons))
+		for i, polygon := range body.Polygons {
+			gf.Polygons[i] = make(domain.Polygon, len(polygon))
+			for j, ring := range polygon {
+				gf.Polygons[i][j] = make([]domain.Coordinate, len(ring))
+				for k, pt := range ring {
+					gf.Polygons[i][j][k] = domain.Coordinate{Lat: pt.Latitude, Lon: pt.Longitude}
+				}
+			}
+		}
+	case domain.ShapeCorridor:
+		gf.Corridor = &domain.Corridor{
+			Line:   make([]domain.Coordinate, len(body.Corridor.Line)),
+			Buffer: body.Corridor.Buffer,
+		}
+		for i, pt := range body.Corridor.Line {
+			gf.Corridor.Line[i] = domain.Coordinate{Lat: pt.Latitude, Lon: pt.Longitude}
+		}
+	}
+	return gf
+}
+
+func toGeofenceBody(gf *domain.Geofence) geofenceBody {
+	body := geofenceBody{
+		ID:           gf.ID,
+		Name:         gf.Name,
+		Tags:         gf.Tags,
+		Shape:        string(gf.Shape),
+		DwellSeconds: int64(gf.DwellThreshold / time.Second),
+		Assignment: assignmentBody{
+			VehicleIDs: nonNil(gf.Assignment.VehicleIDs),
+			GroupIDs:   nonNil(gf.Assignment.GroupIDs),
+			RouteIDs:   nonNil(gf.Assignment.RouteIDs),
+		},
+	}
+	if body.Tags == nil {
+		body.Tags = []string{}
+	}
+	if gf.Schedule != nil {
+		body.Schedule = &scheduleBody{
+			Timezone: gf.Schedule.Timezone,
+			Windows:  make([]scheduleWindowBody, len(gf.Schedule.Windows)),
+		}
+		for i, w := range gf.Schedule.Windows {
+			window := &body.Schedule.Windows[i]
+			for _, day := range w.Days {
+				window.Days = append(window.Days, weekdayName(day))
+			}
+			window.Start = formatClock(w.Start)
+			window.End = formatClock(w.End)
+		}
+	}
+	if gf.Circle != nil {
+		body.Circle = &circleBody{
+			Latitude:  gf.Circle.Lat,
+			Longitude: gf.Circle.Lon,
+			Radius:    gf.Circle.Radius,
+		}
+	}
+	if len(gf.Polygons) > 0 {
+		body.Polygons = make([][][]coordinateBody, len(gf.Polygons))
+		for i, polygon := range gf.Polygons {
+			body.Polygons[i] = make([][]coordinateBody, len(polygon))
+			for j, ring := range polygon {
+				body.Polygons[i][j] = make([]coordinateBody, len(ring))
+				for k, pt := range ring {
+					body.Polygons[i][j][k] = coordinateBody{Latitude: pt.Lat, Longitude: pt.Lon}
+				}
+			}
+		}
+	}
+	if gf.Corridor != nil {
+		body.Corridor = &corridorBody{
+			Line:   make([]coordinateBody, len(gf.Corridor.Line)),
+			Buffer: gf.Corridor.Buffer,
+		}
+		for i, pt := range gf.Corridor.Line {
+			body.Corridor.Line[i] = coordinateBody{Latitude: pt.Lat, Longitude: pt.Lon}
+		}
+	}
+	return body
+}
+
+func toVehicleAssignmentBody(va *domain.VehicleAssignment) vehicleAssignmentBody {
+	return vehicleAssignmentBody{
+		VehicleID: va.VehicleID,
+		GroupIDs:  nonNil(va.GroupIDs),
+		RouteIDs:  nonNil(va.RouteIDs),
+	}
+}
+
+// nonNil keeps empty lists rendered as [] rather than null.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func weekdayName(day time.Weekday) string {
+	return strings.ToLower(day.String()[:3])
+}
@@ -0,0 +1,790 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+type mockGeofenceService struct {
+	listGeofencesFn  func(ctx context.Context) ([]domain.Geofence, error)
+	getGeofenceFn    func(ctx context.Context, id string) (*domain.Geofence, error)
+	createGeofenceFn func(ctx context.Context, gf *domain.Geofence) error
+	updateGeofenceFn func(ctx context.Context, gf *domain.Geofence) error
+	deleteGeofenceFn func(ctx context.Context, id string) error
+	importFn         func(ctx context.Context, geofences []domain.Geofence) (int, int, error)
+	simulateFn       func(ctx context.Context, vehicleID string, track []domain.Location) ([]domain.SimulationStep, error)
+	getAssignmentFn  func(ctx context.Context, vehicleID string) (*domain.VehicleAssignment, error)
+	setAssignmentFn  func(ctx context.Context, va *domain.VehicleAssignment) error
+	vehiclesInsideFn func(ctx context.Context, geofenceID string) ([]domain.GeofencePresence, error)
+	containingFn     func(ctx context.Context, vehicleID string) ([]domain.GeofencePresence, error)
+	geofenceVisitsFn func(ctx context.Context, geofenceID string, start, end time.Time) ([]domain.GeofenceVisit, error)
+	vehicleVisitsFn  func(ctx context.Context, vehicleID string, start, end time.Time) ([]domain.GeofenceVisit, error)
+}
+
+func (m *mockGeofenceService) ListGeofences(ctx context.Context) ([]domain.Geofence, error) {
+	return m.listGeofencesFn(ctx)
+}
+
+func (m *mockGeofenceService) GetGeofence(ctx context.Context, id string) (*domain.Geofence, error) {
+	return m.getGeofenceFn(ctx, id)
+}
+
+func (m *mockGeofenceService) CreateGeofence(ctx context.Context, gf *domain.Geofence) error {
+	return m.createGeofenceFn(ctx, gf)
+}
+
+func (m *mockGeofenceService) UpdateGeofence(ctx context.Context, gf *domain.Geofence) error {
+	return m.updateGeofenceFn(ctx, gf)
+}
+
+func (m *mockGeofenceService) DeleteGeofence(ctx context.Context, id string) error {
+	return m.deleteGeofenceFn(ctx, id)
+}
+
+func (m *mockGeofenceService) GetVehicleAssignment(ctx context.Context, vehicleID string) (*domain.VehicleAssignment, error) {
+	return m.getAssignmentFn(ctx, vehicleID)
+}
+
+func (m *mockGeofenceService) SetVehicleAssignment(ctx context.Context, va *domain.VehicleAssignment) error {
+	return m.setAssignmentFn(ctx, va)
+}
+
+func (m *mockGeofenceService) DeleteVehicleAssignment(_ context.Context, _ string) error {
+	return nil
+}
+
+func (m *mockGeofenceService) VehiclesInside(ctx context.Context, geofenceID string) ([]domain.GeofencePresence, error) {
+	return m.vehiclesInsideFn(ctx, geofenceID)
+}
+
+func (m *mockGeofenceService) GeofencesContaining(ctx context.Context, vehicleID string) ([]domain.GeofencePresence, error) {
+	return m.containingFn(ctx, vehicleID)
+}
+
+func (m *mockGeofenceService) GeofenceVisits(ctx context.Context, geofenceID string, start, end time.Time) ([]domain.GeofenceVisit, error) {
+	return m.geofenceVisitsFn(ctx, geofenceID, start, end)
+}
+
+func (m *mockGeofenceService) VehicleVisits(ctx context.Context, vehicleID string, start, end time.Time) ([]domain.GeofenceVisit, error) {
+	return m.vehicleVisitsFn(ctx, vehicleID, start, end)
+}
+
+func (m *mockGeofenceService) ImportGeofences(ctx context.Context, geofences []domain.Geofence) (int, int, error) {
+	return m.importFn(ctx, geofences)
+}
+
+func (m *mockGeofenceService) Simulate(ctx context.Context, vehicleID string, track []domain.Location) ([]domain.SimulationStep, error) {
+	return m.simulateFn(ctx, vehicleID, track)
+}
+
+func setupGeofenceRouter(svc geofenceService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := NewGeofenceHandler(svc)
+	h.Register(r.Group(""))
+	return r
+}
+
+func TestListGeofences_Success(t *testing.T) {
+	svc := &mockGeofenceService{
+		listGeofencesFn: func(_ context.Context) ([]domain.Geofence, error) {
+			return []domain.Geofence{
+				{ID: "a", Name: "Terminal", Tags: []string{"terminal"}, Shape: domain.ShapeCircle, Circle: &domain.GeoPoint{Lat: -6.2088, Lon: 106.8456, Radius: 50}},
+			}, nil
+		},
+	}
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/geofences", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var resp []geofenceBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(resp) != 1 || resp[0].Circle == nil || resp[0].Circle.Radius != 50 {
+		t.Fatalf("unexpected response: %s", w.Body.String())
+	}
+	if resp[0].Name != "Terminal" || len(resp[0].Tags) != 1 {
+		t.Errorf("expected name and tags in response, got %s", w.Body.String())
+	}
+}
+
+func TestGetGeofence_NotFound(t *testing.T) {
+	svc := &mockGeofenceService{
+		getGeofenceFn: func(_ context.Context, _ string) (*domain.Geofence, error) {
+			return nil, domain.ErrGeofenceNotFound
+		},
+	}
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/geofences/missing", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestCreateGeofence_Polygon(t *testing.T) {
+	var created *domain.Geofence
+	svc := &mockGeofenceService{
+		createGeofenceFn: func(_ context.Context, gf *domain.Geofence) error {
+			gf.ID = "generated"
+			created = gf
+			return nil
+		},
+	}
+
+	body := `{"name":"Depot","tags":["depot"],"dwell_seconds":900,"shape":"polygon","polygons":[[[{"latitude":-6.21,"longitude":106.84},{"latitude":-6.21,"longitude":106.85},{"latitude":-6.2,"longitude":106.85}]]]}`
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/geofences", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if created == nil || len(created.Polygons) != 1 || len(created.Polygons[0][0]) != 3 {
+		t.Fatalf("unexpected created geofence: %+v", created)
+	}
+	if created.DwellThreshold != 15*time.Minute {
+		t.Errorf("expected 15m dwell threshold, got %v", created.DwellThreshold)
+	}
+	if created.Name != "Depot" || len(created.Tags) != 1 || created.Tags[0] != "depot" {
+		t.Errorf("unexpected name/tags: %q %v", created.Name, created.Tags)
+	}
+
+	var resp geofenceBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.ID != "generated" {
+		t.Errorf("expected generated, got %s", resp.ID)
+	}
+}
+
+func TestCreateGeofence_Corridor(t *testing.T) {
+	var created *domain.Geofence
+	svc := &mockGeofenceService{
+		createGeofenceFn: func(_ context.Context, gf *domain.Geofence) error {
+			created = gf
+			return nil
+		},
+	}
+
+	body := `{"id":"koridor-1","shape":"corridor","corridor":{"line":[{"latitude":-6.2,"longitude":106.83},{"latitude":-6.2,"longitude":106.85}],"buffer":30},"assignment":{"route_ids":["corridor-1"]}}`
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/geofences", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if created == nil || created.Corridor == nil || len(created.Corridor.Line) != 2 || created.Corridor.Buffer != 30 {
+		t.Fatalf("unexpected created geofence: %+v", created)
+	}
+
+	var resp geofenceBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Corridor == nil || resp.Corridor.Line[1].Longitude != 106.85 {
+		t.Errorf("expected corridor in response, got %s", w.Body.String())
+	}
+}
+
+func TestCreateGeofence_ValidationError(t *testing.T) {
+	svc := &mockGeofenceService{}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid json", `{`},
+		{"unknown shape", `{"shape":"hexagon"}`},
+		{"circle missing", `{"shape":"circle"}`},
+		{"zero radius", `{"shape":"circle","circle":{"latitude":-6.2,"longitude":106.8,"radius":0}}`},
+		{"latitude out of range", `{"shape":"circle","circle":{"latitude":-91,"longitude":106.8,"radius":50}}`},
+		{"polygons missing", `{"shape":"polygon"}`},
+		{"negative dwell", `{"shape":"circle","dwell_seconds":-1,"circle":{"latitude":-6.2,"longitude":106.8,"radius":50}}`},
+		{"empty tag", `{"shape":"circle","tags":[""],"circle":{"latitude":-6.2,"longitude":106.8,"radius":50}}`},
+		{"long assigned vehicle id", `{"shape":"circle","assignment":{"vehicle_ids":["` + strings.Repeat("B", 51) + `"]},"circle":{"latitude":-6.2,"longitude":106.8,"radius":50}}`},
+		{"short ring", `{"shape":"polygon","polygons":[[[{"latitude":-6.21,"longitude":106.84},{"latitude":-6.21,"longitude":106.85}]]]}`},
+		{"corridor missing", `{"shape":"corridor"}`},
+		{"single point corridor", `{"shape":"corridor","corridor":{"line":[{"latitude":-6.2,"longitude":106.83}],"buffer":30}}`},
+		{"zero buffer", `{"shape":"corridor","corridor":{"line":[{"latitude":-6.2,"longitude":106.83},{"latitude":-6.2,"longitude":106.85}],"buffer":0}}`},
+	}
+
+	r := setupGeofenceRouter(svc)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/geofences", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestUpdateGeofence_UsesPathID(t *testing.T) {
+	var updated *domain.Geofence
+	svc := &mockGeofenceService{
+		updateGeofenceFn: func(_ context.Context, gf *domain.Geofence) error {
+			updated = gf
+			return nil
+		},
+	}
+
+	body := `{"id":"other","shape":"circle","circle":{"latitude":-6.2,"longitude":106.8,"radius":75}}`
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/geofences/a", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if updated.ID != "a" {
+		t.Errorf("expected path id a, got %s", updated.ID)
+	}
+}
+
+func TestUpdateGeofence_NotFound(t *testing.T) {
+	svc := &mockGeofenceService{
+		updateGeofenceFn: func(_ context.Context, _ *domain.Geofence) error {
+			return domain.ErrGeofenceNotFound
+		},
+	}
+
+	body := `{"shape":"circle","circle":{"latitude":-6.2,"longitude":106.8,"radius":75}}`
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/geofences/missing", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestDeleteGeofence_Success(t *testing.T) {
+	svc := &mockGeofenceService{
+		deleteGeofenceFn: func(_ context.Context, id string) error {
+			if id != "a" {
+				t.Fatalf("unexpected id: %s", id)
+			}
+			return nil
+		},
+	}
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/geofences/a", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+}
+
+func TestDeleteGeofence_ServiceError(t *testing.T) {
+	svc := &mockGeofenceService{
+		deleteGeofenceFn: func(_ context.Context, _ string) error {
+			return errors.New("db error")
+		},
+	}
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/geofences/a", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+}
+
+func TestCreateGeofence_WithAssignment(t *testing.T) {
+	var created *domain.Geofence
+	svc := &mockGeofenceService{
+		createGeofenceFn: func(_ context.Context, gf *domain.Geofence) error {
+			created = gf
+			return nil
+		},
+	}
+
+	body := `{"id":"corridor-1-terminal","shape":"circle","circle":{"latitude":-6.2088,"longitude":106.8456,"radius":50},"assignment":{"route_ids":["corridor-1"]}}`
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/geofences", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(created.Assignment.RouteIDs) != 1 || created.Assignment.RouteIDs[0] != "corridor-1" {
+		t.Errorf("unexpected assignment: %+v", created.Assignment)
+	}
+
+	var resp geofenceBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Assignment.VehicleIDs == nil || len(resp.Assignment.RouteIDs) != 1 {
+		t.Errorf("unexpected assignment in response: %s", w.Body.String())
+	}
+}
+
+func TestGetVehicleAssignment_Success(t *testing.T) {
+	svc := &mockGeofenceService{
+		getAssignmentFn: func(_ context.Context, vehicleID string) (*domain.VehicleAssignment, error) {
+			return &domain.VehicleAssignment{VehicleID: vehicleID}, nil
+		},
+	}
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/vehicles/B1234XYZ/assignment", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if got := w.Body.String(); got != `{"vehicle_id":"B1234XYZ","group_ids":[],"route_ids":[]}` {
+		t.Errorf("unexpected response: %s", got)
+	}
+}
+
+func TestSetVehicleAssignment_UsesPathID(t *testing.T) {
+	var saved *domain.VehicleAssignment
+	svc := &mockGeofenceService{
+		setAssignmentFn: func(_ context.Context, va *domain.VehicleAssignment) error {
+			saved = va
+			return nil
+		},
+	}
+
+	body := `{"vehicle_id":"ignored","group_ids":["articulated"],"route_ids":["corridor-1"]}`
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/vehicles/B1234XYZ/assignment", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if saved.VehicleID != "B1234XYZ" || len(saved.GroupIDs) != 1 || len(saved.RouteIDs) != 1 {
+		t.Errorf("unexpected assignment: %+v", saved)
+	}
+}
+
+func TestSetVehicleAssignment_ValidationError(t *testing.T) {
+	tests := []struct {
+		name      string
+		vehicleID string
+		body      string
+	}{
+		{"empty route id", "B1234XYZ", `{"route_ids":[""]}`},
+		{"long vehicle id", strings.Repeat("B", 51), `{}`},
+	}
+	r := setupGeofenceRouter(&mockGeofenceService{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/vehicles/"+tt.vehicleID+"/assignment", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestSetVehicleAssignment_CountsCharacters(t *testing.T) {
+	svc := &mockGeofenceService{
+		setAssignmentFn: func(context.Context, *domain.VehicleAssignment) error { return nil },
+	}
+	// 50 characters of two bytes each fit the column
+	vehicleID := strings.Repeat("é", 50)
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/vehicles/"+url.PathEscape(vehicleID)+"/assignment", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateGeofence_WithSchedule(t *testing.T) {
+	var created *domain.Geofence
+	svc := &mockGeofenceService{
+		createGeofenceFn: func(_ context.Context, gf *domain.Geofence) error {
+			created = gf
+			return nil
+		},
+	}
+
+	body := `{"id":"school-zone","shape":"circle","circle":{"latitude":-6.2088,"longitude":106.8456,"radius":50},
+		"schedule":{"timezone":"Asia/Jakarta","windows":[{"days":["mon","fri"],"start":"06:00","end":"08:30"}]}}`
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/geofences", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	sched := created.Schedule
+	if sched == nil || sched.Timezone != "Asia/Jakarta" || len(sched.Windows) != 1 {
+		t.Fatalf("unexpected schedule: %+v", sched)
+	}
+	window := sched.Windows[0]
+	if len(window.Days) != 2 || window.Days[0] != time.Monday || window.Days[1] != time.Friday {
+		t.Errorf("unexpected days: %v", window.Days)
+	}
+	if window.Start != 6*time.Hour || window.End != 8*time.Hour+30*time.Minute {
+		t.Errorf("unexpected window: %v-%v", window.Start, window.End)
+	}
+
+	var resp geofenceBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Schedule == nil || resp.Schedule.Windows[0].End != "08:30" || resp.Schedule.Windows[0].Days[1] != "fri" {
+		t.Errorf("unexpected schedule in response: %s", w.Body.String())
+	}
+}
+
+func TestCreateGeofence_InvalidSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+	}{
+		{"unknown timezone", `{"timezone":"Mars/Olympus","windows":[{"start":"06:00","end":"08:00"}]}`},
+		{"no windows", `{"timezone":"UTC","windows":[]}`},
+		{"bad day", `{"timezone":"UTC","windows":[{"days":["monday"],"start":"06:00","end":"08:00"}]}`},
+		{"bad time", `{"timezone":"UTC","windows":[{"start":"6am","end":"08:00"}]}`},
+		{"out of range", `{"timezone":"UTC","windows":[{"start":"06:00","end":"24:30"}]}`},
+		{"empty window", `{"timezone":"UTC","windows":[{"start":"08:00","end":"08:00"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"shape":"circle","circle":{"latitude":-6.2088,"longitude":106.8456,"radius":50},"schedule":` + tt.schedule + `}`
+			r := setupGeofenceRouter(&mockGeofenceService{})
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/geofences", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestCreateGeofence_DuplicateID(t *testing.T) {
+	svc := &mockGeofenceService{
+		createGeofenceFn: func(_ context.Context, _ *domain.Geofence) error {
+			return domain.ErrGeofenceExists
+		},
+	}
+
+	body := `{"id":"depot","shape":"circle","circle":{"latitude":-6.2088,"longitude":106.8456,"radius":50}}`
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/geofences", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGetGeofenceVehicles_Success(t *testing.T) {
+	svc := &mockGeofenceService{
+		vehiclesInsideFn: func(_ context.Context, geofenceID string) ([]domain.GeofencePresence, error) {
+			return []domain.GeofencePresence{
+				{VehicleID: "B1234XYZ", Geofence: domain.Geofence{ID: geofenceID}, EnteredAt: time.Unix(1715003456, 0)},
+			}, nil
+		},
+	}
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/geofences/terminal/vehicles", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if got := w.Body.String(); got != `[{"vehicle_id":"B1234XYZ","entered_at":1715003456}]` {
+		t.Errorf("unexpected response: %s", got)
+	}
+}
+
+func TestGetGeofenceVehicles_NotFound(t *testing.T) {
+	svc := &mockGeofenceService{
+		vehiclesInsideFn: func(_ context.Context, _ string) ([]domain.GeofencePresence, error) {
+			return nil, domain.ErrGeofenceNotFound
+		},
+	}
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/geofences/missing/vehicles", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestGetVehicleGeofences_Empty(t *testing.T) {
+	svc := &mockGeofenceService{
+		containingFn: func(_ context.Context, _ string) ([]domain.GeofencePresence, error) {
+			return nil, nil
+		},
+	}
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/vehicles/B1234XYZ/geofences", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if got := w.Body.String(); got != `[]` {
+		t.Errorf("expected empty list, got %s", got)
+	}
+}
+
+func TestGetVehicleGeofences_Success(t *testing.T) {
+	svc := &mockGeofenceService{
+		containingFn: func(_ context.Context, vehicleID string) ([]domain.GeofencePresence, error) {
+			return []domain.GeofencePresence{
+				{VehicleID: vehicleID, Geofence: domain.Geofence{ID: "terminal", Name: "Terminal"}, EnteredAt: time.Unix(1715003456, 0)},
+			}, nil
+		},
+	}
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/vehicles/B1234XYZ/geofences", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if got := w.Body.String(); got != `[{"geofence_id":"terminal","name":"Terminal","tags":[],"entered_at":1715003456}]` {
+		t.Errorf("unexpected response: %s", got)
+	}
+}
+
+func TestGetGeofenceVisits_Success(t *testing.T) {
+	var gotStart, gotEnd time.Time
+	svc := &mockGeofenceService{
+		geofenceVisitsFn: func(_ context.Context, geofenceID string, start, end time.Time) ([]domain.GeofenceVisit, error) {
+			gotStart, gotEnd = start, end
+			entered := time.Unix(1715003456, 0)
+			return []domain.GeofenceVisit{
+				{VehicleID: "B1234XYZ", GeofenceID: geofenceID, EnteredAt: entered, ExitedAt: entered.Add(90 * time.Second), Duration: 90 * time.Second},
+			}, nil
+		},
+	}
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/geofences/terminal/visits?start=1715000000&end=1715086400", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if !gotStart.Equal(time.Unix(1715000000, 0)) || !gotEnd.Equal(time.Unix(1715086400, 0)) {
+		t.Errorf("unexpected range: %v - %v", gotStart, gotEnd)
+	}
+	want := `[{"vehicle_id":"B1234XYZ","geofence_id":"terminal","entered_at":1715003456,"exited_at":1715003546,"duration_seconds":90}]`
+	if got := w.Body.String(); got != want {
+		t.Errorf("unexpected response: %s", got)
+	}
+}
+
+func TestGetGeofenceVisits_NotFound(t *testing.T) {
+	svc := &mockGeofenceService{
+		geofenceVisitsFn: func(_ context.Context, _ string, _, _ time.Time) ([]domain.GeofenceVisit, error) {
+			return nil, domain.ErrGeofenceNotFound
+		},
+	}
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/geofences/missing/visits?start=1&end=2", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestGetVehicleVisits_Empty(t *testing.T) {
+	svc := &mockGeofenceService{
+		vehicleVisitsFn: func(_ context.Context, _ string, _, _ time.Time) ([]domain.GeofenceVisit, error) {
+			return nil, nil
+		},
+	}
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/vehicles/B1234XYZ/visits?start=1&end=2", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if got := w.Body.String(); got != `[]` {
+		t.Errorf("expected empty list, got %s", got)
+	}
+}
+
+func TestGetVisits_InvalidRange(t *testing.T) {
+	for _, path := range []string{
+		"/geofences/terminal/visits?end=2",
+		"/geofences/terminal/visits?start=1&end=abc",
+		"/vehicles/B1234XYZ/visits?start=abc&end=2",
+	} {
+		r := setupGeofenceRouter(&mockGeofenceService{})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestImportGeofences_GeoJSON(t *testing.T) {
+	var imported []domain.Geofence
+	svc := &mockGeofenceService{
+		importFn: func(_ context.Context, geofences []domain.Geofence) (int, int, error) {
+			imported = geofences
+			return 1, 1, nil
+		},
+	}
+
+	body := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","id":"blok-m","geometry":{"type":"Point","coordinates":[106.8017,-6.2443]},"properties":{"name":"Blok M","radius":80}},
+		{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[106.84,-6.21],[106.85,-6.21],[106.85,-6.20],[106.84,-6.21]]]},"properties":{"name":"Depot"}}
+	]}`
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/geofences/import", bytes.NewBufferString(body))
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Body.String(); got != `{"created":1,"updated":1}` {
+		t.Errorf("unexpected response: %s", got)
+	}
+	if len(imported) != 2 || imported[0].Circle.Radius != 80 || imported[1].Name != "Depot" {
+		t.Errorf("unexpected imported geofences: %+v", imported)
+	}
+}
+
+func TestImportGeofences_ValidationError(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		body string
+	}{
+		{"unknown format", "/geofences/import?format=shp", `{}`},
+		{"undecodable", "/geofences/import?format=kml", `not xml`},
+		{"invalid geofence", "/geofences/import", `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[106.8,-6.2]},"properties":{"radius":-5}}]}`},
+		{"duplicate id", "/geofences/import", `{"type":"FeatureCollection","features":[
+			{"type":"Feature","id":"a","geometry":{"type":"Point","coordinates":[106.8,-6.2]},"properties":{"radius":5}},
+			{"type":"Feature","id":"a","geometry":{"type":"Point","coordinates":[106.8,-6.2]},"properties":{"radius":5}}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := setupGeofenceRouter(&mockGeofenceService{})
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", tt.url, bytes.NewBufferString(tt.body))
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestExportGeofences_KML(t *testing.T) {
+	svc := &mockGeofenceService{
+		listGeofencesFn: func(_ context.Context) ([]domain.Geofence, error) {
+			return []domain.Geofence{
+				{ID: "a", Name: "Terminal", Shape: domain.ShapeCircle, Circle: &domain.GeoPoint{Lat: -6.2088, Lon: 106.8456, Radius: 50}},
+			}, nil
+		},
+	}
+
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/geofences/export?format=kml", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/vnd.google-earth.kml+xml" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="geofences.kml"` {
+		t.Errorf("unexpected content disposition %q", cd)
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte(`<coordinates>106.8456,-6.2088</coordinates>`)) {
+		t.Errorf("expected placemark coordinates in export:\n%s", w.Body.String())
+	}
+}
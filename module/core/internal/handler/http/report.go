@@ -0,0 +1,153 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+// maxVisitReportDays bounds the range of a visit report, which is replayed
+// while the request waits.
+const maxVisitReportDays = 31
+
+type reportService interface {
+	RunVisitReport(ctx context.Context, geofenceID string, start, end time.Time) (*domain.VisitReport, error)
+	GetVisitReport(ctx context.Context, id int64) (*domain.VisitReport, error)
+}
+
+type visitReportRequest struct {
+	GeofenceID string `json:"geofence_id"`
+	Start      int64  `json:"start"`
+	End        int64  `json:"end"`
+}
+
+type visitResponse struct {
+	VehicleID       string `json:"vehicle_id"`
+	GeofenceID      string `json:"geofence_id"`
+	EnteredAt       int64  `json:"entered_at"`
+	ExitedAt        *int64 `json:"exited_at"`
+	DurationSeconds int64  `json:"duration_seconds"`
+}
+
+type visitReportResponse struct {
+	ID               int64           `json:"id"`
+	GeofenceID       string          `json:"geofence_id"`
+	Start            int64           `json:"start"`
+	End              int64           `json:"end"`
+	VehiclesScanned  int             `json:"vehicles_scanned"`
+	LocationsScanned int             `json:"locations_scanned"`
+	CreatedAt        int64           `json:"created_at"`
+	Visits           []visitResponse `json:"visits"`
+}
+
+type ReportHandler struct {
+	reportSvc reportService
+}
+
+func NewReportHandler(reportSvc reportService) *ReportHandler {
+	return &ReportHandler{reportSvc: reportSvc}
+}
+
+func (h *ReportHandler) Register(r *gin.RouterGroup) {
+	r.POST("/admin/visit-reports", h.RunVisitReport)
+	r.GET("/admin/visit-reports/:id", h.GetVisitReport)
+}
+
+// RunVisitReport replays stored history through a geofence and responds with
+// the stored report once it is complete.
+func (h *ReportHandler) RunVisitReport(c *gin.Context) {
+	var req visitReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if err := validateVisitReportRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.reportSvc.RunVisitReport(c.Request.Context(), req.GeofenceID, time.Unix(req.Start, 0), time.Unix(req.End, 0))
+	if err != nil {
+		if errors.Is(err, domain.ErrGeofenceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "geofence not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to run visit report"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toVisitReportResponse(report))
+}
+
+func (h *ReportHandler) GetVisitReport(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report id"})
+		return
+	}
+
+	report, err := h.reportSvc.GetVisitReport(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, domain.ErrReportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "visit report not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch visit report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toVisitReportResponse(report))
+}
+
+func validateVisitReportRequest(req *visitReportRequest) error {
+	if req.GeofenceID == "" {
+		return fmt.Errorf("geofence_id: required")
+	}
+	if req.Start <= 0 || req.End <= 0 {
+		return fmt.Errorf("start and end: required unix timestamps")
+	}
+	if req.End < req.Start {
+		return fmt.Errorf("end: must not be before start")
+	}
+	if time.Duration(req.End-req.Start)*time.Second > maxVisitReportDays*24*time.Hour {
+		return fmt.Errorf("end: must be within %d days of start", maxVisitReportDays)
+	}
+	return nil
+}
+
+func toVisitReportResponse(report *domain.VisitReport) visitReportResponse {
+	return visitReportResponse{
+		ID:               report.ID,
+		GeofenceID:       report.GeofenceID,
+		Start:            report.Start.Unix(),
+		End:              report.End.Unix(),
+		VehiclesScanned:  report.VehiclesScanned,
+		LocationsScanned: report.LocationsScanned,
+		CreatedAt:        report.CreatedAt.Unix(),
+		Visits:           toVisitResponses(report.Visits),
+	}
+}
+
+func toVisitResponses(visits []domain.GeofenceVisit) []visitResponse {
+	results := make([]visitResponse, len(visits))
+	for i, v := range visits {
+		results[i] = visitResponse{
+			VehicleID:       v.VehicleID,
+			GeofenceID:      v.GeofenceID,
+			EnteredAt:       v.EnteredAt.Unix(),
+			DurationSeconds: int64(v.Duration / time.Second),
+		}
+		if !v.ExitedAt.IsZero() {
+			exited := v.ExitedAt.Unix()
+			results[i].ExitedAt = &exited
+		}
+	}
+	return results
+}
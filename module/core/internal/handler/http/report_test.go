@@ -0,0 +1,160 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+type mockReportService struct {
+	runFn func(ctx context.Context, geofenceID string, start, end time.Time) (*domain.VisitReport, error)
+	getFn func(ctx context.Context, id int64) (*domain.VisitReport, error)
+}
+
+func (m *mockReportService) RunVisitReport(ctx context.Context, geofenceID string, start, end time.Time) (*domain.VisitReport, error) {
+	return m.runFn(ctx, geofenceID, start, end)
+}
+
+func (m *mockReportService) GetVisitReport(ctx context.Context, id int64) (*domain.VisitReport, error) {
+	return m.getFn(ctx, id)
+}
+
+func setupReportRouter(svc reportService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := NewReportHandler(svc)
+	h.Register(r.Group(""))
+	return r
+}
+
+func TestRunVisitReport_Success(t *testing.T) {
+	svc := &mockReportService{
+		runFn: func(_ context.Context, geofenceID string, start, end time.Time) (*domain.VisitReport, error) {
+			entered := time.Unix(1715003456, 0)
+			return &domain.VisitReport{
+				ID:         3,
+				GeofenceID: geofenceID,
+				Start:      start,
+				End:        end,
+				CreatedAt:  end,
+				Visits: []domain.GeofenceVisit{
+					{VehicleID: "B1234XYZ", GeofenceID: geofenceID, EnteredAt: entered, ExitedAt: entered.Add(time.Hour), Duration: time.Hour},
+					{VehicleID: "B5678ABC", GeofenceID: geofenceID, EnteredAt: entered, Duration: time.Minute},
+				},
+			}, nil
+		},
+	}
+
+	r := setupReportRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/visit-reports", bytes.NewBufferString(`{"geofence_id":"terminal","start":1715000000,"end":1715086400}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	want := `{"id":3,"geofence_id":"terminal","start":1715000000,"end":1715086400,"vehicles_scanned":0,"locations_scanned":0,"created_at":1715086400,"visits":[` +
+		`{"vehicle_id":"B1234XYZ","geofence_id":"terminal","entered_at":1715003456,"exited_at":1715007056,"duration_seconds":3600},` +
+		`{"vehicle_id":"B5678ABC","geofence_id":"terminal","entered_at":1715003456,"exited_at":null,"duration_seconds":60}]}`
+	if got := w.Body.String(); got != want {
+		t.Errorf("unexpected response:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestRunVisitReport_ValidationError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing geofence", `{"start":1,"end":2}`},
+		{"missing range", `{"geofence_id":"terminal"}`},
+		{"end before start", `{"geofence_id":"terminal","start":20,"end":10}`},
+		{"range too long", `{"geofence_id":"terminal","start":1715000000,"end":1717678401}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := setupReportRouter(&mockReportService{})
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/admin/visit-reports", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestRunVisitReport_GeofenceNotFound(t *testing.T) {
+	svc := &mockReportService{
+		runFn: func(_ context.Context, _ string, _, _ time.Time) (*domain.VisitReport, error) {
+			return nil, domain.ErrGeofenceNotFound
+		},
+	}
+
+	r := setupReportRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/visit-reports", bytes.NewBufferString(`{"geofence_id":"missing","start":1,"end":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestGetVisitReport(t *testing.T) {
+	svc := &mockReportService{
+		getFn: func(_ context.Context, id int64) (*domain.VisitReport, error) {
+			if id != 3 {
+				return nil, domain.ErrReportNotFound
+			}
+			return &domain.VisitReport{ID: 3, GeofenceID: "terminal"}, nil
+		},
+	}
+	r := setupReportRouter(svc)
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/admin/visit-reports/3", http.StatusOK},
+		{"/admin/visit-reports/4", http.StatusNotFound},
+		{"/admin/visit-reports/abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.code, w.Code)
+		}
+	}
+}
+
+func TestGetVisitReport_ServiceError(t *testing.T) {
+	svc := &mockReportService{
+		getFn: func(_ context.Context, _ int64) (*domain.VisitReport, error) {
+			return nil, errors.New("db down")
+		},
+	}
+
+	r := setupReportRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin/visit-reports/3", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+}
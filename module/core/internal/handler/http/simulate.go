@@ -0,0 +1,126 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+// maxSimulatePoints bounds a simulated track, which is evaluated while the
+// geofence service holds its lock.
+const maxSimulatePoints = 10000
+
+type simulatePoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+type simulateRequest struct {
+	VehicleID string          `json:"vehicle_id"`
+	Point     *simulatePoint  `json:"point"`
+	Points    []simulatePoint `json:"points"`
+}
+
+type simulatedEvent struct {
+	Event        string  `json:"event"`
+	GeofenceID   string  `json:"geofence_id"`
+	Name         string  `json:"name"`
+	Distance     float64 `json:"distance"`
+	DwellSeconds int64   `json:"dwell_seconds,omitempty"`
+}
+
+type simulateStepResponse struct {
+	Latitude  float64          `json:"latitude"`
+	Longitude float64          `json:"longitude"`
+	Timestamp int64            `json:"timestamp"`
+	Geofences []string         `json:"geofences"`
+	Events    []simulatedEvent `json:"events"`
+}
+
+// SimulateGeofences reports which geofences contain each point of a track and
+// which alerts the geofence service would publish for it, without publishing.
+func (h *GeofenceHandler) SimulateGeofences(c *gin.Context) {
+	var req simulateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	track, err := toSimulateTrack(&req, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	steps, err := h.geofenceSvc.Simulate(c.Request.Context(), req.VehicleID, track)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to simulate geofences"})
+		return
+	}
+
+	results := make([]simulateStepResponse, len(steps))
+	for i, step := range steps {
+		results[i] = simulateStepResponse{
+			Latitude:  step.Location.Lat,
+			Longitude: step.Location.Lon,
+			Timestamp: step.Location.Timestamp.Unix(),
+			Geofences: nonNil(step.Inside),
+			Events:    make([]simulatedEvent, len(step.Alerts)),
+		}
+		for j, alert := range step.Alerts {
+			results[i].Events[j] = simulatedEvent{
+				Event:        string(alert.Event),
+				GeofenceID:   alert.Geofence.ID,
+				Name:         alert.Geofence.Name,
+				Distance:     alert.Distance,
+				DwellSeconds: int64(alert.Dwell / time.Second),
+			}
+		}
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// toSimulateTrack validates the request and returns its points in order. A
+// single point without a timestamp is evaluated at now; track points need
+// timestamps in non-decreasing order.
+func toSimulateTrack(req *simulateRequest, now time.Time) ([]domain.Location, error) {
+	if err := domain.ValidateVehicleID(req.VehicleID); err != nil {
+		return nil, fmt.Errorf("vehicle_id: %w", err)
+	}
+
+	points := req.Points
+	switch {
+	case req.Point != nil && len(points) > 0:
+		return nil, fmt.Errorf("point and points are mutually exclusive")
+	case req.Point != nil:
+		points = []simulatePoint{*req.Point}
+	case len(points) == 0:
+		return nil, fmt.Errorf("point or points: required")
+	case len(points) > maxSimulatePoints:
+		return nil, fmt.Errorf("points: at most %d allowed", maxSimulatePoints)
+	}
+
+	track := make([]domain.Location, len(points))
+	for i, pt := range points {
+		if err := validateCoordinate(pt.Latitude, pt.Longitude); err != nil {
+			return nil, fmt.Errorf("points[%d]: %w", i, err)
+		}
+
+		ts := time.Unix(pt.Timestamp, 0)
+		switch {
+		case pt.Timestamp == 0 && len(points) == 1:
+			ts = now
+		case pt.Timestamp <= 0:
+			return nil, fmt.Errorf("points[%d]: timestamp must be positive", i)
+		case i > 0 && ts.Before(track[i-1].Timestamp):
+			return nil, fmt.Errorf("points[%d]: timestamps must not decrease", i)
+		}
+		track[i] = domain.Location{Lat: pt.Latitude, Lon: pt.Longitude, Timestamp: ts}
+	}
+	return track, nil
+}
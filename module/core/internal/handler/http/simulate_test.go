@@ -0,0 +1,117 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+func TestSimulateGeofences_Track(t *testing.T) {
+	var gotVehicle string
+	var gotTrack []domain.Location
+	svc := &mockGeofenceService{
+		simulateFn: func(_ context.Context, vehicleID string, track []domain.Location) ([]domain.SimulationStep, error) {
+			gotVehicle, gotTrack = vehicleID, track
+			gf := domain.Geofence{ID: "terminal", Name: "Terminal"}
+			return []domain.SimulationStep{
+				{Location: track[0]},
+				{Location: track[1], Inside: []string{"terminal"}, Alerts: []domain.GeofenceAlert{
+					{Event: domain.GeofenceEntry, Geofence: gf, Distance: 12.5},
+				}},
+			}, nil
+		},
+	}
+
+	body := `{"vehicle_id":"B1234XYZ","points":[
+		{"latitude":-6.3,"longitude":106.8,"timestamp":1715003456},
+		{"latitude":-6.2088,"longitude":106.8456,"timestamp":1715003516}]}`
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/geofences/simulate", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if gotVehicle != "B1234XYZ" || len(gotTrack) != 2 || !gotTrack[1].Timestamp.Equal(time.Unix(1715003516, 0)) {
+		t.Errorf("unexpected track passed to service: %s %+v", gotVehicle, gotTrack)
+	}
+
+	var resp []simulateStepResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(resp) != 2 || resp[0].Geofences == nil || len(resp[0].Events) != 0 {
+		t.Fatalf("unexpected first step: %s", w.Body.String())
+	}
+	if ev := resp[1].Events; len(ev) != 1 || ev[0].Event != "geofence_entry" || ev[0].GeofenceID != "terminal" || ev[0].Distance != 12.5 {
+		t.Errorf("unexpected events: %+v", ev)
+	}
+}
+
+func TestSimulateGeofences_SinglePointDefaultsToNow(t *testing.T) {
+	var gotTrack []domain.Location
+	svc := &mockGeofenceService{
+		simulateFn: func(_ context.Context, _ string, track []domain.Location) ([]domain.SimulationStep, error) {
+			gotTrack = track
+			return []domain.SimulationStep{{Location: track[0]}}, nil
+		},
+	}
+
+	before := time.Now().Add(-time.Second)
+	r := setupGeofenceRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/geofences/simulate", bytes.NewBufferString(`{"point":{"latitude":-6.2088,"longitude":106.8456}}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(gotTrack) != 1 || gotTrack[0].Timestamp.Before(before) {
+		t.Errorf("expected single point at now, got %+v", gotTrack)
+	}
+}
+
+func TestSimulateGeofences_ValidationError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty", `{}`},
+		{"both point and points", `{"point":{"latitude":1,"longitude":1},"points":[{"latitude":1,"longitude":1,"timestamp":1}]}`},
+		{"bad coordinate", `{"point":{"latitude":91,"longitude":1}}`},
+		{"track without timestamps", `{"points":[{"latitude":1,"longitude":1},{"latitude":1,"longitude":1}]}`},
+		{"decreasing timestamps", `{"points":[{"latitude":1,"longitude":1,"timestamp":20},{"latitude":1,"longitude":1,"timestamp":10}]}`},
+		{"long vehicle id", `{"vehicle_id":"` + strings.Repeat("B", 51) + `","point":{"latitude":1,"longitude":1}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := setupGeofenceRouter(&mockGeofenceService{})
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/geofences/simulate", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestToSimulateTrack_CountsVehicleIDCharacters(t *testing.T) {
+	// 50 characters of two bytes each fit the column
+	req := &simulateRequest{VehicleID: strings.Repeat("é", 50), Point: &simulatePoint{Latitude: 1, Longitude: 1}}
+	if _, err := toSimulateTrack(req, time.Unix(1715003456, 0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+type trackerService interface {
+	ListTrackers(ctx context.Context) ([]domain.Tracker, error)
+	SetTracker(ctx context.Context, t *domain.Tracker) error
+	DeleteTracker(ctx context.Context, imei string) error
+}
+
+type trackerBody struct {
+	IMEI      string `json:"imei"`
+	VehicleID string `json:"vehicle_id"`
+}
+
+type TrackerHandler struct {
+	trackerSvc trackerService
+}
+
+func NewTrackerHandler(trackerSvc trackerService) *TrackerHandler {
+	return &TrackerHandler{trackerSvc: trackerSvc}
+}
+
+func (h *TrackerHandler) Register(r *gin.RouterGroup) {
+	r.GET("/admin/trackers", h.ListTrackers)
+	r.PUT("/admin/trackers/:imei", h.SetTracker)
+	r.DELETE("/admin/trackers/:imei", h.DeleteTracker)
+}
+
+func (h *TrackerHandler) ListTrackers(c *gin.Context) {
+	trackers, err := h.trackerSvc.ListTrackers(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch trackers"})
+		return
+	}
+
+	results := make([]trackerBody, len(trackers))
+	for i, t := range trackers {
+		results[i] = trackerBody{IMEI: t.IMEI, VehicleID: t.VehicleID}
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// SetTracker registers the tracker with the given IMEI for a vehicle, or moves
+// it to another one. The tracker's next connection uses the new vehicle.
+func (h *TrackerHandler) SetTracker(c *gin.Context) {
+	imei := c.Param("imei")
+	if !validIMEI(imei) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "imei: must be 15 digits"})
+		return
+	}
+
+	var body trackerBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if body.VehicleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "vehicle_id: required"})
+		return
+	}
+	if err := domain.ValidateVehicleID(body.VehicleID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "vehicle_id: " + err.Error()})
+		return
+	}
+
+	t := &domain.Tracker{IMEI: imei, VehicleID: body.VehicleID}
+	if err := h.trackerSvc.SetTracker(c.Request.Context(), t); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save tracker"})
+		return
+	}
+	c.JSON(http.StatusOK, trackerBody{IMEI: t.IMEI, VehicleID: t.VehicleID})
+}
+
+func (h *TrackerHandler) DeleteTracker(c *gin.Context) {
+	if err := h.trackerSvc.DeleteTracker(c.Request.Context(), c.Param("imei")); err != nil {
+		if errors.Is(err, domain.ErrTrackerNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tracker not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete tracker"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func validIMEI(imei string) bool {
+	if len(imei) != 15 {
+		return false
+	}
+	for _, r := range imei {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
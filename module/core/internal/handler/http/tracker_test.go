@@ -0,0 +1,124 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+type mockTrackerService struct {
+	listFn   func(ctx context.Context) ([]domain.Tracker, error)
+	setFn    func(ctx context.Context, t *domain.Tracker) error
+	deleteFn func(ctx context.Context, imei string) error
+}
+
+func (m *mockTrackerService) ListTrackers(ctx context.Context) ([]domain.Tracker, error) {
+	return m.listFn(ctx)
+}
+
+func (m *mockTrackerService) SetTracker(ctx context.Context, t *domain.Tracker) error {
+	return m.setFn(ctx, t)
+}
+
+func (m *mockTrackerService) DeleteTracker(ctx context.Context, imei string) error {
+	return m.deleteFn(ctx, imei)
+}
+
+func setupTrackerRouter(svc trackerService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := NewTrackerHandler(svc)
+	h.Register(r.Group(""))
+	return r
+}
+
+func TestListTrackers_Success(t *testing.T) {
+	svc := &mockTrackerService{
+		listFn: func(context.Context) ([]domain.Tracker, error) {
+			return []domain.Tracker{{IMEI: "356307042441013", VehicleID: "B1234XYZ"}}, nil
+		},
+	}
+
+	r := setupTrackerRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin/trackers", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if want := `[{"imei":"356307042441013","vehicle_id":"B1234XYZ"}]`; w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestSetTracker_Success(t *testing.T) {
+	var saved *domain.Tracker
+	svc := &mockTrackerService{
+		setFn: func(_ context.Context, tr *domain.Tracker) error {
+			saved = tr
+			return nil
+		},
+	}
+
+	r := setupTrackerRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/admin/trackers/356307042441013", strings.NewReader(`{"vehicle_id":"B1234XYZ"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if saved == nil || saved.IMEI != "356307042441013" || saved.VehicleID != "B1234XYZ" {
+		t.Errorf("unexpected tracker saved: %+v", saved)
+	}
+}
+
+func TestSetTracker_Invalid(t *testing.T) {
+	tests := []struct {
+		imei string
+		body string
+		want string
+	}{
+		{"35630704244101", `{"vehicle_id":"B1234XYZ"}`, `{"error":"imei: must be 15 digits"}`},
+		{"35630704244101x", `{"vehicle_id":"B1234XYZ"}`, `{"error":"imei: must be 15 digits"}`},
+		{"356307042441013", `{}`, `{"error":"vehicle_id: required"}`},
+		{"356307042441013", `{"vehicle_id":"` + strings.Repeat("B", 51) + `"}`, `{"error":"vehicle_id: must be at most 50 characters"}`},
+		{"356307042441013", `{"vehicle_id":"B1234/XYZ"}`, `{"error":"vehicle_id: must not contain '/', '+' or '#'"}`},
+		{"356307042441013", `{"vehicle_id":"B1234#"}`, `{"error":"vehicle_id: must not contain '/', '+' or '#'"}`},
+		{"356307042441013", `oops`, `{"error":"invalid request body"}`},
+	}
+	for _, tt := range tests {
+		r := setupTrackerRouter(&mockTrackerService{})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/admin/trackers/"+tt.imei, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest || w.Body.String() != tt.want {
+			t.Errorf("%s %s: expected 400 %s, got %d %s", tt.imei, tt.body, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestDeleteTracker_NotFound(t *testing.T) {
+	svc := &mockTrackerService{
+		deleteFn: func(context.Context, string) error { return domain.ErrTrackerNotFound },
+	}
+
+	r := setupTrackerRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/admin/trackers/356307042441013", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
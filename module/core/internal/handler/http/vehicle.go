@@ -17,11 +17,20 @@ type locationService interface {
 	GetAllVehicles(ctx context.Context) ([]domain.Vehicle, error)
 }
 
+// locationResponse omits telemetry the device did not report.
 type locationResponse struct {
 	VehicleID string  `json:"vehicle_id"`
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 	Timestamp int64   `json:"timestamp"`
+
+	Speed      *float64 `json:"speed,omitempty"`
+	Heading    *float64 `json:"heading,omitempty"`
+	Altitude   *float64 `json:"altitude,omitempty"`
+	Accuracy   *float64 `json:"accuracy,omitempty"`
+	Satellites *int     `json:"satellites,omitempty"`
+	Ignition   *bool    `json:"ignition,omitempty"`
+	Odometer   *float64 `json:"odometer,omitempty"`
 }
 
 type VehicleHandler struct {
@@ -100,5 +109,13 @@ func toLocationResponse(vl *domain.VehicleLocation) locationResponse {
 		Latitude:  vl.Location.Lat,
 		Longitude: vl.Location.Lon,
 		Timestamp: vl.Location.Timestamp.Unix(),
+
+		Speed:      vl.Telemetry.Speed,
+		Heading:    vl.Telemetry.Heading,
+		Altitude:   vl.Telemetry.Altitude,
+		Accuracy:   vl.Telemetry.Accuracy,
+		Satellites: vl.Telemetry.Satellites,
+		Ignition:   vl.Telemetry.Ignition,
+		Odometer:   vl.Telemetry.Odometer,
 	}
 }
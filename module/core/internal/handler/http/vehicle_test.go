@@ -78,6 +78,29 @@ func TestGetLatestLocation_Success(t *testing.T) {
 	}
 }
 
+func TestGetLatestLocation_Telemetry(t *testing.T) {
+	speed, satellites, ignition := 42.5, 9, false
+	svc := &mockLocationService{
+		getLatestFn: func(_ context.Context, _ string) (*domain.VehicleLocation, error) {
+			return &domain.VehicleLocation{
+				VehicleID: "B1234XYZ",
+				Location:  domain.Location{Lat: -6.2088, Lon: 106.8456, Timestamp: time.Unix(1715003456, 0)},
+				Telemetry: domain.Telemetry{Speed: &speed, Satellites: &satellites, Ignition: &ignition},
+			}, nil
+		},
+	}
+
+	r := setupRouter(svc)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/vehicles/B1234XYZ/location", nil)
+	r.ServeHTTP(w, req)
+
+	want := `{"vehicle_id":"B1234XYZ","latitude":-6.2088,"longitude":106.8456,"timestamp":1715003456,"speed":42.5,"satellites":9,"ignition":false}`
+	if got := w.Body.String(); got != want {
+		t.Errorf("unexpected response:\n got %s\nwant %s", got, want)
+	}
+}
+
 func TestGetLatestLocation_NotFound(t *testing.T) {
 	svc := &mockLocationService{
 		getLatestFn: func(_ context.Context, _ string) (*domain.VehicleLocation, error) {
@@ -0,0 +1,141 @@
+package subscriber
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nandanugg/tj-test/proto/fleetv1"
+)
+
+// payloadCodec decodes a message payload into the location messages it
+// carries and reports whether it was a batch. The codec is chosen by the
+// format segment of the topic the message arrived on.
+type payloadCodec interface {
+	decode(payload []byte) (msgs []locationMessage, batch bool, err error)
+}
+
+// codecs maps a topic's format segment to its codec. The bare location topic
+// carries JSON.
+var codecs = map[string]payloadCodec{
+	"":         jsonCodec{},
+	"json":     jsonCodec{},
+	"protobuf": protobufCodec{},
+	"cbor":     cborCodec{},
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) decode(payload []byte) ([]locationMessage, bool, error) {
+	trimmed := bytes.TrimSpace(payload)
+	isArray := len(trimmed) > 0 && trimmed[0] == '['
+
+	msgs, batch, err := unmarshalLocations(payload, isArray, json.Unmarshal)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid json: %w", err)
+	}
+	return msgs, batch, nil
+}
+
+// cborCodec decodes the same maps as JSON, keyed by the JSON field names.
+type cborCodec struct{}
+
+var cborHandle codec.CborHandle
+
+func (cborCodec) decode(payload []byte) ([]locationMessage, bool, error) {
+	// major type 4 is an array
+	isArray := len(payload) > 0 && payload[0]>>5 == 4
+
+	msgs, batch, err := unmarshalLocations(payload, isArray, func(b []byte, v any) error {
+		return codec.NewDecoderBytes(b, &cborHandle).Decode(v)
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid cbor: %w", err)
+	}
+	return msgs, batch, nil
+}
+
+// unmarshalLocations decodes a single location, an array of locations or a
+// batch envelope. unmarshal must leave fields absent from the payload
+// untouched, so a single location can be told apart from an envelope.
+func unmarshalLocations(payload []byte, isArray bool, unmarshal func([]byte, any) error) ([]locationMessage, bool, error) {
+	if isArray {
+		var msgs []locationMessage
+		if err := unmarshal(payload, &msgs); err != nil {
+			return nil, false, err
+		}
+		return msgs, true, nil
+	}
+
+	var env batchMessage
+	if err := unmarshal(payload, &env); err != nil {
+		return nil, false, err
+	}
+	if env.Points != nil {
+		for i := range env.Points {
+			if env.Points[i].VehicleID == "" {
+				env.Points[i].VehicleID = env.VehicleID
+			}
+		}
+		return env.Points, true, nil
+	}
+
+	var raw locationMessage
+	if err := unmarshal(payload, &raw); err != nil {
+		return nil, false, err
+	}
+	return []locationMessage{raw}, false, nil
+}
+
+// protobufCodec decodes the Location message of proto/location.proto. A
+// message with points is a batch, and its vehicle_id applies to points that
+// have none of their own. Protobuf does not encode empty repeated fields, so
+// an empty batch cannot be told apart from a single location.
+type protobufCodec struct{}
+
+func (protobufCodec) decode(payload []byte) ([]locationMessage, bool, error) {
+	var pb fleetv1.Location
+	if err := proto.Unmarshal(payload, &pb); err != nil {
+		return nil, false, fmt.Errorf("invalid protobuf: %w", err)
+	}
+	if len(pb.Points) == 0 {
+		return []locationMessage{locationFromProto(&pb)}, false, nil
+	}
+
+	msgs := make([]locationMessage, len(pb.Points))
+	for i, point := range pb.Points {
+		if len(point.Points) > 0 {
+			return nil, false, fmt.Errorf("invalid protobuf: points[%d]: batches cannot be nested", i)
+		}
+		msgs[i] = locationFromProto(point)
+		if msgs[i].VehicleID == "" {
+			msgs[i].VehicleID = pb.VehicleId
+		}
+	}
+	return msgs, true, nil
+}
+
+// locationFromProto converts a decoded Location, leaving fields that were
+// absent from the payload nil.
+func locationFromProto(pb *fleetv1.Location) locationMessage {
+	msg := locationMessage{
+		VehicleID: pb.VehicleId,
+		Latitude:  pb.Latitude,
+		Longitude: pb.Longitude,
+		Timestamp: pb.Timestamp,
+		Speed:     pb.Speed,
+		Heading:   pb.Heading,
+		Altitude:  pb.Altitude,
+		Accuracy:  pb.Accuracy,
+		Ignition:  pb.Ignition,
+		Odometer:  pb.Odometer,
+	}
+	if pb.Satellites != nil {
+		satellites := int(*pb.Satellites)
+		msg.Satellites = &satellites
+	}
+	return msg
+}
@@ -0,0 +1,165 @@
+package subscriber
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/proto/fleetv1"
+)
+
+func marshalProto(t *testing.T, pb *fleetv1.Location) []byte {
+	t.Helper()
+	b, err := proto.Marshal(pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func protoLocation(t *testing.T, lat, lon float64, ts int64) []byte {
+	return marshalProto(t, &fleetv1.Location{Latitude: lat, Longitude: lon, Timestamp: ts})
+}
+
+func TestProtobufCodec_Single(t *testing.T) {
+	b := marshalProto(t, &fleetv1.Location{
+		VehicleId:  "B1234XYZ",
+		Latitude:   -6.2088,
+		Longitude:  106.8456,
+		Timestamp:  1715003456,
+		Speed:      proto.Float64(0),
+		Satellites: proto.Int32(9),
+		Ignition:   proto.Bool(true),
+	})
+	// an unknown field from a newer schema is skipped
+	b = protowire.AppendTag(b, 99, protowire.VarintType)
+	b = protowire.AppendVarint(b, 1)
+
+	msgs, batch, err := protobufCodec{}.decode(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if batch || len(msgs) != 1 {
+		t.Fatalf("expected a single location, got %d (batch %v)", len(msgs), batch)
+	}
+	m := msgs[0]
+	if m.VehicleID != "B1234XYZ" || m.Latitude != -6.2088 || m.Longitude != 106.8456 || m.Timestamp != 1715003456 {
+		t.Errorf("unexpected location: %+v", m)
+	}
+	if m.Speed == nil || *m.Speed != 0 || *m.Satellites != 9 || !*m.Ignition {
+		t.Errorf("unexpected telemetry: %+v", m)
+	}
+	if m.Heading != nil || m.Odometer != nil {
+		t.Errorf("expected absent fields to stay nil: %+v", m)
+	}
+}
+
+func TestProtobufCodec_Batch(t *testing.T) {
+	b := marshalProto(t, &fleetv1.Location{
+		VehicleId: "B1234XYZ",
+		Points: []*fleetv1.Location{
+			{Latitude: -6.2, Longitude: 106.8, Timestamp: 1715003456},
+			{Latitude: -6.2, Longitude: 106.8, Timestamp: 1715003466},
+		},
+	})
+
+	msgs, batch, err := protobufCodec{}.decode(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !batch || len(msgs) != 2 {
+		t.Fatalf("expected a batch of 2, got %d (batch %v)", len(msgs), batch)
+	}
+	if msgs[0].VehicleID != "B1234XYZ" || msgs[1].Timestamp != 1715003466 {
+		t.Errorf("unexpected points: %+v", msgs)
+	}
+}
+
+func TestProtobufCodec_Invalid(t *testing.T) {
+	batchOfBatch := marshalProto(t, &fleetv1.Location{
+		Points: []*fleetv1.Location{
+			{Points: []*fleetv1.Location{{Latitude: -6.2, Longitude: 106.8, Timestamp: 1715003456}}},
+		},
+	})
+	invalidUTF8 := protowire.AppendTag(nil, 1, protowire.BytesType)
+	invalidUTF8 = protowire.AppendString(invalidUTF8, "B1234\xff")
+
+	tests := map[string][]byte{
+		"truncated":    protoLocation(t, -6.2, 106.8, 1715003456)[:5],
+		"invalid utf8": invalidUTF8,
+		"nested batch": batchOfBatch,
+	}
+	for name, payload := range tests {
+		if _, _, err := (protobufCodec{}).decode(payload); err == nil || !strings.HasPrefix(err.Error(), "invalid protobuf: ") {
+			t.Errorf("%s: expected an invalid protobuf error, got %v", name, err)
+		}
+	}
+}
+
+func TestCBORCodec(t *testing.T) {
+	encode := func(v any) []byte {
+		var b []byte
+		codec.NewEncoderBytes(&b, &cborHandle).MustEncode(v)
+		return b
+	}
+
+	msgs, batch, err := cborCodec{}.decode(encode(map[string]any{
+		"latitude": -6.2088, "longitude": 106.8456, "timestamp": 1715003456, "heading": 90,
+	}))
+	if err != nil || batch || len(msgs) != 1 {
+		t.Fatalf("expected a single location, got %v (batch %v): %v", msgs, batch, err)
+	}
+	if msgs[0].Latitude != -6.2088 || msgs[0].Heading == nil || *msgs[0].Heading != 90 || msgs[0].Speed != nil {
+		t.Errorf("unexpected location: %+v", msgs[0])
+	}
+
+	points := []map[string]any{
+		{"latitude": -6.2, "longitude": 106.8, "timestamp": 1715003456},
+		{"latitude": -6.3, "longitude": 106.9, "timestamp": 1715003466},
+	}
+	for name, payload := range map[string][]byte{
+		"array":    encode(points),
+		"envelope": encode(map[string]any{"vehicle_id": "B1234XYZ", "points": points}),
+	} {
+		msgs, batch, err := cborCodec{}.decode(payload)
+		if err != nil || !batch || len(msgs) != 2 {
+			t.Errorf("%s: expected a batch of 2, got %v (batch %v): %v", name, msgs, batch, err)
+		}
+	}
+
+	if _, _, err := (cborCodec{}).decode([]byte{0xff}); err == nil || !strings.HasPrefix(err.Error(), "invalid cbor: ") {
+		t.Errorf("expected an invalid cbor error, got %v", err)
+	}
+}
+
+func TestHandleMessage_ProtobufTopic(t *testing.T) {
+	var saved *domain.VehicleLocation
+	locSvc := &mockLocationSvc{
+		saveLocationFn: func(_ context.Context, vl *domain.VehicleLocation) error {
+			saved = vl
+			return nil
+		},
+	}
+	geoSvc := &mockGeofenceSvc{
+		checkAndAlertFn: func(context.Context, *domain.VehicleLocation) error { return nil },
+	}
+	sub := newTestSubscriber(locSvc, geoSvc, nil)
+
+	msg := &fakeMQTTMessage{
+		topic:   "/fleet/vehicle/B1234XYZ/location/protobuf",
+		payload: protoLocation(t, -6.2088, 106.8456, 1715003456),
+	}
+	deliver(t, sub, msg)
+
+	if saved == nil {
+		t.Fatal("expected SaveLocation to be called")
+	}
+	if saved.VehicleID != "B1234XYZ" || saved.Location.Lat != -6.2088 {
+		t.Errorf("unexpected location: %+v", saved)
+	}
+}
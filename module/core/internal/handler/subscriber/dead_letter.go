@@ -0,0 +1,72 @@
+package subscriber
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/queue"
+)
+
+const (
+	// deadLetterQueueSize bounds the rejected messages waiting to be
+	// recorded.
+	deadLetterQueueSize = 256
+	// deadLetterTimeout bounds recording a single dead letter.
+	deadLetterTimeout = 5 * time.Second
+)
+
+// deadLetterQueue records rejected messages on its own goroutine, so a slow
+// database does not hold up paho's callback. A message rejected while the
+// queue is full is logged and counted as dead_letters_dropped instead.
+type deadLetterQueue struct {
+	svc   deadLetterService
+	queue *queue.Queue[*domain.DeadLetter]
+	done  chan struct{}
+}
+
+func newDeadLetterQueue(svc deadLetterService) *deadLetterQueue {
+	q := &deadLetterQueue{
+		svc:   svc,
+		queue: queue.New[*domain.DeadLetter](deadLetterQueueSize),
+		done:  make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// add queues dl to be recorded without waiting.
+func (q *deadLetterQueue) add(dl *domain.DeadLetter) {
+	ok, err := q.queue.TryPush(dl)
+	switch {
+	case errors.Is(err, queue.ErrClosed):
+		log.Printf("subscriber stopped, dead letter for %s not recorded", dl.Topic)
+	case !ok:
+		poolMetrics.Add("dead_letters_dropped", 1)
+		log.Printf("dead letter queue full, dead letter for %s not recorded", dl.Topic)
+	}
+}
+
+// stop waits for the queued dead letters to be recorded, or until ctx is
+// done.
+func (q *deadLetterQueue) stop(ctx context.Context) error {
+	q.queue.Close()
+	return queue.Wait(ctx, q.done)
+}
+
+func (q *deadLetterQueue) run() {
+	defer close(q.done)
+	for dl := range q.queue.C() {
+		q.record(dl)
+	}
+}
+
+func (q *deadLetterQueue) record(dl *domain.DeadLetter) {
+	ctx, cancel := context.WithTimeout(context.Background(), deadLetterTimeout)
+	defer cancel()
+	if err := q.svc.RecordDeadLetter(ctx, dl); err != nil {
+		log.Printf("record dead letter error: %v", err)
+	}
+}
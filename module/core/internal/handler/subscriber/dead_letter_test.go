@@ -0,0 +1,74 @@
+package subscriber
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+// blockingDeadLetterSvc blocks every RecordDeadLetter until release is
+// closed.
+type blockingDeadLetterSvc struct {
+	release chan struct{}
+
+	mu          sync.Mutex
+	recorded    int
+	hasDeadline bool
+}
+
+func (m *blockingDeadLetterSvc) RecordDeadLetter(ctx context.Context, _ *domain.DeadLetter) error {
+	<-m.release
+	_, ok := ctx.Deadline()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recorded++
+	m.hasDeadline = ok
+	return nil
+}
+
+func droppedDeadLetters() int64 {
+	if v, ok := poolMetrics.Get("dead_letters_dropped").(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestDeadLetterQueue_DoesNotBlockWhenFull(t *testing.T) {
+	svc := &blockingDeadLetterSvc{release: make(chan struct{})}
+	q := newDeadLetterQueue(svc)
+	droppedBefore := droppedDeadLetters()
+
+	// one dead letter is taken by the goroutine and blocks there, the queue
+	// holds deadLetterQueueSize more and the rest are dropped
+	added := make(chan struct{})
+	go func() {
+		for range deadLetterQueueSize + 10 {
+			q.add(&domain.DeadLetter{Topic: "/fleet/vehicle/B1234XYZ/location"})
+		}
+		close(added)
+	}()
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Fatal("expected add not to wait for the database")
+	}
+
+	close(svc.release)
+	if err := q.stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	recorded := int64(svc.recorded)
+	if dropped := droppedDeadLetters() - droppedBefore; recorded+dropped != deadLetterQueueSize+10 || dropped == 0 {
+		t.Errorf("expected every dead letter recorded or dropped, got %d recorded and %d dropped", recorded, dropped)
+	}
+	if !svc.hasDeadline {
+		t.Error("expected dead letters to be recorded with a timeout")
+	}
+}
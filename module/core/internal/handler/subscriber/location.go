@@ -2,9 +2,10 @@ package subscriber
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
@@ -12,80 +13,301 @@ import (
 	"github.com/nandanugg/tj-test/module/core/domain"
 )
 
-const topicPattern = "/fleet/vehicle/+/location"
+const (
+	// topicPattern carries JSON and formatTopicPattern the other payload
+	// formats, named by the last topic segment.
+	topicPattern       = "/fleet/vehicle/+/location"
+	formatTopicPattern = "/fleet/vehicle/+/location/+"
+	topicPrefix        = "/fleet/vehicle/"
+	topicKind          = "location"
+
+	// maxBatchPoints bounds how many locations one message may carry.
+	maxBatchPoints = 1000
+
+	// maxSatellites is the limit of the satellites SMALLINT column; the
+	// satellite count is one byte in every tracker protocol.
+	maxSatellites = 255
+)
+
+// unsubscribeTimeout bounds how long Stop waits for the broker to confirm the
+// unsubscribe, leaving the rest of the shutdown time to drain the workers.
+var unsubscribeTimeout = 2 * time.Second
 
 type locationService interface {
-	SaveLocation(ctx context.Context, vl *domain.VehicleLocation) error
+	SaveLocation(ctx context.Context, vl *domain.VehicleLocation) (live bool, err error)
+	SaveLocations(ctx context.Context, vls []domain.VehicleLocation) (live []bool, err error)
 }
 
 type geofenceService interface {
 	CheckAndAlert(ctx context.Context, vl *domain.VehicleLocation) error
 }
 
+type deadLetterService interface {
+	RecordDeadLetter(ctx context.Context, dl *domain.DeadLetter) error
+}
+
 type locationMessage struct {
 	VehicleID string  `json:"vehicle_id"`
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 	Timestamp int64   `json:"timestamp"`
+
+	Speed      *float64 `json:"speed,omitempty"`
+	Heading    *float64 `json:"heading,omitempty"`
+	Altitude   *float64 `json:"altitude,omitempty"`
+	Accuracy   *float64 `json:"accuracy,omitempty"`
+	Satellites *int     `json:"satellites,omitempty"`
+	Ignition   *bool    `json:"ignition,omitempty"`
+	Odometer   *float64 `json:"odometer,omitempty"`
+}
+
+// batchMessage is the envelope form of a batch. Its vehicle_id, if set,
+// applies to every point that has none of its own.
+type batchMessage struct {
+	VehicleID string            `json:"vehicle_id"`
+	Points    []locationMessage `json:"points"`
 }
 
 type LocationSubscriber struct {
 	client      mqtt.Client
 	locationSvc locationService
 	geofenceSvc geofenceService
+	deadLetters *deadLetterQueue
+	pool        *workerPool
 }
 
-func NewLocationSubscriber(client mqtt.Client, locationSvc locationService, geofenceSvc geofenceService) *LocationSubscriber {
-	return &LocationSubscriber{
+func NewLocationSubscriber(client mqtt.Client, locationSvc locationService, geofenceSvc geofenceService, deadLetters deadLetterService, opts PoolOptions) *LocationSubscriber {
+	s := &LocationSubscriber{
 		client:      client,
 		locationSvc: locationSvc,
 		geofenceSvc: geofenceSvc,
+		deadLetters: newDeadLetterQueue(deadLetters),
 	}
+	s.pool = newWorkerPool(opts, s.handle)
+	return s
 }
 
 func (s *LocationSubscriber) Start() error {
-	token := s.client.Subscribe(topicPattern, 1, s.handleMessage)
+	token := s.client.SubscribeMultiple(map[string]byte{topicPattern: 1, formatTopicPattern: 1}, s.handleMessage)
 	token.Wait()
 	return token.Error()
 }
 
+// Stop unsubscribes and waits for the workers to finish the locations already
+// queued and for queued dead letters to be recorded, or until ctx is done.
+// Both are drained even if unsubscribing fails, for example because the
+// broker is unreachable.
+func (s *LocationSubscriber) Stop(ctx context.Context) error {
+	unsubCtx, cancel := context.WithTimeout(ctx, unsubscribeTimeout)
+	defer cancel()
+
+	var unsubErr error
+	token := s.client.Unsubscribe(topicPattern, formatTopicPattern)
+	select {
+	case <-token.Done():
+		unsubErr = token.Error()
+	case <-unsubCtx.Done():
+		unsubErr = unsubCtx.Err()
+	}
+	if unsubErr != nil {
+		unsubErr = fmt.Errorf("unsubscribe: %w", unsubErr)
+	}
+	return errors.Join(unsubErr, s.pool.stop(ctx), s.deadLetters.stop(ctx))
+}
+
 func (s *LocationSubscriber) handleMessage(_ mqtt.Client, msg mqtt.Message) {
-	var raw locationMessage
-	if err := json.Unmarshal(msg.Payload(), &raw); err != nil {
-		log.Printf("invalid location message: %v", err)
+	locs, err := decodeLocations(msg.Topic(), msg.Payload())
+	if err != nil {
+		s.deadLetter(msg.Topic(), msg.Payload(), err)
 		return
 	}
 
-	if err := validateLocationMessage(&raw); err != nil {
-		log.Printf("validation error: %v", err)
-		return
+	if !s.pool.submit(locs) {
+		log.Printf("subscriber stopped, dropped %d locations for %s", len(locs), locs[0].VehicleID)
+	}
+}
+
+// Reprocess runs a previously rejected message through decoding, validation
+// and processing again, bypassing the worker pool. A message that is still
+// invalid returns an error wrapping domain.ErrMessageRejected.
+func (s *LocationSubscriber) Reprocess(ctx context.Context, topic string, payload []byte) error {
+	locs, err := decodeLocations(topic, payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrMessageRejected, err)
+	}
+	return s.process(ctx, locs)
+}
+
+// deadLetter queues a rejected message to be recorded so it can be
+// reprocessed later.
+func (s *LocationSubscriber) deadLetter(topic string, payload []byte, reason error) {
+	log.Printf("rejected message on %s: %v", topic, reason)
+
+	dl := &domain.DeadLetter{
+		Topic:      topic,
+		Payload:    payload,
+		Reason:     reason.Error(),
+		ReceivedAt: time.Now(),
+	}
+	s.deadLetters.add(dl)
+}
+
+func (s *LocationSubscriber) handle(ctx context.Context, locs []domain.VehicleLocation) {
+	if err := s.process(ctx, locs); err != nil {
+		log.Printf("%v", err)
+	}
+}
+
+// process saves the location and runs the geofence check on it. Only a failed
+// save is returned; the geofence check is skipped then, and its own errors
+// are logged. A duplicate, such as a QoS 1 redelivery, was already checked
+// when it first arrived and is skipped without an error. A late location is
+// stored as history but not checked, so it cannot trigger transitions from a
+// stale position.
+//
+// A batch is saved in one transaction and its live locations are then checked
+// in timestamp order.
+func (s *LocationSubscriber) process(ctx context.Context, locs []domain.VehicleLocation) error {
+	if len(locs) > 1 {
+		return s.processBatch(ctx, locs)
 	}
 
-	vl := &domain.VehicleLocation{
+	vl := &locs[0]
+	live, err := s.locationSvc.SaveLocation(ctx, vl)
+	if err != nil {
+		if errors.Is(err, domain.ErrDuplicateLocation) {
+			log.Printf("duplicate location for %s at %d, skipped", vl.VehicleID, vl.Location.Timestamp.Unix())
+			return nil
+		}
+		return fmt.Errorf("save location error: %w", err)
+	}
+	if !live {
+		log.Printf("late location for %s at %d, stored as history only", vl.VehicleID, vl.Location.Timestamp.Unix())
+		return nil
+	}
+
+	if err := s.geofenceSvc.CheckAndAlert(ctx, vl); err != nil {
+		log.Printf("geofence check error: %v", err)
+	}
+	return nil
+}
+
+func (s *LocationSubscriber) processBatch(ctx context.Context, locs []domain.VehicleLocation) error {
+	live, err := s.locationSvc.SaveLocations(ctx, locs)
+	if err != nil {
+		return fmt.Errorf("save locations error: %w", err)
+	}
+
+	skipped := 0
+	for i := range locs {
+		if !live[i] {
+			skipped++
+			continue
+		}
+		if err := s.geofenceSvc.CheckAndAlert(ctx, &locs[i]); err != nil {
+			log.Printf("geofence check error: %v", err)
+		}
+	}
+	if skipped > 0 {
+		log.Printf("batch of %d locations for %s: %d duplicate or late, not checked", len(locs), locs[0].VehicleID, skipped)
+	}
+	return nil
+}
+
+// decodeLocations parses a message received on topic, in the payload format
+// the topic names. The payload is either a single location, an array of
+// locations or a batch envelope with the locations under "points". The
+// vehicle is the one named in the topic; a vehicle_id in the payload is
+// optional but must match it, so a device cannot report locations for another
+// vehicle. One invalid point rejects the whole message.
+func decodeLocations(topic string, payload []byte) ([]domain.VehicleLocation, error) {
+	vehicleID, c, err := parseTopic(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, batch, err := c.decode(payload)
+	if err != nil {
+		return nil, err
+	}
+	if batch && len(msgs) == 0 {
+		return nil, fmt.Errorf("points: must not be empty")
+	}
+	if len(msgs) > maxBatchPoints {
+		return nil, fmt.Errorf("points: must not be more than %d", maxBatchPoints)
+	}
+
+	locs := make([]domain.VehicleLocation, len(msgs))
+	for i := range msgs {
+		vl, err := toVehicleLocation(vehicleID, &msgs[i])
+		if err != nil {
+			if batch {
+				return nil, fmt.Errorf("points[%d]: %w", i, err)
+			}
+			return nil, err
+		}
+		locs[i] = *vl
+	}
+	return locs, nil
+}
+
+func toVehicleLocation(vehicleID string, raw *locationMessage) (*domain.VehicleLocation, error) {
+	if raw.VehicleID == "" {
+		raw.VehicleID = vehicleID
+	} else if raw.VehicleID != vehicleID {
+		return nil, fmt.Errorf("vehicle_id: %q does not match topic vehicle %q", raw.VehicleID, vehicleID)
+	}
+
+	if err := validateLocationMessage(raw); err != nil {
+		return nil, err
+	}
+
+	return &domain.VehicleLocation{
 		VehicleID: raw.VehicleID,
 		Location: domain.Location{
 			Lat:       raw.Latitude,
 			Lon:       raw.Longitude,
 			Timestamp: time.Unix(raw.Timestamp, 0),
 		},
-	}
-
-	ctx := context.Background()
+		Telemetry: domain.Telemetry{
+			Speed:      raw.Speed,
+			Heading:    raw.Heading,
+			Altitude:   raw.Altitude,
+			Accuracy:   raw.Accuracy,
+			Satellites: raw.Satellites,
+			Ignition:   raw.Ignition,
+			Odometer:   raw.Odometer,
+		},
+	}, nil
+}
 
-	if err := s.locationSvc.SaveLocation(ctx, vl); err != nil {
-		log.Printf("save location error: %v", err)
-		return
+// parseTopic returns the vehicle a topic belongs to and the codec for its
+// payload format.
+func parseTopic(topic string) (string, payloadCodec, error) {
+	rest, ok := strings.CutPrefix(topic, topicPrefix)
+	id, rest, _ := strings.Cut(rest, "/")
+	kind, format, _ := strings.Cut(rest, "/")
+	if !ok || id == "" || kind != topicKind || strings.Contains(format, "/") {
+		return "", nil, fmt.Errorf("topic: %q is not a vehicle location topic", topic)
+	}
+	if err := domain.ValidateVehicleID(id); err != nil {
+		return "", nil, fmt.Errorf("topic: vehicle id %w", err)
 	}
 
-	if err := s.geofenceSvc.CheckAndAlert(ctx, vl); err != nil {
-		log.Printf("geofence check error: %v", err)
+	c, ok := codecs[format]
+	if !ok {
+		return "", nil, fmt.Errorf("topic: unknown payload format %q", format)
 	}
+	return id, c, nil
 }
 
 func validateLocationMessage(msg *locationMessage) error {
 	if msg.VehicleID == "" {
 		return fmt.Errorf("vehicle_id: required")
 	}
+	if err := domain.ValidateVehicleID(msg.VehicleID); err != nil {
+		return fmt.Errorf("vehicle_id: %w", err)
+	}
 	if msg.Latitude < -90 || msg.Latitude > 90 {
 		return fmt.Errorf("latitude: must be between -90 and 90")
 	}
@@ -95,5 +317,20 @@ func validateLocationMessage(msg *locationMessage) error {
 	if msg.Timestamp <= 0 {
 		return fmt.Errorf("timestamp: must be positive")
 	}
+	if msg.Speed != nil && *msg.Speed < 0 {
+		return fmt.Errorf("speed: must not be negative")
+	}
+	if msg.Heading != nil && (*msg.Heading < 0 || *msg.Heading >= 360) {
+		return fmt.Errorf("heading: must be at least 0 and below 360")
+	}
+	if msg.Accuracy != nil && *msg.Accuracy < 0 {
+		return fmt.Errorf("accuracy: must not be negative")
+	}
+	if msg.Satellites != nil && (*msg.Satellites < 0 || *msg.Satellites > maxSatellites) {
+		return fmt.Errorf("satellites: must be between 0 and %d", maxSatellites)
+	}
+	if msg.Odometer != nil && *msg.Odometer < 0 {
+		return fmt.Errorf("odometer: must not be negative")
+	}
 	return nil
 }
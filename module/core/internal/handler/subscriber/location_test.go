@@ -4,18 +4,32 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
 	"github.com/nandanugg/tj-test/module/core/domain"
 )
 
 type mockLocationSvc struct {
-	saveLocationFn func(ctx context.Context, vl *domain.VehicleLocation) error
+	saveLocationFn  func(ctx context.Context, vl *domain.VehicleLocation) error
+	saveLocationsFn func(ctx context.Context, vls []domain.VehicleLocation) ([]bool, error)
+	// late makes successful saves report the location as late.
+	late bool
+}
+
+func (m *mockLocationSvc) SaveLocation(ctx context.Context, vl *domain.VehicleLocation) (bool, error) {
+	if err := m.saveLocationFn(ctx, vl); err != nil {
+		return false, err
+	}
+	return !m.late, nil
 }
 
-func (m *mockLocationSvc) SaveLocation(ctx context.Context, vl *domain.VehicleLocation) error {
-	return m.saveLocationFn(ctx, vl)
+func (m *mockLocationSvc) SaveLocations(ctx context.Context, vls []domain.VehicleLocation) ([]bool, error) {
+	return m.saveLocationsFn(ctx, vls)
 }
 
 type mockGeofenceSvc struct {
@@ -26,18 +40,48 @@ func (m *mockGeofenceSvc) CheckAndAlert(ctx context.Context, vl *domain.VehicleL
 	return m.checkAndAlertFn(ctx, vl)
 }
 
+type mockDeadLetterSvc struct {
+	recorded []*domain.DeadLetter
+}
+
+func (m *mockDeadLetterSvc) RecordDeadLetter(_ context.Context, dl *domain.DeadLetter) error {
+	m.recorded = append(m.recorded, dl)
+	return nil
+}
+
 type fakeMQTTMessage struct {
+	topic   string
 	payload []byte
 }
 
-func (f *fakeMQTTMessage) Duplicate() bool   { return false }
-func (f *fakeMQTTMessage) Qos() byte         { return 0 }
-func (f *fakeMQTTMessage) Retained() bool    { return false }
-func (f *fakeMQTTMessage) Topic() string     { return "/fleet/vehicle/B1234XYZ/location" }
+func (f *fakeMQTTMessage) Duplicate() bool { return false }
+func (f *fakeMQTTMessage) Qos() byte       { return 0 }
+func (f *fakeMQTTMessage) Retained() bool  { return false }
+func (f *fakeMQTTMessage) Topic() string {
+	if f.topic == "" {
+		return "/fleet/vehicle/B1234XYZ/location"
+	}
+	return f.topic
+}
 func (f *fakeMQTTMessage) MessageID() uint16 { return 0 }
 func (f *fakeMQTTMessage) Payload() []byte   { return f.payload }
 func (f *fakeMQTTMessage) Ack()              {}
 
+// newTestSubscriber returns a subscriber with a single worker.
+func newTestSubscriber(locSvc locationService, geoSvc geofenceService, deadLetters deadLetterService) *LocationSubscriber {
+	return NewLocationSubscriber(nil, locSvc, geoSvc, deadLetters, PoolOptions{Workers: 1})
+}
+
+// deliver hands msg to the subscriber as paho would, then drains the workers
+// and the dead letter queue so the message has been handled when it returns.
+func deliver(t *testing.T, sub *LocationSubscriber, msg mqtt.Message) {
+	t.Helper()
+	sub.handleMessage(nil, msg)
+	if err := errors.Join(sub.pool.stop(context.Background()), sub.deadLetters.stop(context.Background())); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+}
+
 func TestHandleMessage_Success(t *testing.T) {
 	var savedVL *domain.VehicleLocation
 	var checkedVL *domain.VehicleLocation
@@ -55,7 +99,7 @@ func TestHandleMessage_Success(t *testing.T) {
 		},
 	}
 
-	sub := &LocationSubscriber{locationSvc: locSvc, geofenceSvc: geoSvc}
+	sub := newTestSubscriber(locSvc, geoSvc, nil)
 
 	msg := locationMessage{
 		VehicleID: "B1234XYZ",
@@ -64,7 +108,7 @@ func TestHandleMessage_Success(t *testing.T) {
 		Timestamp: 1715003456,
 	}
 	payload, _ := json.Marshal(msg)
-	sub.handleMessage(nil, &fakeMQTTMessage{payload: payload})
+	deliver(t, sub, &fakeMQTTMessage{payload: payload})
 
 	if savedVL == nil {
 		t.Fatal("expected SaveLocation to be called")
@@ -84,6 +128,34 @@ func TestHandleMessage_Success(t *testing.T) {
 	}
 }
 
+func TestHandleMessage_Telemetry(t *testing.T) {
+	var savedVL *domain.VehicleLocation
+	locSvc := &mockLocationSvc{
+		saveLocationFn: func(_ context.Context, vl *domain.VehicleLocation) error {
+			savedVL = vl
+			return nil
+		},
+	}
+	geoSvc := &mockGeofenceSvc{
+		checkAndAlertFn: func(context.Context, *domain.VehicleLocation) error { return nil },
+	}
+	sub := newTestSubscriber(locSvc, geoSvc, nil)
+
+	payload := []byte(`{"latitude":-6.2,"longitude":106.8,"timestamp":1715003456,"speed":42.5,"heading":90,"satellites":9,"ignition":false,"odometer":10523.4}`)
+	deliver(t, sub, &fakeMQTTMessage{payload: payload})
+
+	if savedVL == nil {
+		t.Fatal("expected SaveLocation to be called")
+	}
+	tm := savedVL.Telemetry
+	if *tm.Speed != 42.5 || *tm.Heading != 90 || *tm.Satellites != 9 || *tm.Ignition || *tm.Odometer != 10523.4 {
+		t.Errorf("unexpected telemetry: %+v", tm)
+	}
+	if tm.Altitude != nil || tm.Accuracy != nil {
+		t.Errorf("expected unreported fields to be nil, got %+v", tm)
+	}
+}
+
 func TestHandleMessage_InvalidJSON(t *testing.T) {
 	locSvc := &mockLocationSvc{
 		saveLocationFn: func(_ context.Context, _ *domain.VehicleLocation) error {
@@ -92,9 +164,21 @@ func TestHandleMessage_InvalidJSON(t *testing.T) {
 		},
 	}
 	geoSvc := &mockGeofenceSvc{}
+	dlSvc := &mockDeadLetterSvc{}
 
-	sub := &LocationSubscriber{locationSvc: locSvc, geofenceSvc: geoSvc}
-	sub.handleMessage(nil, &fakeMQTTMessage{payload: []byte("invalid")})
+	sub := newTestSubscriber(locSvc, geoSvc, dlSvc)
+	deliver(t, sub, &fakeMQTTMessage{payload: []byte("invalid")})
+
+	if len(dlSvc.recorded) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(dlSvc.recorded))
+	}
+	dl := dlSvc.recorded[0]
+	if dl.Topic != "/fleet/vehicle/B1234XYZ/location" || string(dl.Payload) != "invalid" || !strings.HasPrefix(dl.Reason, "invalid json") {
+		t.Errorf("unexpected dead letter: %+v", dl)
+	}
+	if dl.ReceivedAt.IsZero() {
+		t.Error("expected received_at to be set")
+	}
 }
 
 func TestHandleMessage_ValidationError(t *testing.T) {
@@ -105,13 +189,138 @@ func TestHandleMessage_ValidationError(t *testing.T) {
 		},
 	}
 	geoSvc := &mockGeofenceSvc{}
+	dlSvc := &mockDeadLetterSvc{}
 
-	sub := &LocationSubscriber{locationSvc: locSvc, geofenceSvc: geoSvc}
+	sub := newTestSubscriber(locSvc, geoSvc, dlSvc)
+
+	// latitude out of range
+	msg := locationMessage{VehicleID: "B1234XYZ", Latitude: -96.2, Longitude: 106.8, Timestamp: 1715003456}
+	payload, _ := json.Marshal(msg)
+	deliver(t, sub, &fakeMQTTMessage{payload: payload})
+
+	if len(dlSvc.recorded) != 1 || dlSvc.recorded[0].Reason != "latitude: must be between -90 and 90" {
+		t.Errorf("expected a dead letter for the latitude, got %+v", dlSvc.recorded)
+	}
+}
+
+func TestHandleMessage_VehicleIDFromTopic(t *testing.T) {
+	var savedVL *domain.VehicleLocation
+	locSvc := &mockLocationSvc{
+		saveLocationFn: func(_ context.Context, vl *domain.VehicleLocation) error {
+			savedVL = vl
+			return nil
+		},
+	}
+	geoSvc := &mockGeofenceSvc{
+		checkAndAlertFn: func(context.Context, *domain.VehicleLocation) error { return nil },
+	}
+	sub := newTestSubscriber(locSvc, geoSvc, nil)
+
+	payload := []byte(`{"latitude":-6.2,"longitude":106.8,"timestamp":1715003456}`)
+	deliver(t, sub, &fakeMQTTMessage{topic: "/fleet/vehicle/B5678ABC/location", payload: payload})
+
+	if savedVL == nil || savedVL.VehicleID != "B5678ABC" {
+		t.Fatalf("expected the location to be saved for the topic vehicle, got %+v", savedVL)
+	}
+}
 
-	// empty vehicle_id
-	msg := locationMessage{Latitude: -6.2, Longitude: 106.8, Timestamp: 1715003456}
+func TestHandleMessage_TopicMismatch(t *testing.T) {
+	locSvc := &mockLocationSvc{
+		saveLocationFn: func(_ context.Context, _ *domain.VehicleLocation) error {
+			t.Fatal("SaveLocation should not be called")
+			return nil
+		},
+	}
+	dlSvc := &mockDeadLetterSvc{}
+	sub := newTestSubscriber(locSvc, &mockGeofenceSvc{}, dlSvc)
+
+	msg := locationMessage{VehicleID: "B1234XYZ", Latitude: -6.2, Longitude: 106.8, Timestamp: 1715003456}
 	payload, _ := json.Marshal(msg)
-	sub.handleMessage(nil, &fakeMQTTMessage{payload: payload})
+	deliver(t, sub, &fakeMQTTMessage{topic: "/fleet/vehicle/B5678ABC/location", payload: payload})
+
+	want := `vehicle_id: "B1234XYZ" does not match topic vehicle "B5678ABC"`
+	if len(dlSvc.recorded) != 1 || dlSvc.recorded[0].Reason != want {
+		t.Errorf("expected a dead letter for the mismatch, got %+v", dlSvc.recorded)
+	}
+}
+
+func TestParseTopic(t *testing.T) {
+	tests := []struct {
+		topic     string
+		want      string
+		wantCodec payloadCodec
+		wantErr   bool
+	}{
+		{"/fleet/vehicle/B1234XYZ/location", "B1234XYZ", jsonCodec{}, false},
+		{"/fleet/vehicle/B1234XYZ/location/json", "B1234XYZ", jsonCodec{}, false},
+		{"/fleet/vehicle/B1234XYZ/location/protobuf", "B1234XYZ", protobufCodec{}, false},
+		{"/fleet/vehicle/B1234XYZ/location/cbor", "B1234XYZ", cborCodec{}, false},
+		{"/fleet/vehicle/B1234XYZ/location/xml", "", nil, true},
+		{"/fleet/vehicle/B1234XYZ/location/cbor/x", "", nil, true},
+		{"/fleet/vehicle//location", "", nil, true},
+		{"/fleet/vehicle/" + strings.Repeat("B", 50) + "/location", strings.Repeat("B", 50), jsonCodec{}, false},
+		{"/fleet/vehicle/" + strings.Repeat("B", 51) + "/location", "", nil, true},
+		{"/fleet/vehicle/a/b/location", "", nil, true},
+		{"/fleet/vehicle/B1234XYZ/status", "", nil, true},
+		{"fleet/vehicle/B1234XYZ/location", "", nil, true},
+	}
+	for _, tt := range tests {
+		got, c, err := parseTopic(tt.topic)
+		if (err != nil) != tt.wantErr || got != tt.want || c != tt.wantCodec {
+			t.Errorf("parseTopic(%q) = %q, %T, %v", tt.topic, got, c, err)
+		}
+	}
+}
+
+func TestReprocess_StillInvalid(t *testing.T) {
+	dlSvc := &mockDeadLetterSvc{}
+	sub := newTestSubscriber(&mockLocationSvc{}, &mockGeofenceSvc{}, dlSvc)
+
+	err := sub.Reprocess(context.Background(), "/fleet/vehicle/B1234XYZ/location", []byte("invalid"))
+	if !errors.Is(err, domain.ErrMessageRejected) {
+		t.Fatalf("expected ErrMessageRejected, got %v", err)
+	}
+	if err := sub.deadLetters.stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if len(dlSvc.recorded) != 0 {
+		t.Error("reprocessing must not record another dead letter")
+	}
+}
+
+func TestReprocess_Success(t *testing.T) {
+	var checked bool
+	locSvc := &mockLocationSvc{
+		saveLocationFn: func(context.Context, *domain.VehicleLocation) error { return nil },
+	}
+	geoSvc := &mockGeofenceSvc{
+		checkAndAlertFn: func(context.Context, *domain.VehicleLocation) error {
+			checked = true
+			return nil
+		},
+	}
+	sub := &LocationSubscriber{locationSvc: locSvc, geofenceSvc: geoSvc}
+
+	payload, _ := json.Marshal(locationMessage{VehicleID: "B1234XYZ", Latitude: -6.2, Longitude: 106.8, Timestamp: 1715003456})
+	if err := sub.Reprocess(context.Background(), "/fleet/vehicle/B1234XYZ/location", payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !checked {
+		t.Error("expected CheckAndAlert to be called")
+	}
+}
+
+func TestReprocess_SaveError(t *testing.T) {
+	locSvc := &mockLocationSvc{
+		saveLocationFn: func(context.Context, *domain.VehicleLocation) error { return errors.New("db error") },
+	}
+	sub := &LocationSubscriber{locationSvc: locSvc, geofenceSvc: &mockGeofenceSvc{}}
+
+	payload, _ := json.Marshal(locationMessage{VehicleID: "B1234XYZ", Latitude: -6.2, Longitude: 106.8, Timestamp: 1715003456})
+	err := sub.Reprocess(context.Background(), "/fleet/vehicle/B1234XYZ/location", payload)
+	if err == nil || errors.Is(err, domain.ErrMessageRejected) {
+		t.Fatalf("expected a processing error, got %v", err)
+	}
 }
 
 func TestHandleMessage_SaveError_SkipsGeofence(t *testing.T) {
@@ -127,11 +336,161 @@ func TestHandleMessage_SaveError_SkipsGeofence(t *testing.T) {
 		},
 	}
 
-	sub := &LocationSubscriber{locationSvc: locSvc, geofenceSvc: geoSvc}
+	sub := newTestSubscriber(locSvc, geoSvc, nil)
 
 	msg := locationMessage{VehicleID: "B1234XYZ", Latitude: -6.2, Longitude: 106.8, Timestamp: 1715003456}
 	payload, _ := json.Marshal(msg)
-	sub.handleMessage(nil, &fakeMQTTMessage{payload: payload})
+	deliver(t, sub, &fakeMQTTMessage{payload: payload})
+}
+
+func TestHandleMessage_Duplicate_SkipsGeofence(t *testing.T) {
+	locSvc := &mockLocationSvc{
+		saveLocationFn: func(_ context.Context, _ *domain.VehicleLocation) error {
+			return domain.ErrDuplicateLocation
+		},
+	}
+	geoSvc := &mockGeofenceSvc{
+		checkAndAlertFn: func(_ context.Context, _ *domain.VehicleLocation) error {
+			t.Fatal("CheckAndAlert should not be called for a duplicate")
+			return nil
+		},
+	}
+	sub := &LocationSubscriber{locationSvc: locSvc, geofenceSvc: geoSvc}
+
+	payload, _ := json.Marshal(locationMessage{VehicleID: "B1234XYZ", Latitude: -6.2, Longitude: 106.8, Timestamp: 1715003456})
+	if err := sub.Reprocess(context.Background(), "/fleet/vehicle/B1234XYZ/location", payload); err != nil {
+		t.Fatalf("expected a duplicate not to be an error, got %v", err)
+	}
+}
+
+func TestHandleMessage_Late_SkipsGeofence(t *testing.T) {
+	saved := false
+	locSvc := &mockLocationSvc{
+		saveLocationFn: func(_ context.Context, _ *domain.VehicleLocation) error {
+			saved = true
+			return nil
+		},
+		late: true,
+	}
+	geoSvc := &mockGeofenceSvc{
+		checkAndAlertFn: func(_ context.Context, _ *domain.VehicleLocation) error {
+			t.Fatal("CheckAndAlert should not be called for a late location")
+			return nil
+		},
+	}
+	sub := newTestSubscriber(locSvc, geoSvc, nil)
+
+	payload, _ := json.Marshal(locationMessage{VehicleID: "B1234XYZ", Latitude: -6.2, Longitude: 106.8, Timestamp: 1715003456})
+	deliver(t, sub, &fakeMQTTMessage{payload: payload})
+
+	if !saved {
+		t.Error("expected a late location to be saved")
+	}
+}
+
+func TestHandleMessage_Batch(t *testing.T) {
+	payloads := map[string]string{
+		"array": `[{"latitude":-6.2,"longitude":106.8,"timestamp":1715003466},` +
+			`{"vehicle_id":"B1234XYZ","latitude":-6.3,"longitude":106.9,"timestamp":1715003456},` +
+			`{"latitude":-6.4,"longitude":107,"timestamp":1715003476}]`,
+		"envelope": `{"vehicle_id":"B1234XYZ","points":[{"latitude":-6.2,"longitude":106.8,"timestamp":1715003466},` +
+			`{"latitude":-6.3,"longitude":106.9,"timestamp":1715003456},` +
+			`{"latitude":-6.4,"longitude":107,"timestamp":1715003476}]}`,
+	}
+	for name, payload := range payloads {
+		t.Run(name, func(t *testing.T) {
+			var saved int
+			locSvc := &mockLocationSvc{
+				saveLocationsFn: func(_ context.Context, vls []domain.VehicleLocation) ([]bool, error) {
+					saved = len(vls)
+					// the service sorts the batch; the middle point is late
+					slices.SortFunc(vls, func(a, b domain.VehicleLocation) int {
+						return a.Location.Timestamp.Compare(b.Location.Timestamp)
+					})
+					return []bool{true, false, true}, nil
+				},
+			}
+			var checked []int64
+			geoSvc := &mockGeofenceSvc{
+				checkAndAlertFn: func(_ context.Context, vl *domain.VehicleLocation) error {
+					checked = append(checked, vl.Location.Timestamp.Unix())
+					return nil
+				},
+			}
+			sub := newTestSubscriber(locSvc, geoSvc, nil)
+
+			deliver(t, sub, &fakeMQTTMessage{payload: []byte(payload)})
+
+			if saved != 3 {
+				t.Fatalf("expected 3 locations saved together, got %d", saved)
+			}
+			if want := []int64{1715003456, 1715003476}; !slices.Equal(checked, want) {
+				t.Errorf("expected live points checked in order %v, got %v", want, checked)
+			}
+		})
+	}
+}
+
+func TestHandleMessage_BatchRejected(t *testing.T) {
+	tooMany := make([]locationMessage, maxBatchPoints+1)
+	for i := range tooMany {
+		tooMany[i] = locationMessage{Latitude: -6.2, Longitude: 106.8, Timestamp: int64(1715003456 + i)}
+	}
+	tooManyPayload, _ := json.Marshal(tooMany)
+
+	tests := []struct {
+		name    string
+		payload string
+		reason  string
+	}{
+		{"invalid point", `[{"latitude":-6.2,"longitude":106.8,"timestamp":1715003456},{"latitude":-96.2,"longitude":106.8,"timestamp":1715003466}]`, "points[1]: latitude: must be between -90 and 90"},
+		{"other vehicle", `{"points":[{"vehicle_id":"X9999ZZZ","latitude":-6.2,"longitude":106.8,"timestamp":1715003456}]}`, `points[0]: vehicle_id: "X9999ZZZ" does not match topic vehicle "B1234XYZ"`},
+		{"envelope other vehicle", `{"vehicle_id":"X9999ZZZ","points":[{"latitude":-6.2,"longitude":106.8,"timestamp":1715003456}]}`, `points[0]: vehicle_id: "X9999ZZZ" does not match topic vehicle "B1234XYZ"`},
+		{"empty array", `[]`, "points: must not be empty"},
+		{"empty envelope", `{"points":[]}`, "points: must not be empty"},
+		{"too many", string(tooManyPayload), "points: must not be more than 1000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locSvc := &mockLocationSvc{
+				saveLocationsFn: func(context.Context, []domain.VehicleLocation) ([]bool, error) {
+					t.Fatal("a rejected batch must not be saved")
+					return nil, nil
+				},
+			}
+			deadLetters := &mockDeadLetterSvc{}
+			sub := newTestSubscriber(locSvc, nil, deadLetters)
+
+			deliver(t, sub, &fakeMQTTMessage{payload: []byte(tt.payload)})
+
+			if len(deadLetters.recorded) != 1 {
+				t.Fatalf("expected 1 dead letter, got %d", len(deadLetters.recorded))
+			}
+			if got := deadLetters.recorded[0].Reason; got != tt.reason {
+				t.Errorf("expected reason %q, got %q", tt.reason, got)
+			}
+		})
+	}
+}
+
+func TestHandleMessage_BatchSaveError_SkipsGeofence(t *testing.T) {
+	locSvc := &mockLocationSvc{
+		saveLocationsFn: func(context.Context, []domain.VehicleLocation) ([]bool, error) {
+			return nil, errors.New("db error")
+		},
+	}
+	geoSvc := &mockGeofenceSvc{
+		checkAndAlertFn: func(context.Context, *domain.VehicleLocation) error {
+			t.Fatal("CheckAndAlert must not run when the batch was not saved")
+			return nil
+		},
+	}
+	sub := &LocationSubscriber{locationSvc: locSvc, geofenceSvc: geoSvc}
+
+	payload := `[{"latitude":-6.2,"longitude":106.8,"timestamp":1715003456},{"latitude":-6.3,"longitude":106.9,"timestamp":1715003466}]`
+	if err := sub.Reprocess(context.Background(), "/fleet/vehicle/B1234XYZ/location", []byte(payload)); err == nil {
+		t.Fatal("expected the save error to be returned")
+	}
 }
 
 func TestValidateLocationMessage(t *testing.T) {
@@ -142,12 +501,22 @@ func TestValidateLocationMessage(t *testing.T) {
 	}{
 		{"valid", locationMessage{VehicleID: "X", Latitude: 0, Longitude: 0, Timestamp: 1}, false},
 		{"empty vehicle_id", locationMessage{Latitude: 0, Longitude: 0, Timestamp: 1}, true},
+		{"vehicle_id too long", locationMessage{VehicleID: strings.Repeat("X", 51), Timestamp: 1}, true},
 		{"lat too low", locationMessage{VehicleID: "X", Latitude: -91, Longitude: 0, Timestamp: 1}, true},
 		{"lat too high", locationMessage{VehicleID: "X", Latitude: 91, Longitude: 0, Timestamp: 1}, true},
 		{"lon too low", locationMessage{VehicleID: "X", Latitude: 0, Longitude: -181, Timestamp: 1}, true},
 		{"lon too high", locationMessage{VehicleID: "X", Latitude: 0, Longitude: 181, Timestamp: 1}, true},
 		{"zero timestamp", locationMessage{VehicleID: "X", Latitude: 0, Longitude: 0, Timestamp: 0}, true},
 		{"negative timestamp", locationMessage{VehicleID: "X", Latitude: 0, Longitude: 0, Timestamp: -1}, true},
+		{"valid telemetry", locationMessage{VehicleID: "X", Timestamp: 1, Speed: ptr(40.0), Heading: ptr(359.9), Altitude: ptr(-10.0), Accuracy: ptr(0.0), Satellites: ptr(0), Odometer: ptr(0.0)}, false},
+		{"negative speed", locationMessage{VehicleID: "X", Timestamp: 1, Speed: ptr(-1.0)}, true},
+		{"heading 360", locationMessage{VehicleID: "X", Timestamp: 1, Heading: ptr(360.0)}, true},
+		{"negative heading", locationMessage{VehicleID: "X", Timestamp: 1, Heading: ptr(-0.1)}, true},
+		{"negative accuracy", locationMessage{VehicleID: "X", Timestamp: 1, Accuracy: ptr(-1.0)}, true},
+		{"negative satellites", locationMessage{VehicleID: "X", Timestamp: 1, Satellites: ptr(-1)}, true},
+		{"satellites 255", locationMessage{VehicleID: "X", Timestamp: 1, Satellites: ptr(255)}, false},
+		{"satellites too many", locationMessage{VehicleID: "X", Timestamp: 1, Satellites: ptr(256)}, true},
+		{"negative odometer", locationMessage{VehicleID: "X", Timestamp: 1, Odometer: ptr(-1.0)}, true},
 	}
 
 	for _, tt := range tests {
@@ -159,3 +528,7 @@ func TestValidateLocationMessage(t *testing.T) {
 		})
 	}
 }
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,170 @@
+package subscriber
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/queue"
+)
+
+// OverflowPolicy decides what happens to a message whose shard queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock makes the MQTT callback wait for room, which stops paho
+	// reading from the broker until the workers catch up.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest discards the oldest queued message of the shard to
+	// make room for the new one.
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+)
+
+const (
+	defaultWorkers   = 8
+	defaultQueueSize = 256
+)
+
+// poolMetrics is served under "location_pool" by expvar: queue_depth is the
+// number of messages waiting for a worker, dropped counts messages discarded
+// by OverflowDropOldest and dead_letters_dropped counts rejected messages not
+// recorded because the dead letter queue was full.
+var poolMetrics = expvar.NewMap("location_pool")
+
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch p := OverflowPolicy(s); p {
+	case OverflowBlock, OverflowDropOldest:
+		return p, nil
+	case "":
+		return OverflowBlock, nil
+	default:
+		return "", fmt.Errorf("overflow policy %q: must be one of block, drop_oldest", s)
+	}
+}
+
+// PoolOptions controls how received messages are handed to workers.
+type PoolOptions struct {
+	// Workers is the number of shards, each drained by its own goroutine.
+	// Defaults to 8.
+	Workers int
+	// QueueSize bounds each shard's queue. Defaults to 256.
+	QueueSize int
+	// Overflow applies when a shard's queue is full. Defaults to OverflowBlock.
+	Overflow OverflowPolicy
+}
+
+// workerPool runs handle on a fixed set of workers. Each job holds the
+// locations of one message, all for the same vehicle. Jobs are sharded by
+// vehicle ID, so one vehicle's messages are always handled by the same
+// worker in the order they were submitted, while a slow vehicle only holds
+// up the others in its shard.
+type workerPool struct {
+	handle   func(ctx context.Context, locs []domain.VehicleLocation)
+	overflow OverflowPolicy
+	shards   []*queue.Queue[[]domain.VehicleLocation]
+	wg       sync.WaitGroup
+	// ctx is passed to handle and cancelled once stop gives up waiting, so
+	// a stalled worker does not outlive the shutdown.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newWorkerPool(opts PoolOptions, handle func(ctx context.Context, locs []domain.VehicleLocation)) *workerPool {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	if opts.Overflow == "" {
+		opts.Overflow = OverflowBlock
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &workerPool{
+		handle:   handle,
+		overflow: opts.Overflow,
+		shards:   make([]*queue.Queue[[]domain.VehicleLocation], opts.Workers),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	for i := range p.shards {
+		p.shards[i] = queue.New[[]domain.VehicleLocation](opts.QueueSize)
+		p.wg.Add(1)
+		go p.work(p.shards[i])
+	}
+	return p
+}
+
+// submit queues a message's locations on their vehicle's shard. It reports
+// false if the pool has been stopped, including while it waits for room.
+func (p *workerPool) submit(locs []domain.VehicleLocation) bool {
+	shard := p.shards[shardOf(locs[0].VehicleID, len(p.shards))]
+	if p.overflow == OverflowBlock {
+		if err := shard.Push(context.Background(), locs); err != nil {
+			return false
+		}
+		poolMetrics.Add("queue_depth", 1)
+		return true
+	}
+
+	for {
+		ok, err := shard.TryPush(locs)
+		if err != nil {
+			return false
+		}
+		if ok {
+			poolMetrics.Add("queue_depth", 1)
+			return true
+		}
+		// The worker may empty the slot first, in which case the next push
+		// succeeds without dropping anything.
+		select {
+		case old, ok := <-shard.C():
+			if ok {
+				poolMetrics.Add("queue_depth", -1)
+				poolMetrics.Add("dropped", 1)
+				log.Printf("location queue full, dropped %d locations for %s", len(old), old[0].VehicleID)
+			}
+		default:
+		}
+	}
+}
+
+// stop stops accepting messages and waits until the queued ones have been
+// handled, or ctx is done. A submit waiting for room returns false. Once ctx
+// is done, the context passed to handle is cancelled.
+func (p *workerPool) stop(ctx context.Context) error {
+	for _, shard := range p.shards {
+		shard.Close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	if err := queue.Wait(ctx, done); err != nil {
+		p.cancel()
+		return err
+	}
+	return nil
+}
+
+func (p *workerPool) work(shard *queue.Queue[[]domain.VehicleLocation]) {
+	defer p.wg.Done()
+	for locs := range shard.C() {
+		poolMetrics.Add("queue_depth", -1)
+		p.handle(p.ctx, locs)
+	}
+}
+
+func shardOf(vehicleID string, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(vehicleID))
+	return int(h.Sum32() % uint32(n))
+}
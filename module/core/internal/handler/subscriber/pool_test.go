@@ -0,0 +1,228 @@
+package subscriber
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+func locationAt(vehicleID string, sec int64) []domain.VehicleLocation {
+	return []domain.VehicleLocation{{VehicleID: vehicleID, Location: domain.Location{Timestamp: time.Unix(sec, 0)}}}
+}
+
+func TestWorkerPool_PreservesPerVehicleOrder(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen = map[string][]int64{}
+	)
+	p := newWorkerPool(PoolOptions{Workers: 4, QueueSize: 4}, func(_ context.Context, locs []domain.VehicleLocation) {
+		vl := locs[0]
+		mu.Lock()
+		defer mu.Unlock()
+		seen[vl.VehicleID] = append(seen[vl.VehicleID], vl.Location.Timestamp.Unix())
+	})
+
+	for sec := int64(1); sec <= 50; sec++ {
+		for v := range 10 {
+			if !p.submit(locationAt(fmt.Sprintf("V%d", v), sec)) {
+				t.Fatal("submit rejected by a running pool")
+			}
+		}
+	}
+	if err := p.stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	if len(seen) != 10 {
+		t.Fatalf("expected 10 vehicles, got %d", len(seen))
+	}
+	for id, ts := range seen {
+		if len(ts) != 50 || !slices.IsSorted(ts) {
+			t.Errorf("%s: expected 50 ordered locations, got %v", id, ts)
+		}
+	}
+}
+
+func TestWorkerPool_DropOldest(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var handled []int64
+	p := newWorkerPool(PoolOptions{Workers: 1, QueueSize: 2, Overflow: OverflowDropOldest}, func(_ context.Context, locs []domain.VehicleLocation) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		handled = append(handled, locs[0].Location.Timestamp.Unix())
+	})
+
+	// the worker holds the first location, leaving the queue empty
+	p.submit(locationAt("V", 1))
+	<-started
+
+	for sec := int64(2); sec <= 5; sec++ {
+		p.submit(locationAt("V", sec))
+	}
+	close(release)
+	if err := p.stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	if want := []int64{1, 4, 5}; !slices.Equal(handled, want) {
+		t.Errorf("expected %v, got %v", want, handled)
+	}
+}
+
+func TestWorkerPool_StopHonoursDeadlineWithStalledWorker(t *testing.T) {
+	started := make(chan struct{}, 1)
+	cancelled := make(chan struct{})
+	p := newWorkerPool(PoolOptions{Workers: 1, QueueSize: 1}, func(ctx context.Context, _ []domain.VehicleLocation) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		select {
+		case <-cancelled:
+		default:
+			close(cancelled)
+		}
+	})
+
+	// the worker stalls on the first location, the second fills the queue
+	// and the third waits for room
+	p.submit(locationAt("V", 1))
+	<-started
+	p.submit(locationAt("V", 2))
+	submitted := make(chan bool, 1)
+	go func() { submitted <- p.submit(locationAt("V", 3)) }()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- p.stop(ctx) }()
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected stop to return once its deadline passed")
+	}
+	if <-submitted {
+		t.Error("expected the waiting submit to be rejected")
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected the handler's context to be cancelled")
+	}
+}
+
+func TestWorkerPool_RejectsAfterStop(t *testing.T) {
+	p := newWorkerPool(PoolOptions{}, func(context.Context, []domain.VehicleLocation) {})
+	if err := p.stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := p.stop(context.Background()); err != nil {
+		t.Fatalf("second stop: %v", err)
+	}
+	if p.submit(locationAt("V", 1)) {
+		t.Error("expected submit to be rejected after stop")
+	}
+}
+
+func TestHandleMessage_UsesPool(t *testing.T) {
+	saved := make(chan *domain.VehicleLocation, 1)
+	locSvc := &mockLocationSvc{
+		saveLocationFn: func(_ context.Context, vl *domain.VehicleLocation) error {
+			saved <- vl
+			return nil
+		},
+	}
+	geoSvc := &mockGeofenceSvc{
+		checkAndAlertFn: func(context.Context, *domain.VehicleLocation) error { return nil },
+	}
+	sub := NewLocationSubscriber(nil, locSvc, geoSvc, &mockDeadLetterSvc{}, PoolOptions{Workers: 2})
+
+	payload, _ := json.Marshal(locationMessage{VehicleID: "B1234XYZ", Latitude: -6.2, Longitude: 106.8, Timestamp: 1715003456})
+	sub.handleMessage(nil, &fakeMQTTMessage{payload: payload})
+	if err := sub.pool.stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	select {
+	case vl := <-saved:
+		if vl.VehicleID != "B1234XYZ" {
+			t.Errorf("expected B1234XYZ, got %s", vl.VehicleID)
+		}
+	default:
+		t.Fatal("expected the worker to save the location before stop returned")
+	}
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    OverflowPolicy
+		wantErr bool
+	}{
+		{"", OverflowBlock, false},
+		{"block", OverflowBlock, false},
+		{"drop_oldest", OverflowDropOldest, false},
+		{"spill", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseOverflowPolicy(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseOverflowPolicy(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+// fakeClient is an MQTT client whose Unsubscribe never completes.
+type fakeClient struct {
+	mqtt.Client
+}
+
+func (fakeClient) Unsubscribe(...string) mqtt.Token { return stuckToken{} }
+
+type stuckToken struct{ mqtt.Token }
+
+func (stuckToken) Done() <-chan struct{} { return nil }
+
+func TestStop_DrainsPoolWhenUnsubscribeHangs(t *testing.T) {
+	var saved int
+	locSvc := &mockLocationSvc{
+		saveLocationFn: func(context.Context, *domain.VehicleLocation) error {
+			saved++
+			return nil
+		},
+	}
+	geoSvc := &mockGeofenceSvc{
+		checkAndAlertFn: func(context.Context, *domain.VehicleLocation) error { return nil },
+	}
+	sub := NewLocationSubscriber(fakeClient{}, locSvc, geoSvc, &mockDeadLetterSvc{}, PoolOptions{Workers: 1})
+	sub.pool.submit(locationAt("B1234XYZ", 1715003456))
+
+	defer func(d time.Duration) { unsubscribeTimeout = d }(unsubscribeTimeout)
+	unsubscribeTimeout = 10 * time.Millisecond
+
+	err := sub.Stop(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the unsubscribe timeout, got %v", err)
+	}
+	if saved != 1 {
+		t.Errorf("expected the queued location to be saved, got %d", saved)
+	}
+}
@@ -0,0 +1,219 @@
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+// GT06 protocol numbers. 0x12 and 0x13 are the original GT06 location and
+// heartbeat packets, 0x22 and 0x23 their Concox successors.
+const (
+	gt06Login          = 0x01
+	gt06Location       = 0x12
+	gt06Heartbeat      = 0x13
+	gt06Location2      = 0x22
+	gt06Heartbeat2     = 0x23
+	gt06ShortStart     = 0x7878
+	gt06LongStart      = 0x7979
+	gt06Stop           = 0x0d0a
+	gt06GPSLength      = 18
+	gt06IgnitionOffset = gt06GPSLength + 8
+)
+
+// gt06Packet is one frame with its start, length, CRC and stop bits removed.
+type gt06Packet struct {
+	protocol byte
+	content  []byte
+	serial   uint16
+}
+
+// serveGT06 handles a GT06/Concox tracker. The tracker logs in with its IMEI
+// first; login and heartbeat packets are acknowledged, location packets are
+// not, so a fix that fails to save is dead-lettered instead of resent.
+func (s *Server) serveGT06(ctx context.Context, conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	var vehicleID string
+	for {
+		p, err := readGT06Packet(r)
+		if err != nil {
+			return err
+		}
+
+		switch p.protocol {
+		case gt06Login:
+			imei, err := gt06IMEI(p.content)
+			if err != nil {
+				return err
+			}
+			if vehicleID, err = s.login(ctx, imei); err != nil {
+				return err
+			}
+			if _, err := conn.Write(gt06Response(p.protocol, p.serial)); err != nil {
+				return err
+			}
+		case gt06Heartbeat, gt06Heartbeat2:
+			if _, err := conn.Write(gt06Response(p.protocol, p.serial)); err != nil {
+				return err
+			}
+		case gt06Location, gt06Location2:
+			if vehicleID == "" {
+				return errors.New("location before login")
+			}
+			vl, ok, err := decodeGT06Location(p.protocol, p.content)
+			if err != nil {
+				log.Printf("gt06 %s: %v", vehicleID, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			vl.VehicleID = vehicleID
+			if err := s.process(ctx, []domain.VehicleLocation{*vl}); err != nil {
+				log.Printf("gt06 %s: %v", vehicleID, err)
+				s.deadLetter(vl, err)
+			}
+		}
+	}
+}
+
+// readGT06Packet reads the next frame and checks its CRC.
+func readGT06Packet(r *bufio.Reader) (*gt06Packet, error) {
+	head, err := readFull(r, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	var lengthField []byte
+	switch binary.BigEndian.Uint16(head) {
+	case gt06ShortStart:
+		lengthField, err = readFull(r, 1)
+	case gt06LongStart:
+		lengthField, err = readFull(r, 2)
+	default:
+		return nil, fmt.Errorf("gt06: bad start bits %x", head)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	length := int(lengthField[0])
+	if len(lengthField) == 2 {
+		length = int(binary.BigEndian.Uint16(lengthField))
+	}
+	// protocol number, serial number and CRC
+	if length < 5 {
+		return nil, fmt.Errorf("gt06: packet length %d too short", length)
+	}
+
+	body, err := readFull(r, length+2)
+	if err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint16(body[length:]) != gt06Stop {
+		return nil, fmt.Errorf("gt06: bad stop bits %x", body[length:])
+	}
+
+	crc := binary.BigEndian.Uint16(body[length-2 : length])
+	if want := crcITU(append(lengthField, body[:length-2]...)); crc != want {
+		return nil, fmt.Errorf("gt06: crc %04x, want %04x", crc, want)
+	}
+
+	return &gt06Packet{
+		protocol: body[0],
+		content:  body[1 : length-4],
+		serial:   binary.BigEndian.Uint16(body[length-4 : length-2]),
+	}, nil
+}
+
+// gt06Response acknowledges a login or heartbeat packet.
+func gt06Response(protocol byte, serial uint16) []byte {
+	b := []byte{0x78, 0x78, 0x05, protocol, byte(serial >> 8), byte(serial)}
+	b = binary.BigEndian.AppendUint16(b, crcITU(b[2:]))
+	return binary.BigEndian.AppendUint16(b, gt06Stop)
+}
+
+// gt06IMEI decodes the terminal ID of a login packet: the IMEI as 16 BCD
+// digits with a leading zero.
+func gt06IMEI(content []byte) (string, error) {
+	if len(content) < 8 {
+		return "", fmt.Errorf("gt06: login content length %d too short", len(content))
+	}
+	var sb strings.Builder
+	for _, b := range content[:8] {
+		if b>>4 > 9 || b&0x0f > 9 {
+			return "", fmt.Errorf("gt06: terminal id %x is not BCD", content[:8])
+		}
+		sb.WriteByte('0' + b>>4)
+		sb.WriteByte('0' + b&0x0f)
+	}
+	return strings.TrimPrefix(sb.String(), "0"), nil
+}
+
+// decodeGT06Location decodes the GPS block that starts a location packet. It
+// reports false for a fix the tracker marks as not positioned. The packet time
+// is taken as UTC, the trackers' default.
+func decodeGT06Location(protocol byte, content []byte) (*domain.VehicleLocation, bool, error) {
+	if len(content) < gt06GPSLength {
+		return nil, false, fmt.Errorf("gt06: location content length %d too short", len(content))
+	}
+
+	ts := time.Date(2000+int(content[0]), time.Month(content[1]), int(content[2]),
+		int(content[3]), int(content[4]), int(content[5]), 0, time.UTC)
+	satellites := int(content[6] & 0x0f)
+	// coordinates are in units of 1/30000 of a minute
+	lat := float64(binary.BigEndian.Uint32(content[7:11])) / 1800000
+	lon := float64(binary.BigEndian.Uint32(content[11:15])) / 1800000
+	speed := float64(content[15])
+
+	flags := binary.BigEndian.Uint16(content[16:18])
+	if flags&(1<<12) == 0 {
+		return nil, false, nil
+	}
+	if flags&(1<<10) == 0 {
+		lat = -lat
+	}
+	if flags&(1<<11) != 0 {
+		lon = -lon
+	}
+
+	vl := &domain.VehicleLocation{
+		Location: domain.Location{Lat: lat, Lon: lon, Timestamp: ts},
+		Telemetry: domain.Telemetry{
+			Speed:      &speed,
+			Satellites: &satellites,
+		},
+	}
+	if course := float64(flags & 0x3ff); course < 360 {
+		vl.Telemetry.Heading = &course
+	}
+	if protocol == gt06Location2 && len(content) > gt06IgnitionOffset {
+		ignition := content[gt06IgnitionOffset] != 0
+		vl.Telemetry.Ignition = &ignition
+	}
+	return vl, true, nil
+}
+
+// crcITU is CRC-16/X-25, which the GT06 protocol calls CRC-ITU.
+func crcITU(b []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, v := range b {
+		crc ^= uint16(v)
+		for range 8 {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return ^crc
+}
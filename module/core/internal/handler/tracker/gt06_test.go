@@ -0,0 +1,102 @@
+package tracker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+// Sample frames from the GT06 protocol document.
+const (
+	gt06LoginFrame    = "78780d01012345678901234500018cdd0d0a"
+	gt06LoginAck      = "787805010001d9dc0d0a"
+	gt06LocationFrame = "78781f120b081d112e10cf027ac7eb0c46584900148f01cc00287d001fb8000380810d0a"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestReadGT06Packet_Login(t *testing.T) {
+	p, err := readGT06Packet(bufio.NewReader(bytes.NewReader(mustHex(t, gt06LoginFrame))))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.protocol != gt06Login || p.serial != 1 {
+		t.Errorf("unexpected packet: %+v", p)
+	}
+	imei, err := gt06IMEI(p.content)
+	if err != nil || imei != "123456789012345" {
+		t.Errorf("expected imei 123456789012345, got %q, %v", imei, err)
+	}
+	if got, want := gt06Response(p.protocol, p.serial), mustHex(t, gt06LoginAck); !bytes.Equal(got, want) {
+		t.Errorf("expected ack %x, got %x", want, got)
+	}
+}
+
+func TestReadGT06Packet_Location(t *testing.T) {
+	p, err := readGT06Packet(bufio.NewReader(bytes.NewReader(mustHex(t, gt06LocationFrame))))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vl, ok, err := decodeGT06Location(p.protocol, p.content)
+	if err != nil || !ok {
+		t.Fatalf("expected a positioned fix, got %v, %v", ok, err)
+	}
+
+	if want := time.Date(2011, 8, 29, 17, 46, 16, 0, time.UTC); !vl.Location.Timestamp.Equal(want) {
+		t.Errorf("expected %v, got %v", want, vl.Location.Timestamp)
+	}
+	if got := vl.Location.Lat; got < 23.1116 || got > 23.1117 {
+		t.Errorf("expected latitude 23.1116, got %f", got)
+	}
+	if got := vl.Location.Lon; got < 114.4092 || got > 114.4093 {
+		t.Errorf("expected longitude 114.4092, got %f", got)
+	}
+	tm := vl.Telemetry
+	if *tm.Speed != 0 || *tm.Heading != 143 || *tm.Satellites != 15 || tm.Ignition != nil {
+		t.Errorf("unexpected telemetry: speed %v heading %v satellites %v", *tm.Speed, *tm.Heading, *tm.Satellites)
+	}
+}
+
+func TestDecodeGT06Location_Hemispheres(t *testing.T) {
+	content := mustHex(t, "0b081d112e10cf027ac7eb0c465849000000")
+	// west and south, positioned
+	content[16], content[17] = 0x18, 0x8f
+	vl, ok, err := decodeGT06Location(gt06Location, content)
+	if err != nil || !ok {
+		t.Fatalf("expected a positioned fix, got %v, %v", ok, err)
+	}
+	if vl.Location.Lat >= 0 || vl.Location.Lon >= 0 {
+		t.Errorf("expected south west, got %f, %f", vl.Location.Lat, vl.Location.Lon)
+	}
+
+	// not positioned
+	content[16] = 0x04
+	if _, ok, _ := decodeGT06Location(gt06Location, content); ok {
+		t.Error("expected a fix without position to be skipped")
+	}
+}
+
+func TestReadGT06Packet_Invalid(t *testing.T) {
+	badCRC := mustHex(t, gt06LoginFrame)
+	badCRC[len(badCRC)-3] ^= 0xff
+	tests := map[string][]byte{
+		"bad crc":   badCRC,
+		"bad start": mustHex(t, "7777"+gt06LoginFrame[4:]),
+		"bad stop":  mustHex(t, gt06LoginFrame[:len(gt06LoginFrame)-4]+"0d0d"),
+		"truncated": mustHex(t, gt06LoginFrame[:20]),
+	}
+	for name, frame := range tests {
+		if _, err := readGT06Packet(bufio.NewReader(bytes.NewReader(frame))); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
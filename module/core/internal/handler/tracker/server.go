@@ -0,0 +1,311 @@
+// Package tracker is a TCP gateway for GPS trackers that speak binary
+// protocols instead of MQTT. It decodes their fixes and feeds them to the same
+// save and geofence path as the MQTT subscriber.
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+const (
+	// idleTimeout closes a connection whose tracker has sent nothing for this
+	// long. Trackers send heartbeats every few minutes while parked.
+	idleTimeout = 10 * time.Minute
+	// deadLetterTimeout bounds recording a fix that could not be saved.
+	deadLetterTimeout = 5 * time.Second
+)
+
+// gatewayMetrics is served under "tracker_gateway" by expvar: connections is
+// the number of open tracker connections, rejected counts logins from
+// unregistered IMEIs and locations counts fixes passed on to be saved.
+var gatewayMetrics = expvar.NewMap("tracker_gateway")
+
+type locationService interface {
+	SaveLocations(ctx context.Context, vls []domain.VehicleLocation) (live []bool, err error)
+}
+
+type geofenceService interface {
+	CheckAndAlert(ctx context.Context, vl *domain.VehicleLocation) error
+}
+
+type trackerService interface {
+	VehicleForIMEI(ctx context.Context, imei string) (string, error)
+}
+
+type deadLetterService interface {
+	RecordDeadLetter(ctx context.Context, dl *domain.DeadLetter) error
+}
+
+// Options sets the address each protocol listens on, such as ":5023". An
+// empty address disables that protocol.
+type Options struct {
+	GT06Addr      string
+	TeltonikaAddr string
+}
+
+type Server struct {
+	locationSvc locationService
+	geofenceSvc geofenceService
+	trackers    trackerService
+	deadLetters deadLetterService
+	opts        Options
+
+	// mu guards closed, listeners and conns.
+	mu        sync.Mutex
+	closed    bool
+	listeners []net.Listener
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+func NewServer(locationSvc locationService, geofenceSvc geofenceService, trackers trackerService, deadLetters deadLetterService, opts Options) *Server {
+	return &Server{
+		locationSvc: locationSvc,
+		geofenceSvc: geofenceSvc,
+		trackers:    trackers,
+		deadLetters: deadLetters,
+		opts:        opts,
+		conns:       map[net.Conn]struct{}{},
+	}
+}
+
+// Start opens a listener for each enabled protocol and accepts trackers in
+// the background.
+func (s *Server) Start() error {
+	protocols := []struct {
+		name  string
+		addr  string
+		serve func(ctx context.Context, conn net.Conn) error
+	}{
+		{"gt06", s.opts.GT06Addr, s.serveGT06},
+		{"teltonika", s.opts.TeltonikaAddr, s.serveTeltonika},
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range protocols {
+		if p.addr == "" {
+			continue
+		}
+		ln, err := net.Listen("tcp", p.addr)
+		if err != nil {
+			for _, l := range s.listeners {
+				_ = l.Close()
+			}
+			return fmt.Errorf("%s listener: %w", p.name, err)
+		}
+		log.Printf("%s trackers listening on %s", p.name, ln.Addr())
+		s.listeners = append(s.listeners, ln)
+		s.wg.Add(1)
+		go s.accept(ln, p.name, p.serve)
+	}
+	return nil
+}
+
+// Stop closes the listeners and every tracker connection, then waits for
+// fixes already being processed, or until ctx is done. Trackers reconnect and
+// resend what was not acknowledged.
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		for _, ln := range s.listeners {
+			_ = ln.Close()
+		}
+		for conn := range s.conns {
+			_ = conn.Close()
+		}
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) accept(ln net.Listener, name string, serve func(ctx context.Context, conn net.Conn) error) {
+	defer s.wg.Done()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("%s accept: %v", name, err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		if !s.track(conn) {
+			_ = conn.Close()
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+
+			err := serve(context.Background(), &idleConn{Conn: conn})
+			if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Printf("%s tracker %s: %v", name, conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	gatewayMetrics.Add("connections", 1)
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	_ = conn.Close()
+	gatewayMetrics.Add("connections", -1)
+}
+
+// login resolves the vehicle a tracker is installed in.
+func (s *Server) login(ctx context.Context, imei string) (string, error) {
+	vehicleID, err := s.trackers.VehicleForIMEI(ctx, imei)
+	if errors.Is(err, domain.ErrTrackerNotFound) {
+		gatewayMetrics.Add("rejected", 1)
+		return "", fmt.Errorf("login: unknown imei %s", imei)
+	}
+	if err != nil {
+		return "", fmt.Errorf("login %s: %w", imei, err)
+	}
+	return vehicleID, nil
+}
+
+// process saves the fixes of one packet and runs the geofence check on those
+// that are live, in timestamp order, like the MQTT subscriber does. The fixes
+// are written before process returns, bypassing the write buffer, so a packet
+// is only acknowledged once it is stored. Fixes outside the valid coordinate
+// range are dropped. Only a failed save is returned.
+func (s *Server) process(ctx context.Context, locs []domain.VehicleLocation) error {
+	valid := locs[:0]
+	for _, vl := range locs {
+		if err := validateFix(&vl); err != nil {
+			log.Printf("dropped fix for %s: %v", vl.VehicleID, err)
+			continue
+		}
+		valid = append(valid, vl)
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+	gatewayMetrics.Add("locations", int64(len(valid)))
+
+	live, err := s.locationSvc.SaveLocations(ctx, valid)
+	if err != nil {
+		return fmt.Errorf("save location error: %w", err)
+	}
+	for i := range valid {
+		if !live[i] {
+			continue
+		}
+		if err := s.geofenceSvc.CheckAndAlert(ctx, &valid[i]); err != nil {
+			log.Printf("geofence check error: %v", err)
+		}
+	}
+	return nil
+}
+
+// fixMessage is a fix in the MQTT location payload, so a dead-lettered fix can
+// be reprocessed like a rejected MQTT message.
+type fixMessage struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Timestamp int64   `json:"timestamp"`
+	domain.Telemetry
+}
+
+// deadLetter records a fix that could not be saved on its vehicle's location
+// topic.
+func (s *Server) deadLetter(vl *domain.VehicleLocation, reason error) {
+	payload, err := json.Marshal(fixMessage{
+		Latitude:  vl.Location.Lat,
+		Longitude: vl.Location.Lon,
+		Timestamp: vl.Location.Timestamp.Unix(),
+		Telemetry: vl.Telemetry,
+	})
+	if err != nil {
+		log.Printf("encode fix for %s: %v", vl.VehicleID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deadLetterTimeout)
+	defer cancel()
+	dl := &domain.DeadLetter{
+		Topic:      "/fleet/vehicle/" + vl.VehicleID + "/location",
+		Payload:    payload,
+		Reason:     reason.Error(),
+		ReceivedAt: time.Now(),
+	}
+	if err := s.deadLetters.RecordDeadLetter(ctx, dl); err != nil {
+		log.Printf("record dead letter for %s: %v", vl.VehicleID, err)
+	}
+}
+
+func validateFix(vl *domain.VehicleLocation) error {
+	if vl.Location.Lat < -90 || vl.Location.Lat > 90 {
+		return fmt.Errorf("latitude: must be between -90 and 90")
+	}
+	if vl.Location.Lon < -180 || vl.Location.Lon > 180 {
+		return fmt.Errorf("longitude: must be between -180 and 180")
+	}
+	if vl.Location.Timestamp.Unix() <= 0 {
+		return fmt.Errorf("timestamp: must be positive")
+	}
+	return nil
+}
+
+// idleConn pushes the read deadline back on every read, so a connection is
+// closed once its tracker has been silent for idleTimeout.
+type idleConn struct {
+	net.Conn
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(idleTimeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+// readFull reads exactly n bytes.
+func readFull(r *bufio.Reader, n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,262 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+type mockLocationSvc struct {
+	mu    sync.Mutex
+	saved []domain.VehicleLocation
+	err   error
+}
+
+func (m *mockLocationSvc) SaveLocations(_ context.Context, vls []domain.VehicleLocation) ([]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.saved = append(m.saved, vls...)
+	live := make([]bool, len(vls))
+	for i := range live {
+		live[i] = true
+	}
+	return live, nil
+}
+
+type mockGeofenceSvc struct {
+	mu      sync.Mutex
+	checked []domain.VehicleLocation
+}
+
+func (m *mockGeofenceSvc) CheckAndAlert(_ context.Context, vl *domain.VehicleLocation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checked = append(m.checked, *vl)
+	return nil
+}
+
+type mockTrackerSvc map[string]string
+
+func (m mockTrackerSvc) VehicleForIMEI(_ context.Context, imei string) (string, error) {
+	if id, ok := m[imei]; ok {
+		return id, nil
+	}
+	return "", domain.ErrTrackerNotFound
+}
+
+type mockDeadLetterSvc struct {
+	mu       sync.Mutex
+	recorded []domain.DeadLetter
+}
+
+func (m *mockDeadLetterSvc) RecordDeadLetter(_ context.Context, dl *domain.DeadLetter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recorded = append(m.recorded, *dl)
+	return nil
+}
+
+func startServer(t *testing.T) (*Server, *mockLocationSvc, *mockGeofenceSvc) {
+	t.Helper()
+	s, locSvc, geoSvc, _ := startServerWithDeadLetters(t, nil)
+	return s, locSvc, geoSvc
+}
+
+// startServerWithDeadLetters starts a server whose saves fail with saveErr.
+func startServerWithDeadLetters(t *testing.T, saveErr error) (*Server, *mockLocationSvc, *mockGeofenceSvc, *mockDeadLetterSvc) {
+	t.Helper()
+	locSvc := &mockLocationSvc{err: saveErr}
+	geoSvc := &mockGeofenceSvc{}
+	dlSvc := &mockDeadLetterSvc{}
+	trackers := mockTrackerSvc{"123456789012345": "B1234XYZ"}
+	s := NewServer(locSvc, geoSvc, trackers, dlSvc, Options{GT06Addr: "127.0.0.1:0", TeltonikaAddr: "127.0.0.1:0"})
+	if err := s.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Stop(context.Background()) })
+	return s, locSvc, geoSvc, dlSvc
+}
+
+func dial(t *testing.T, ln net.Listener) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func readN(t *testing.T, conn net.Conn, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := io.ReadFull(conn, b); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return b
+}
+
+// gt06Frame encodes a short GT06 frame.
+func gt06Frame(protocol byte, content []byte, serial uint16) []byte {
+	b := []byte{0x78, 0x78, byte(len(content) + 5), protocol}
+	b = append(b, content...)
+	b = binary.BigEndian.AppendUint16(b, serial)
+	b = binary.BigEndian.AppendUint16(b, crcITU(b[2:]))
+	return binary.BigEndian.AppendUint16(b, gt06Stop)
+}
+
+func TestServer_GT06(t *testing.T) {
+	s, locSvc, geoSvc := startServer(t)
+	conn := dial(t, s.listeners[0])
+
+	_, _ = conn.Write(mustHex(t, gt06LoginFrame))
+	if got, want := readN(t, conn, 10), mustHex(t, gt06LoginAck); !bytes.Equal(got, want) {
+		t.Fatalf("expected login ack %x, got %x", want, got)
+	}
+
+	_, _ = conn.Write(mustHex(t, gt06LocationFrame))
+	// packets are handled in order, so the heartbeat ack follows the save
+	_, _ = conn.Write(gt06Frame(gt06Heartbeat, []byte{0x40, 0x04, 0x04, 0x00, 0x01}, 2))
+	if got, want := readN(t, conn, 10), gt06Response(gt06Heartbeat, 2); !bytes.Equal(got, want) {
+		t.Fatalf("expected heartbeat ack %x, got %x", want, got)
+	}
+
+	locSvc.mu.Lock()
+	defer locSvc.mu.Unlock()
+	geoSvc.mu.Lock()
+	defer geoSvc.mu.Unlock()
+	if len(locSvc.saved) != 1 || locSvc.saved[0].VehicleID != "B1234XYZ" {
+		t.Fatalf("expected 1 location saved for B1234XYZ, got %+v", locSvc.saved)
+	}
+	if len(geoSvc.checked) != 1 {
+		t.Errorf("expected 1 geofence check, got %d", len(geoSvc.checked))
+	}
+}
+
+func TestServer_GT06_FailedSaveIsDeadLettered(t *testing.T) {
+	s, _, geoSvc, dlSvc := startServerWithDeadLetters(t, errors.New("db down"))
+	conn := dial(t, s.listeners[0])
+
+	_, _ = conn.Write(mustHex(t, gt06LoginFrame))
+	readN(t, conn, 10)
+	_, _ = conn.Write(mustHex(t, gt06LocationFrame))
+	_, _ = conn.Write(gt06Frame(gt06Heartbeat, []byte{0x40, 0x04, 0x04, 0x00, 0x01}, 2))
+	readN(t, conn, 10)
+
+	dlSvc.mu.Lock()
+	defer dlSvc.mu.Unlock()
+	if len(dlSvc.recorded) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(dlSvc.recorded))
+	}
+	dl := dlSvc.recorded[0]
+	if dl.Topic != "/fleet/vehicle/B1234XYZ/location" || dl.Reason != "save location error: db down" {
+		t.Errorf("unexpected dead letter: %+v", dl)
+	}
+	var msg fixMessage
+	if err := json.Unmarshal(dl.Payload, &msg); err != nil || msg.Timestamp <= 0 || msg.Satellites == nil {
+		t.Errorf("expected the fix as an MQTT location payload, got %s: %v", dl.Payload, err)
+	}
+	if len(geoSvc.checked) != 0 {
+		t.Errorf("expected no geofence check, got %d", len(geoSvc.checked))
+	}
+}
+
+func TestServer_GT06_UnknownIMEI(t *testing.T) {
+	s, _, _ := startServer(t)
+	conn := dial(t, s.listeners[0])
+
+	_, _ = conn.Write(gt06Frame(gt06Login, mustHex(t, "0356307042441013"), 1))
+
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected the connection to be closed, got %v", err)
+	}
+}
+
+func TestServer_GT06_LocationBeforeLogin(t *testing.T) {
+	s, locSvc, _ := startServer(t)
+	conn := dial(t, s.listeners[0])
+
+	_, _ = conn.Write(mustHex(t, gt06LocationFrame))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected the connection to be closed, got %v", err)
+	}
+	if len(locSvc.saved) != 0 {
+		t.Errorf("expected nothing saved, got %d", len(locSvc.saved))
+	}
+}
+
+func TestServer_Teltonika(t *testing.T) {
+	s, locSvc, geoSvc := startServer(t)
+	conn := dial(t, s.listeners[1])
+
+	_, _ = conn.Write(append([]byte{0, 15}, "123456789012345"...))
+	if got := readN(t, conn, 1); got[0] != 0x01 {
+		t.Fatalf("expected the imei to be accepted, got %x", got)
+	}
+
+	ts := time.Unix(1715003456, 0)
+	_, _ = conn.Write(avlPacket(
+		avlRecord(ts.Add(10*time.Second), -6.2091, 106.846, 9),
+		avlRecord(ts, -6.2088, 106.8456, 9),
+		avlRecord(ts.Add(20*time.Second), 0, 0, 0),
+	))
+	if got := binary.BigEndian.Uint32(readN(t, conn, 4)); got != 3 {
+		t.Fatalf("expected 3 records acknowledged, got %d", got)
+	}
+
+	locSvc.mu.Lock()
+	defer locSvc.mu.Unlock()
+	if len(locSvc.saved) != 2 || locSvc.saved[0].VehicleID != "B1234XYZ" {
+		t.Fatalf("expected the 2 fixes saved for B1234XYZ, got %+v", locSvc.saved)
+	}
+	geoSvc.mu.Lock()
+	defer geoSvc.mu.Unlock()
+	if len(geoSvc.checked) != 2 {
+		t.Errorf("expected 2 geofence checks, got %d", len(geoSvc.checked))
+	}
+}
+
+func TestServer_Teltonika_FailedSaveIsNotAcknowledged(t *testing.T) {
+	s, _, _, dlSvc := startServerWithDeadLetters(t, errors.New("db down"))
+	conn := dial(t, s.listeners[1])
+
+	_, _ = conn.Write(append([]byte{0, 15}, "123456789012345"...))
+	readN(t, conn, 1)
+	_, _ = conn.Write(avlPacket(avlRecord(time.Unix(1715003456, 0), -6.2088, 106.8456, 9)))
+
+	// the tracker resends the packet after reconnecting
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected the connection to be closed without an ack, got %v", err)
+	}
+	dlSvc.mu.Lock()
+	defer dlSvc.mu.Unlock()
+	if len(dlSvc.recorded) != 0 {
+		t.Errorf("expected no dead letter, got %d", len(dlSvc.recorded))
+	}
+}
+
+func TestServer_Teltonika_UnknownIMEI(t *testing.T) {
+	s, _, _ := startServer(t)
+	conn := dial(t, s.listeners[1])
+
+	_, _ = conn.Write(append([]byte{0, 15}, "356307042441013"...))
+	if got := readN(t, conn, 1); got[0] != 0x00 {
+		t.Fatalf("expected the imei to be refused, got %x", got)
+	}
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected the connection to be closed, got %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+const (
+	teltonikaCodec8 = 0x08
+	// maxTeltonikaData bounds the data field of one AVL packet; trackers send
+	// at most a few kilobytes.
+	maxTeltonikaData = 64 << 10
+
+	// AVL IO element IDs.
+	teltonikaIOIgnition = 239
+	teltonikaIOOdometer = 16
+)
+
+var errTeltonikaTruncated = errors.New("teltonika: record truncated")
+
+// serveTeltonika handles a Teltonika tracker speaking Codec 8. The tracker
+// sends its IMEI, which is accepted with 0x01 or refused with 0x00, then AVL
+// packets of one or more records. A packet is acknowledged with its record
+// count only once it has been saved; the tracker resends packets that are not
+// acknowledged.
+func (s *Server) serveTeltonika(ctx context.Context, conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	imei, err := readTeltonikaIMEI(r)
+	if err != nil {
+		return err
+	}
+	vehicleID, err := s.login(ctx, imei)
+	if err != nil {
+		_, _ = conn.Write([]byte{0x00})
+		return err
+	}
+	if _, err := conn.Write([]byte{0x01}); err != nil {
+		return err
+	}
+
+	for {
+		locs, count, err := readTeltonikaPacket(r)
+		if err != nil {
+			return err
+		}
+		for i := range locs {
+			locs[i].VehicleID = vehicleID
+		}
+		if len(locs) > 0 {
+			if err := s.process(ctx, locs); err != nil {
+				return err
+			}
+		}
+		if _, err := conn.Write(binary.BigEndian.AppendUint32(nil, uint32(count))); err != nil {
+			return err
+		}
+	}
+}
+
+// readTeltonikaIMEI reads the handshake: the IMEI in ASCII, prefixed by its
+// length.
+func readTeltonikaIMEI(r *bufio.Reader) (string, error) {
+	head, err := readFull(r, 2)
+	if err != nil {
+		return "", err
+	}
+	n := int(binary.BigEndian.Uint16(head))
+	if n == 0 || n > 32 {
+		return "", fmt.Errorf("teltonika: imei length %d", n)
+	}
+	imei, err := readFull(r, n)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range imei {
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("teltonika: imei %q is not numeric", imei)
+		}
+	}
+	return string(imei), nil
+}
+
+// readTeltonikaPacket reads one AVL packet and decodes its records. It returns
+// the fixes that have a GPS position and the number of records in the packet,
+// which is what the tracker expects as acknowledgement.
+func readTeltonikaPacket(r *bufio.Reader) ([]domain.VehicleLocation, int, error) {
+	head, err := readFull(r, 8)
+	if err != nil {
+		return nil, 0, err
+	}
+	if binary.BigEndian.Uint32(head[:4]) != 0 {
+		return nil, 0, fmt.Errorf("teltonika: bad preamble %x", head[:4])
+	}
+	n := binary.BigEndian.Uint32(head[4:])
+	if n < 3 || n > maxTeltonikaData {
+		return nil, 0, fmt.Errorf("teltonika: data length %d", n)
+	}
+
+	data, err := readFull(r, int(n)+4)
+	if err != nil {
+		return nil, 0, err
+	}
+	crc := binary.BigEndian.Uint32(data[n:])
+	data = data[:n]
+	if want := crcIBM(data); crc != uint32(want) {
+		return nil, 0, fmt.Errorf("teltonika: crc %08x, want %08x", crc, want)
+	}
+
+	return decodeCodec8(data)
+}
+
+// decodeCodec8 decodes the data field of an AVL packet: the codec ID, the
+// record count, the records and the record count again.
+func decodeCodec8(data []byte) ([]domain.VehicleLocation, int, error) {
+	if data[0] != teltonikaCodec8 {
+		return nil, 0, fmt.Errorf("teltonika: unsupported codec 0x%02x", data[0])
+	}
+	count := int(data[1])
+	if int(data[len(data)-1]) != count {
+		return nil, 0, fmt.Errorf("teltonika: record counts %d and %d differ", count, data[len(data)-1])
+	}
+
+	c := &cursor{b: data[2 : len(data)-1]}
+	locs := make([]domain.VehicleLocation, 0, count)
+	for range count {
+		vl, ok := decodeAVLRecord(c)
+		if c.err != nil {
+			return nil, 0, c.err
+		}
+		if ok {
+			locs = append(locs, *vl)
+		}
+	}
+	if len(c.b) != 0 {
+		return nil, 0, fmt.Errorf("teltonika: %d bytes after the last record", len(c.b))
+	}
+	return locs, count, nil
+}
+
+// decodeAVLRecord decodes one record. It reports false for a record without a
+// GPS fix, which Teltonika marks with zero satellites.
+func decodeAVLRecord(c *cursor) (*domain.VehicleLocation, bool) {
+	ts := time.Unix(int64(c.uint64()/1000), 0)
+	c.skip(1) // priority
+	lon := float64(int32(c.uint32())) / 1e7
+	lat := float64(int32(c.uint32())) / 1e7
+	altitude := float64(int16(c.uint16()))
+	angle := float64(c.uint16())
+	satellites := int(c.uint8())
+	speed := float64(c.uint16())
+
+	vl := &domain.VehicleLocation{
+		Location: domain.Location{Lat: lat, Lon: lon, Timestamp: ts},
+		Telemetry: domain.Telemetry{
+			Speed:      &speed,
+			Altitude:   &altitude,
+			Satellites: &satellites,
+		},
+	}
+	if angle < 360 {
+		vl.Telemetry.Heading = &angle
+	}
+
+	c.skip(2) // event IO ID and total IO count
+	for _, size := range []int{1, 2, 4, 8} {
+		for range int(c.uint8()) {
+			id := c.uint8()
+			v := c.uint(size)
+			switch {
+			case id == teltonikaIOIgnition && size == 1:
+				ignition := v != 0
+				vl.Telemetry.Ignition = &ignition
+			case id == teltonikaIOOdometer && size == 4:
+				odometer := float64(v) / 1000 // reported in meters
+				vl.Telemetry.Odometer = &odometer
+			}
+		}
+	}
+	return vl, satellites > 0
+}
+
+// cursor reads big-endian values from an AVL packet. A read past the end
+// sets err and returns zero.
+type cursor struct {
+	b   []byte
+	err error
+}
+
+func (c *cursor) next(n int) []byte {
+	if c.err != nil || len(c.b) < n {
+		c.err = errTeltonikaTruncated
+		return make([]byte, n)
+	}
+	v := c.b[:n]
+	c.b = c.b[n:]
+	return v
+}
+
+func (c *cursor) skip(n int)     { c.next(n) }
+func (c *cursor) uint8() uint8   { return c.next(1)[0] }
+func (c *cursor) uint16() uint16 { return binary.BigEndian.Uint16(c.next(2)) }
+func (c *cursor) uint32() uint32 { return binary.BigEndian.Uint32(c.next(4)) }
+func (c *cursor) uint64() uint64 { return binary.BigEndian.Uint64(c.next(8)) }
+func (c *cursor) uint(n int) uint64 {
+	switch n {
+	case 1:
+		return uint64(c.uint8())
+	case 2:
+		return uint64(c.uint16())
+	case 4:
+		return uint64(c.uint32())
+	}
+	return c.uint64()
+}
+
+// crcIBM is CRC-16/ARC, which Teltonika calls CRC-16/IBM.
+func crcIBM(b []byte) uint16 {
+	var crc uint16
+	for _, v := range b {
+		crc ^= uint16(v)
+		for range 8 {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
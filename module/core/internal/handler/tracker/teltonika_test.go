@@ -0,0 +1,110 @@
+package tracker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// teltonikaSample is the first Codec 8 example from the Teltonika
+// documentation: one record without a GPS fix.
+const teltonikaSample = "000000000000003608010000016b40d8ea30010000000000000000000000000000000105021503010101425e0f01f10000601a014e0000000000000000010000c7cf"
+
+// avlRecord encodes one Codec 8 record with ignition on and an odometer.
+func avlRecord(ts time.Time, lat, lon float64, satellites byte) []byte {
+	b := binary.BigEndian.AppendUint64(nil, uint64(ts.UnixMilli()))
+	b = append(b, 0) // priority
+	b = binary.BigEndian.AppendUint32(b, uint32(int32(lon*1e7)))
+	b = binary.BigEndian.AppendUint32(b, uint32(int32(lat*1e7)))
+	b = binary.BigEndian.AppendUint16(b, 12)  // altitude
+	b = binary.BigEndian.AppendUint16(b, 270) // angle
+	b = append(b, satellites)
+	b = binary.BigEndian.AppendUint16(b, 42) // speed
+	b = append(b, 0, 2)                      // event IO ID, total IO count
+	b = append(b, 1, teltonikaIOIgnition, 1) // one 1-byte element
+	b = append(b, 0)                         // no 2-byte elements
+	b = append(b, 1, teltonikaIOOdometer)    // one 4-byte element
+	b = binary.BigEndian.AppendUint32(b, 10523400)
+	return append(b, 0) // no 8-byte elements
+}
+
+// avlPacket frames records as a Codec 8 AVL packet.
+func avlPacket(records ...[]byte) []byte {
+	data := []byte{teltonikaCodec8, byte(len(records))}
+	for _, r := range records {
+		data = append(data, r...)
+	}
+	data = append(data, byte(len(records)))
+
+	b := binary.BigEndian.AppendUint32(make([]byte, 4), uint32(len(data)))
+	b = append(b, data...)
+	return binary.BigEndian.AppendUint32(b, uint32(crcIBM(data)))
+}
+
+func TestReadTeltonikaPacket_Sample(t *testing.T) {
+	locs, count, err := readTeltonikaPacket(bufio.NewReader(bytes.NewReader(mustHex(t, teltonikaSample))))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 1 || len(locs) != 0 {
+		t.Errorf("expected 1 record without a fix, got count %d and %d fixes", count, len(locs))
+	}
+}
+
+func TestReadTeltonikaPacket_Records(t *testing.T) {
+	ts := time.Unix(1715003456, 0)
+	packet := avlPacket(
+		avlRecord(ts, -6.2088, 106.8456, 9),
+		avlRecord(ts.Add(10*time.Second), -6.2091, 106.846, 0),
+	)
+
+	locs, count, err := readTeltonikaPacket(bufio.NewReader(bytes.NewReader(packet)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 2 || len(locs) != 1 {
+		t.Fatalf("expected 2 records with 1 fix, got count %d and %d fixes", count, len(locs))
+	}
+	vl := locs[0]
+	if !vl.Location.Timestamp.Equal(ts) || vl.Location.Lat != -6.2088 || vl.Location.Lon != 106.8456 {
+		t.Errorf("unexpected location: %+v", vl.Location)
+	}
+	tm := vl.Telemetry
+	if *tm.Speed != 42 || *tm.Heading != 270 || *tm.Altitude != 12 || *tm.Satellites != 9 || !*tm.Ignition || *tm.Odometer != 10523.4 {
+		t.Errorf("unexpected telemetry: %+v", tm)
+	}
+}
+
+func TestReadTeltonikaPacket_Invalid(t *testing.T) {
+	record := avlRecord(time.Unix(1715003456, 0), -6.2, 106.8, 9)
+
+	badCRC := avlPacket(record)
+	badCRC[len(badCRC)-1] ^= 0xff
+
+	codec8E := avlPacket(record)
+	codec8E[8] = 0x8e
+	binary.BigEndian.PutUint32(codec8E[len(codec8E)-4:], uint32(crcIBM(codec8E[8:len(codec8E)-4])))
+
+	truncated := avlPacket(record[:20])
+
+	tests := map[string][]byte{
+		"bad crc":   badCRC,
+		"codec 8e":  codec8E,
+		"truncated": truncated,
+	}
+	for name, packet := range tests {
+		if _, _, err := readTeltonikaPacket(bufio.NewReader(bytes.NewReader(packet))); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if _, _, err := decodeCodec8(avlPacket(record)[8 : len(avlPacket(record))-4]); err != nil {
+		t.Errorf("unexpected error for a valid packet: %v", err)
+	}
+	if _, _, err := decodeCodec8([]byte{teltonikaCodec8, 1, 0, 1}); !errors.Is(err, errTeltonikaTruncated) {
+		t.Errorf("expected errTeltonikaTruncated, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+// Package queue is the bounded queue behind the location writer and the
+// subscriber's worker pool and dead letter recorder: producers add to it until
+// it is closed, and its consumers then drain what is left.
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned when adding to a closed queue.
+var ErrClosed = errors.New("queue closed")
+
+// Queue is a bounded channel that producers may still be adding to, or
+// waiting for room in, when it is closed.
+type Queue[T any] struct {
+	c chan T
+	// closing is closed by Close to wake producers waiting for room.
+	closing chan struct{}
+
+	// mu guards closed. No lock is held while a producer waits for room;
+	// instead Close waits for the producers in senders before closing c.
+	mu      sync.Mutex
+	closed  bool
+	senders sync.WaitGroup
+}
+
+func New[T any](size int) *Queue[T] {
+	return &Queue[T]{
+		c:       make(chan T, size),
+		closing: make(chan struct{}),
+	}
+}
+
+// C is the channel consumers receive from. It is closed once the queue is
+// closed and drained.
+func (q *Queue[T]) C() <-chan T {
+	return q.c
+}
+
+// Push adds v, waiting for room until ctx is done or the queue is closed.
+func (q *Queue[T]) Push(ctx context.Context, v T) error {
+	if !q.enter() {
+		return ErrClosed
+	}
+	defer q.senders.Done()
+
+	select {
+	case q.c <- v:
+		return nil
+	case <-q.closing:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryPush adds v if there is room and reports whether it did.
+func (q *Queue[T]) TryPush(v T) (bool, error) {
+	if !q.enter() {
+		return false, ErrClosed
+	}
+	defer q.senders.Done()
+
+	select {
+	case q.c <- v:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// enter registers a producer, reporting false if the queue is closed.
+func (q *Queue[T]) enter() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	q.senders.Add(1)
+	return true
+}
+
+// Close stops the queue accepting values and wakes producers waiting for
+// room. Values already queued are still received from C.
+func (q *Queue[T]) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.closing)
+	q.mu.Unlock()
+
+	q.senders.Wait()
+	close(q.c)
+}
+
+// Wait waits until done is closed, typically once the consumers have drained
+// the queue, or until ctx is done.
+func Wait(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestQueue_DrainsAfterClose(t *testing.T) {
+	q := New[int](3)
+	for i := range 3 {
+		if err := q.Push(context.Background(), i); err != nil {
+			t.Fatalf("push %d: %v", i, err)
+		}
+	}
+	if ok, err := q.TryPush(3); ok || err != nil {
+		t.Fatalf("expected a full queue to refuse without an error, got %v, %v", ok, err)
+	}
+
+	q.Close()
+	q.Close()
+	if err := q.Push(context.Background(), 4); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed from Push, got %v", err)
+	}
+	if _, err := q.TryPush(4); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed from TryPush, got %v", err)
+	}
+
+	var got []int
+	for v := range q.C() {
+		got = append(got, v)
+	}
+	if want := []int{0, 1, 2}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestQueue_PushWaitsForContext(t *testing.T) {
+	q := New[int](0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := q.Push(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestQueue_CloseWakesWaitingPush(t *testing.T) {
+	q := New[int](1)
+	if err := q.Push(context.Background(), 1); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	pushed := make(chan error, 1)
+	go func() { pushed <- q.Push(context.Background(), 2) }()
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		q.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("expected Close not to wait for room in the queue")
+	}
+	if err := <-pushed; !errors.Is(err, ErrClosed) {
+		t.Errorf("expected the waiting push to return ErrClosed, got %v", err)
+	}
+}
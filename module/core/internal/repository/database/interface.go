@@ -8,7 +8,52 @@ import (
 
 type LocationRepository interface {
 	Insert(ctx context.Context, loc *domain.VehicleLocation) error
+	InsertBatch(ctx context.Context, locs []domain.VehicleLocation) error
 	GetLatest(ctx context.Context, vehicleID string) (*domain.VehicleLocation, error)
 	GetHistory(ctx context.Context, query *domain.HistoryQuery) ([]domain.VehicleLocation, error)
 	GetAllVehicles(ctx context.Context) ([]domain.Vehicle, error)
 }
+
+type GeofenceRepository interface {
+	List(ctx context.Context) ([]domain.Geofence, error)
+	Get(ctx context.Context, id string) (*domain.Geofence, error)
+	Create(ctx context.Context, gf *domain.Geofence) error
+	Update(ctx context.Context, gf *domain.Geofence) error
+	Delete(ctx context.Context, id string) error
+}
+
+type GeofenceStateRepository interface {
+	GetByVehicle(ctx context.Context, vehicleID string) ([]domain.GeofenceState, error)
+	ListInside(ctx context.Context, geofenceID string) ([]domain.GeofenceState, error)
+	Upsert(ctx context.Context, state *domain.GeofenceState) error
+}
+
+type VehicleAssignmentRepository interface {
+	Get(ctx context.Context, vehicleID string) (*domain.VehicleAssignment, error)
+	Upsert(ctx context.Context, assignment *domain.VehicleAssignment) error
+	Delete(ctx context.Context, vehicleID string) error
+}
+
+type GeofenceVisitRepository interface {
+	Insert(ctx context.Context, visit *domain.GeofenceVisit) error
+	List(ctx context.Context, query *domain.VisitQuery) ([]domain.GeofenceVisit, error)
+}
+
+type VisitReportRepository interface {
+	Create(ctx context.Context, report *domain.VisitReport) error
+	Get(ctx context.Context, id int64) (*domain.VisitReport, error)
+}
+
+type DeadLetterRepository interface {
+	Insert(ctx context.Context, dl *domain.DeadLetter) error
+	List(ctx context.Context, limit int) ([]domain.DeadLetter, error)
+	Get(ctx context.Context, id int64) (*domain.DeadLetter, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type TrackerRepository interface {
+	List(ctx context.Context) ([]domain.Tracker, error)
+	Get(ctx context.Context, imei string) (*domain.Tracker, error)
+	Upsert(ctx context.Context, tracker *domain.Tracker) error
+	Delete(ctx context.Context, imei string) error
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
+)
+
+var _ database.DeadLetterRepository = (*DeadLetterRepo)(nil)
+
+type DeadLetterRepo struct {
+	db *sql.DB
+}
+
+func NewDeadLetterRepo(db *sql.DB) *DeadLetterRepo {
+	return &DeadLetterRepo{db: db}
+}
+
+// Insert stores the dead letter and fills in its ID.
+func (r *DeadLetterRepo) Insert(ctx context.Context, dl *domain.DeadLetter) error {
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO dead_letters (topic, payload, reason, received_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+		dl.Topic, dl.Payload, dl.Reason, dl.ReceivedAt,
+	).Scan(&dl.ID)
+}
+
+// List returns up to limit dead letters, most recently received first.
+func (r *DeadLetterRepo) List(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, topic, payload, reason, received_at FROM dead_letters ORDER BY received_at DESC, id DESC LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	results := []domain.DeadLetter{}
+	for rows.Next() {
+		var dl domain.DeadLetter
+		if err := rows.Scan(&dl.ID, &dl.Topic, &dl.Payload, &dl.Reason, &dl.ReceivedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, dl)
+	}
+	return results, rows.Err()
+}
+
+func (r *DeadLetterRepo) Get(ctx context.Context, id int64) (*domain.DeadLetter, error) {
+	dl := domain.DeadLetter{ID: id}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT topic, payload, reason, received_at FROM dead_letters WHERE id = $1`,
+		id,
+	).Scan(&dl.Topic, &dl.Payload, &dl.Reason, &dl.ReceivedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &dl, nil
+}
+
+func (r *DeadLetterRepo) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM dead_letters WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, domain.ErrDeadLetterNotFound)
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+func TestDeadLetterInsert_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	received := time.Unix(1715003456, 0)
+	mock.ExpectQuery(`INSERT INTO dead_letters (.+) RETURNING id`).
+		WithArgs("/fleet/vehicle/B1234XYZ/location", []byte("invalid"), "invalid json", received).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+
+	repo := NewDeadLetterRepo(db)
+	dl := &domain.DeadLetter{Topic: "/fleet/vehicle/B1234XYZ/location", Payload: []byte("invalid"), Reason: "invalid json", ReceivedAt: received}
+	if err := repo.Insert(context.Background(), dl); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dl.ID != 4 {
+		t.Errorf("expected id 4, got %d", dl.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeadLetterList_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	received := time.Unix(1715003456, 0)
+	mock.ExpectQuery(`SELECT id, topic, payload, reason, received_at FROM dead_letters ORDER BY received_at DESC, id DESC LIMIT (.+)`).
+		WithArgs(50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "topic", "payload", "reason", "received_at"}).
+			AddRow(2, "/fleet/vehicle/B1/location", []byte("{}"), "vehicle_id: required", received).
+			AddRow(1, "/fleet/vehicle/B2/location", []byte("x"), "invalid json", received.Add(-time.Minute)))
+
+	repo := NewDeadLetterRepo(db)
+	results, err := repo.List(context.Background(), 50)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != 2 || string(results[1].Payload) != "x" {
+		t.Errorf("unexpected dead letters: %+v", results)
+	}
+}
+
+func TestDeadLetterGet_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`SELECT (.+) FROM dead_letters WHERE id = (.+)`).
+		WithArgs(int64(9)).
+		WillReturnError(sql.ErrNoRows)
+
+	repo := NewDeadLetterRepo(db)
+	if _, err := repo.Get(context.Background(), 9); !errors.Is(err, domain.ErrDeadLetterNotFound) {
+		t.Fatalf("expected ErrDeadLetterNotFound, got %v", err)
+	}
+}
+
+func TestDeadLetterDelete_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(`DELETE FROM dead_letters WHERE id = (.+)`).
+		WithArgs(int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewDeadLetterRepo(db)
+	if err := repo.Delete(context.Background(), 9); !errors.Is(err, domain.ErrDeadLetterNotFound) {
+		t.Fatalf("expected ErrDeadLetterNotFound, got %v", err)
+	}
+}
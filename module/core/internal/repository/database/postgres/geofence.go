@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
+)
+
+var _ database.GeofenceRepository = (*GeofenceRepo)(nil)
+
+const geofenceColumns = `id, name, tags, shape, latitude, longitude, radius, polygons, dwell_seconds, vehicle_ids, group_ids, route_ids, schedule, corridor`
+
+type GeofenceRepo struct {
+	db *sql.DB
+}
+
+func NewGeofenceRepo(db *sql.DB) *GeofenceRepo {
+	return &GeofenceRepo{db: db}
+}
+
+func (r *GeofenceRepo) List(ctx context.Context) ([]domain.Geofence, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+geofenceColumns+` FROM geofences ORDER BY id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []domain.Geofence
+	for rows.Next() {
+		gf, err := scanGeofence(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *gf)
+	}
+	return results, rows.Err()
+}
+
+func (r *GeofenceRepo) Get(ctx context.Context, id string) (*domain.Geofence, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+geofenceColumns+` FROM geofences WHERE id = $1`,
+		id,
+	)
+
+	gf, err := scanGeofence(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrGeofenceNotFound
+	}
+	return gf, err
+}
+
+// Create stores gf, generating its ID when empty. It returns
+// domain.ErrGeofenceExists if gf.ID is already taken.
+func (r *GeofenceRepo) Create(ctx context.Context, gf *domain.Geofence) error {
+	args, err := geofenceArgs(gf)
+	if err != nil {
+		return err
+	}
+
+	if gf.ID == "" {
+		return r.db.QueryRowContext(ctx,
+			`INSERT INTO geofences (name, tags, shape, latitude, longitude, radius, polygons, dwell_seconds, vehicle_ids, group_ids, route_ids, schedule, corridor) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`,
+			args...,
+		).Scan(&gf.ID)
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO geofences (id, name, tags, shape, latitude, longitude, radius, polygons, dwell_seconds, vehicle_ids, group_ids, route_ids, schedule, corridor) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) ON CONFLICT (id) DO NOTHING`,
+		append([]any{gf.ID}, args...)...,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, domain.ErrGeofenceExists)
+}
+
+func (r *GeofenceRepo) Update(ctx context.Context, gf *domain.Geofence) error {
+	args, err := geofenceArgs(gf)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE geofences SET name = $2, tags = $3, shape = $4, latitude = $5, longitude = $6, radius = $7, polygons = $8, dwell_seconds = $9, vehicle_ids = $10, group_ids = $11, route_ids = $12, schedule = $13, corridor = $14, updated_at = NOW() WHERE id = $1`,
+		append([]any{gf.ID}, args...)...,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, domain.ErrGeofenceNotFound)
+}
+
+func (r *GeofenceRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM geofences WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, domain.ErrGeofenceNotFound)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanGeofence(s scanner) (*domain.Geofence, error) {
+	var (
+		gf            domain.Geofence
+		lat, lon, rad sql.NullFloat64
+		polygons      []byte
+		schedule      []byte
+		corridor      []byte
+		dwellSeconds  int64
+	)
+	if err := s.Scan(&gf.ID, &gf.Name, pq.Array(&gf.Tags), &gf.Shape, &lat, &lon, &rad, &polygons, &dwellSeconds,
+		pq.Array(&gf.Assignment.VehicleIDs), pq.Array(&gf.Assignment.GroupIDs), pq.Array(&gf.Assignment.RouteIDs), &schedule, &corridor); err != nil {
+		return nil, err
+	}
+	gf.DwellThreshold = time.Duration(dwellSeconds) * time.Second
+
+	if lat.Valid && lon.Valid && rad.Valid {
+		gf.Circle = &domain.GeoPoint{Lat: lat.Float64, Lon: lon.Float64, Radius: rad.Float64}
+	}
+	if len(polygons) > 0 {
+		if err := json.Unmarshal(polygons, &gf.Polygons); err != nil {
+			return nil, fmt.Errorf("decode polygons for geofence %s: %w", gf.ID, err)
+		}
+	}
+	if len(schedule) > 0 {
+		if err := json.Unmarshal(schedule, &gf.Schedule); err != nil {
+			return nil, fmt.Errorf("decode schedule for geofence %s: %w", gf.ID, err)
+		}
+	}
+	if len(corridor) > 0 {
+		if err := json.Unmarshal(corridor, &gf.Corridor); err != nil {
+			return nil, fmt.Errorf("decode corridor for geofence %s: %w", gf.ID, err)
+		}
+	}
+	return &gf, nil
+}
+
+func geofenceArgs(gf *domain.Geofence) ([]any, error) {
+	var lat, lon, rad sql.NullFloat64
+	if gf.Circle != nil {
+		lat = sql.NullFloat64{Float64: gf.Circle.Lat, Valid: true}
+		lon = sql.NullFloat64{Float64: gf.Circle.Lon, Valid: true}
+		rad = sql.NullFloat64{Float64: gf.Circle.Radius, Valid: true}
+	}
+
+	var polygons []byte
+	if len(gf.Polygons) > 0 {
+		b, err := json.Marshal(gf.Polygons)
+		if err != nil {
+			return nil, fmt.Errorf("encode polygons: %w", err)
+		}
+		polygons = b
+	}
+
+	var schedule []byte
+	if gf.Schedule != nil {
+		b, err := json.Marshal(gf.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("encode schedule: %w", err)
+		}
+		schedule = b
+	}
+
+	var corridor []byte
+	if gf.Corridor != nil {
+		b, err := json.Marshal(gf.Corridor)
+		if err != nil {
+			return nil, fmt.Errorf("encode corridor: %w", err)
+		}
+		corridor = b
+	}
+
+	return []any{
+		gf.Name, textArray(gf.Tags), string(gf.Shape), lat, lon, rad, polygons, int64(gf.DwellThreshold / time.Second),
+		textArray(gf.Assignment.VehicleIDs), textArray(gf.Assignment.GroupIDs), textArray(gf.Assignment.RouteIDs), schedule, corridor,
+	}, nil
+}
+
+// textArray encodes s for a NOT NULL TEXT[] column, storing nil as '{}'.
+func textArray(s []string) any {
+	if s == nil {
+		s = []string{}
+	}
+	return pq.Array(s)
+}
+
+func requireAffected(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
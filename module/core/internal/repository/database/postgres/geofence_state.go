@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
+)
+
+var _ database.GeofenceStateRepository = (*GeofenceStateRepo)(nil)
+
+const geofenceStateColumns = `vehicle_id, geofence_id, inside, entered_at, dwell_alerted, pending_fixes, pending_since, updated_at`
+
+type GeofenceStateRepo struct {
+	db *sql.DB
+}
+
+func NewGeofenceStateRepo(db *sql.DB) *GeofenceStateRepo {
+	return &GeofenceStateRepo{db: db}
+}
+
+func (r *GeofenceStateRepo) GetByVehicle(ctx context.Context, vehicleID string) ([]domain.GeofenceState, error) {
+	return r.query(ctx,
+		`SELECT `+geofenceStateColumns+` FROM geofence_states WHERE vehicle_id = $1`,
+		vehicleID,
+	)
+}
+
+// ListInside returns the states of every vehicle currently inside the
+// geofence, earliest entry first.
+func (r *GeofenceStateRepo) ListInside(ctx context.Context, geofenceID string) ([]domain.GeofenceState, error) {
+	return r.query(ctx,
+		`SELECT `+geofenceStateColumns+` FROM geofence_states WHERE geofence_id = $1 AND inside ORDER BY entered_at, vehicle_id`,
+		geofenceID,
+	)
+}
+
+func (r *GeofenceStateRepo) Upsert(ctx context.Context, state *domain.GeofenceState) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO geofence_states (vehicle_id, geofence_id, inside, entered_at, dwell_alerted, pending_fixes, pending_since, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (vehicle_id, geofence_id) DO UPDATE SET inside = EXCLUDED.inside, entered_at = EXCLUDED.entered_at, dwell_alerted = EXCLUDED.dwell_alerted,
+		pending_fixes = EXCLUDED.pending_fixes, pending_since = EXCLUDED.pending_since, updated_at = EXCLUDED.updated_at`,
+		state.VehicleID, state.GeofenceID, state.Inside, nullTime(state.EnteredAt), state.DwellAlerted, state.PendingFixes, nullTime(state.PendingSince), state.UpdatedAt,
+	)
+	return err
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func (r *GeofenceStateRepo) query(ctx context.Context, query string, args ...any) ([]domain.GeofenceState, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []domain.GeofenceState
+	for rows.Next() {
+		var (
+			st                   domain.GeofenceState
+			enteredAt, pendingAt sql.NullTime
+		)
+		if err := rows.Scan(&st.VehicleID, &st.GeofenceID, &st.Inside, &enteredAt, &st.DwellAlerted, &st.PendingFixes, &pendingAt, &st.UpdatedAt); err != nil {
+			return nil, err
+		}
+		st.EnteredAt = enteredAt.Time
+		st.PendingSince = pendingAt.Time
+		results = append(results, st)
+	}
+	return results, rows.Err()
+}
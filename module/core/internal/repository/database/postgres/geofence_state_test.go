@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+func TestGeofenceStateGetByVehicle_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	entered := time.Unix(1715003456, 0)
+	updated := time.Unix(1715003500, 0)
+	rows := sqlmock.NewRows([]string{"vehicle_id", "geofence_id", "inside", "entered_at", "dwell_alerted", "pending_fixes", "pending_since", "updated_at"}).
+		AddRow("B1234XYZ", "a", true, entered, true, 0, nil, updated).
+		AddRow("B1234XYZ", "b", false, nil, false, 2, updated, updated)
+
+	mock.ExpectQuery(`SELECT vehicle_id, geofence_id, inside, entered_at, dwell_alerted, pending_fixes, pending_since, updated_at FROM geofence_states WHERE vehicle_id = (.+)`).
+		WithArgs("B1234XYZ").
+		WillReturnRows(rows)
+
+	repo := NewGeofenceStateRepo(db)
+	results, err := repo.GetByVehicle(context.Background(), "B1234XYZ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 states, got %d", len(results))
+	}
+	if results[0].GeofenceID != "a" || !results[0].Inside || !results[0].DwellAlerted {
+		t.Errorf("unexpected state: %+v", results[0])
+	}
+	if !results[0].PendingSince.IsZero() {
+		t.Errorf("expected NULL pending_since to scan as zero time, got %v", results[0].PendingSince)
+	}
+	if results[1].PendingFixes != 2 || !results[1].PendingSince.Equal(updated) || !results[1].EnteredAt.IsZero() {
+		t.Errorf("unexpected pending state: %+v", results[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeofenceStateGetByVehicle_QueryError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`SELECT vehicle_id, geofence_id, inside, entered_at, dwell_alerted, pending_fixes, pending_since, updated_at FROM geofence_states`).
+		WithArgs("B1234XYZ").
+		WillReturnError(sqlmock.ErrCancelled)
+
+	repo := NewGeofenceStateRepo(db)
+	_, err = repo.GetByVehicle(context.Background(), "B1234XYZ")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestGeofenceStateUpsert_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	entered := time.Unix(1715003456, 0)
+	mock.ExpectExec(`INSERT INTO geofence_states (.+) ON CONFLICT \(vehicle_id, geofence_id\) DO UPDATE`).
+		WithArgs("B1234XYZ", "a", true, entered, false, 0, nil, entered).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := NewGeofenceStateRepo(db)
+	err = repo.Upsert(context.Background(), &domain.GeofenceState{
+		VehicleID:  "B1234XYZ",
+		GeofenceID: "a",
+		Inside:     true,
+		EnteredAt:  entered,
+		UpdatedAt:  entered,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeofenceStateListInside_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	entered := time.Unix(1715003456, 0)
+	rows := sqlmock.NewRows([]string{"vehicle_id", "geofence_id", "inside", "entered_at", "dwell_alerted", "pending_fixes", "pending_since", "updated_at"}).
+		AddRow("B1234XYZ", "terminal", true, entered, false, 0, nil, entered).
+		AddRow("B5678ABC", "terminal", true, entered.Add(time.Minute), false, 1, entered.Add(2*time.Minute), entered.Add(2*time.Minute))
+
+	mock.ExpectQuery(`SELECT (.+) FROM geofence_states WHERE geofence_id = (.+) AND inside ORDER BY entered_at, vehicle_id`).
+		WithArgs("terminal").
+		WillReturnRows(rows)
+
+	repo := NewGeofenceStateRepo(db)
+	results, err := repo.ListInside(context.Background(), "terminal")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[1].VehicleID != "B5678ABC" || !results[1].EnteredAt.Equal(entered.Add(time.Minute)) {
+		t.Errorf("unexpected states: %+v", results)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
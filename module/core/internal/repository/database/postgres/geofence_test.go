@@ -0,0 +1,269 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+var geofenceRowColumns = []string{"id", "name", "tags", "shape", "latitude", "longitude", "radius", "polygons", "dwell_seconds", "vehicle_ids", "group_ids", "route_ids", "schedule", "corridor"}
+
+func TestGeofenceList_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	rows := sqlmock.NewRows(geofenceRowColumns).
+		AddRow("jakarta-center", "Jakarta Center", "{terminal,central}", "circle", -6.2088, 106.8456, 50.0, nil, 600, "{}", "{}", "{}", nil, nil).
+		AddRow("depot", "Depot", "{}", "polygon", nil, nil, nil, []byte(`[[[{"latitude":-6.21,"longitude":106.84},{"latitude":-6.21,"longitude":106.85},{"latitude":-6.2,"longitude":106.85}]]]`), 0, "{B1234XYZ}", "{}", "{corridor-1}",
+			[]byte(`{"timezone":"Asia/Jakarta","windows":[{"days":[1,2,3,4,5],"start":21600000000000,"end":28800000000000}]}`), nil).
+		AddRow("koridor-1", "Koridor 1", "{}", "corridor", nil, nil, nil, nil, 0, "{}", "{}", "{corridor-1}", nil,
+			[]byte(`{"line":[{"latitude":-6.2,"longitude":106.83},{"latitude":-6.2,"longitude":106.85}],"buffer":30}`))
+
+	mock.ExpectQuery(`SELECT id, name, tags, shape, latitude, longitude, radius, polygons, dwell_seconds, vehicle_ids, group_ids, route_ids, schedule, corridor FROM geofences ORDER BY id`).
+		WillReturnRows(rows)
+
+	repo := NewGeofenceRepo(db)
+	results, err := repo.List(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 geofences, got %d", len(results))
+	}
+	if results[0].Name != "Jakarta Center" || len(results[0].Tags) != 2 || results[0].Tags[1] != "central" {
+		t.Errorf("unexpected name/tags: %q %v", results[0].Name, results[0].Tags)
+	}
+	if results[0].DwellThreshold != 10*time.Minute {
+		t.Errorf("expected 10m dwell threshold, got %v", results[0].DwellThreshold)
+	}
+	if results[0].Circle == nil || results[0].Circle.Radius != 50 {
+		t.Errorf("expected circle with radius 50, got %+v", results[0].Circle)
+	}
+	if results[1].Circle != nil {
+		t.Errorf("expected polygon geofence to have no circle, got %+v", results[1].Circle)
+	}
+	if len(results[1].Polygons) != 1 || len(results[1].Polygons[0][0]) != 3 {
+		t.Errorf("unexpected polygons: %+v", results[1].Polygons)
+	}
+	if results[0].Schedule != nil {
+		t.Errorf("expected no schedule, got %+v", results[0].Schedule)
+	}
+	if sched := results[1].Schedule; sched == nil || sched.Timezone != "Asia/Jakarta" || len(sched.Windows) != 1 || sched.Windows[0].Start != 6*time.Hour {
+		t.Errorf("unexpected schedule: %+v", sched)
+	}
+	if c := results[2].Corridor; c == nil || len(c.Line) != 2 || c.Buffer != 30 || results[1].Corridor != nil {
+		t.Errorf("unexpected corridor: %+v", c)
+	}
+	if !results[0].Assignment.IsGlobal() {
+		t.Errorf("expected global assignment, got %+v", results[0].Assignment)
+	}
+	if a := results[1].Assignment; len(a.VehicleIDs) != 1 || a.VehicleIDs[0] != "B1234XYZ" || len(a.RouteIDs) != 1 || a.RouteIDs[0] != "corridor-1" {
+		t.Errorf("unexpected assignment: %+v", a)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeofenceGet_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`SELECT id, name, tags, shape, latitude, longitude, radius, polygons, dwell_seconds, vehicle_ids, group_ids, route_ids, schedule, corridor FROM geofences WHERE id = (.+)`).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+
+	repo := NewGeofenceRepo(db)
+	_, err = repo.Get(context.Background(), "missing")
+	if !errors.Is(err, domain.ErrGeofenceNotFound) {
+		t.Fatalf("expected ErrGeofenceNotFound, got %v", err)
+	}
+}
+
+func TestGeofenceCreate_GeneratesID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`INSERT INTO geofences \(name, tags, shape, latitude, longitude, radius, polygons, dwell_seconds, vehicle_ids, group_ids, route_ids, schedule, corridor\) (.+) RETURNING id`).
+		WithArgs("Jakarta Center", sqlmock.AnyArg(), "circle", -6.2088, 106.8456, 50.0, sqlmock.AnyArg(), int64(0), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), []byte(nil)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("generated-id"))
+
+	repo := NewGeofenceRepo(db)
+	gf := &domain.Geofence{
+		Name:   "Jakarta Center",
+		Shape:  domain.ShapeCircle,
+		Circle: &domain.GeoPoint{Lat: -6.2088, Lon: 106.8456, Radius: 50},
+	}
+	if err := repo.Create(context.Background(), gf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gf.ID != "generated-id" {
+		t.Errorf("expected generated-id, got %s", gf.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeofenceCreate_WithID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(`INSERT INTO geofences \(id, name, tags, shape, latitude, longitude, radius, polygons, dwell_seconds, vehicle_ids, group_ids, route_ids, schedule, corridor\) .* ON CONFLICT \(id\) DO NOTHING`).
+		WithArgs("depot", "", sqlmock.AnyArg(), "polygon", nil, nil, nil, sqlmock.AnyArg(), int64(900), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), []byte(nil)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := NewGeofenceRepo(db)
+	gf := &domain.Geofence{
+		ID:             "depot",
+		Shape:          domain.ShapePolygon,
+		DwellThreshold: 15 * time.Minute,
+		Polygons: []domain.Polygon{{{
+			{Lat: -6.21, Lon: 106.84},
+			{Lat: -6.21, Lon: 106.85},
+			{Lat: -6.20, Lon: 106.85},
+		}}},
+	}
+	if err := repo.Create(context.Background(), gf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeofenceCreate_DuplicateID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(`INSERT INTO geofences \(id, name, tags, shape, latitude, longitude, radius, polygons, dwell_seconds, vehicle_ids, group_ids, route_ids, schedule, corridor\) .* ON CONFLICT \(id\) DO NOTHING`).
+		WithArgs("depot", "", sqlmock.AnyArg(), "polygon", nil, nil, nil, sqlmock.AnyArg(), int64(900), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), []byte(nil)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewGeofenceRepo(db)
+	gf := &domain.Geofence{
+		ID:             "depot",
+		Shape:          domain.ShapePolygon,
+		DwellThreshold: 15 * time.Minute,
+		Polygons: []domain.Polygon{{{
+			{Lat: -6.21, Lon: 106.84},
+			{Lat: -6.21, Lon: 106.85},
+			{Lat: -6.20, Lon: 106.85},
+		}}},
+	}
+	if err := repo.Create(context.Background(), gf); !errors.Is(err, domain.ErrGeofenceExists) {
+		t.Fatalf("expected ErrGeofenceExists, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeofenceCreate_Corridor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	corridor := []byte(`{"line":[{"latitude":-6.2,"longitude":106.83},{"latitude":-6.2,"longitude":106.85}],"buffer":30}`)
+	mock.ExpectExec(`INSERT INTO geofences \(id, (.+), corridor\)`).
+		WithArgs("koridor-1", "", sqlmock.AnyArg(), "corridor", nil, nil, nil, []byte(nil), int64(0), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), []byte(nil), corridor).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := NewGeofenceRepo(db)
+	gf := &domain.Geofence{
+		ID:    "koridor-1",
+		Shape: domain.ShapeCorridor,
+		Corridor: &domain.Corridor{
+			Line:   []domain.Coordinate{{Lat: -6.2, Lon: 106.83}, {Lat: -6.2, Lon: 106.85}},
+			Buffer: 30,
+		},
+	}
+	if err := repo.Create(context.Background(), gf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeofenceUpdate_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(`UPDATE geofences SET`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewGeofenceRepo(db)
+	err = repo.Update(context.Background(), &domain.Geofence{
+		ID:     "missing",
+		Shape:  domain.ShapeCircle,
+		Circle: &domain.GeoPoint{Lat: -6.2088, Lon: 106.8456, Radius: 50},
+	})
+	if !errors.Is(err, domain.ErrGeofenceNotFound) {
+		t.Fatalf("expected ErrGeofenceNotFound, got %v", err)
+	}
+}
+
+func TestGeofenceDelete_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(`DELETE FROM geofences WHERE id = (.+)`).
+		WithArgs("depot").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := NewGeofenceRepo(db)
+	if err := repo.Delete(context.Background(), "depot"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeofenceDelete_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(`DELETE FROM geofences WHERE id = (.+)`).
+		WithArgs("missing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewGeofenceRepo(db)
+	err = repo.Delete(context.Background(), "missing")
+	if !errors.Is(err, domain.ErrGeofenceNotFound) {
+		t.Fatalf("expected ErrGeofenceNotFound, got %v", err)
+	}
+}
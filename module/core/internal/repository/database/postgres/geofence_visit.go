@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
+)
+
+var _ database.GeofenceVisitRepository = (*GeofenceVisitRepo)(nil)
+
+type GeofenceVisitRepo struct {
+	db *sql.DB
+}
+
+func NewGeofenceVisitRepo(db *sql.DB) *GeofenceVisitRepo {
+	return &GeofenceVisitRepo{db: db}
+}
+
+// Insert stores the visit. A visit already stored for the same vehicle,
+// geofence and entry time is kept, so an exit that is evaluated again after a
+// failed state write does not record the visit twice.
+func (r *GeofenceVisitRepo) Insert(ctx context.Context, visit *domain.GeofenceVisit) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO geofence_visits (vehicle_id, geofence_id, entered_at, exited_at, duration_seconds) VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (vehicle_id, entered_at, geofence_id) DO NOTHING`,
+		visit.VehicleID, visit.GeofenceID, visit.EnteredAt, visit.ExitedAt, int64(visit.Duration/time.Second),
+	)
+	return err
+}
+
+// List returns the visits matching the query, earliest entry first.
+func (r *GeofenceVisitRepo) List(ctx context.Context, query *domain.VisitQuery) ([]domain.GeofenceVisit, error) {
+	conds := []string{"entered_at >= $1", "entered_at <= $2"}
+	args := []any{query.Start, query.End}
+	if query.VehicleID != "" {
+		args = append(args, query.VehicleID)
+		conds = append(conds, fmt.Sprintf("vehicle_id = $%d", len(args)))
+	}
+	if query.GeofenceID != "" {
+		args = append(args, query.GeofenceID)
+		conds = append(conds, fmt.Sprintf("geofence_id = $%d", len(args)))
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT vehicle_id, geofence_id, entered_at, exited_at, duration_seconds FROM geofence_visits WHERE `+
+			strings.Join(conds, " AND ")+` ORDER BY entered_at, vehicle_id, geofence_id`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	results := []domain.GeofenceVisit{}
+	for rows.Next() {
+		var (
+			v       domain.GeofenceVisit
+			seconds int64
+		)
+		if err := rows.Scan(&v.VehicleID, &v.GeofenceID, &v.EnteredAt, &v.ExitedAt, &seconds); err != nil {
+			return nil, err
+		}
+		v.Duration = time.Duration(seconds) * time.Second
+		results = append(results, v)
+	}
+	return results, rows.Err()
+}
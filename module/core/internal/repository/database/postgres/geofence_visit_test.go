@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+func TestGeofenceVisitInsert_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	entered := time.Unix(1715003456, 0)
+	exited := entered.Add(90 * time.Second)
+
+	mock.ExpectExec(`INSERT INTO geofence_visits .* ON CONFLICT \(vehicle_id, entered_at, geofence_id\) DO NOTHING`).
+		WithArgs("B1234XYZ", "terminal", entered, exited, int64(90)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	repo := NewGeofenceVisitRepo(db)
+	err = repo.Insert(context.Background(), &domain.GeofenceVisit{
+		VehicleID:  "B1234XYZ",
+		GeofenceID: "terminal",
+		EnteredAt:  entered,
+		ExitedAt:   exited,
+		Duration:   90 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeofenceVisitList_ByGeofence(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	start := time.Unix(1715000000, 0)
+	end := time.Unix(1715086400, 0)
+	entered := time.Unix(1715003456, 0)
+
+	rows := sqlmock.NewRows([]string{"vehicle_id", "geofence_id", "entered_at", "exited_at", "duration_seconds"}).
+		AddRow("B1234XYZ", "terminal", entered, entered.Add(time.Hour), int64(3600))
+	mock.ExpectQuery(`SELECT (.+) FROM geofence_visits WHERE entered_at >= \$1 AND entered_at <= \$2 AND geofence_id = \$3 ORDER BY`).
+		WithArgs(start, end, "terminal").
+		WillReturnRows(rows)
+
+	repo := NewGeofenceVisitRepo(db)
+	visits, err := repo.List(context.Background(), &domain.VisitQuery{GeofenceID: "terminal", Start: start, End: end})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(visits) != 1 {
+		t.Fatalf("expected 1 visit, got %d", len(visits))
+	}
+	if visits[0].VehicleID != "B1234XYZ" || visits[0].Duration != time.Hour || !visits[0].ExitedAt.Equal(entered.Add(time.Hour)) {
+		t.Errorf("unexpected visit: %+v", visits[0])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeofenceVisitList_ByVehicleEmpty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	start := time.Unix(1715000000, 0)
+	end := time.Unix(1715086400, 0)
+
+	mock.ExpectQuery(`SELECT (.+) FROM geofence_visits WHERE entered_at >= \$1 AND entered_at <= \$2 AND vehicle_id = \$3 ORDER BY`).
+		WithArgs(start, end, "B1234XYZ").
+		WillReturnRows(sqlmock.NewRows([]string{"vehicle_id", "geofence_id", "entered_at", "exited_at", "duration_seconds"}))
+
+	repo := NewGeofenceVisitRepo(db)
+	visits, err := repo.List(context.Background(), &domain.VisitQuery{VehicleID: "B1234XYZ", Start: start, End: end})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if visits == nil || len(visits) != 0 {
+		t.Errorf("expected empty non-nil slice, got %#v", visits)
+	}
+}
+
+func TestGeofenceVisitList_QueryError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`SELECT (.+) FROM geofence_visits`).WillReturnError(errors.New("db down"))
+
+	repo := NewGeofenceVisitRepo(db)
+	if _, err := repo.List(context.Background(), &domain.VisitQuery{GeofenceID: "terminal"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
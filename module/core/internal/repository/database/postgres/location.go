@@ -3,6 +3,11 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
 
 	"github.com/nandanugg/tj-test/module/core/domain"
 	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
@@ -10,6 +15,14 @@ import (
 
 var _ database.LocationRepository = (*LocationRepo)(nil)
 
+const (
+	locationColumns = `vehicle_id, latitude, longitude, timestamp, speed, heading, altitude, accuracy, satellites, ignition, odometer`
+	locationParams  = 11
+	// maxBatchRows keeps a multi-row insert under Postgres' limit of 65535
+	// bind parameters.
+	maxBatchRows = 65535 / locationParams
+)
+
 type LocationRepo struct {
 	db *sql.DB
 }
@@ -18,30 +31,93 @@ func NewLocationRepo(db *sql.DB) *LocationRepo {
 	return &LocationRepo{db: db}
 }
 
+// Insert stores the location, returning domain.ErrDuplicateLocation if the
+// vehicle already has one with the same timestamp and
+// domain.ErrLocationRejected if the database refuses its data.
 func (r *LocationRepo) Insert(ctx context.Context, loc *domain.VehicleLocation) error {
-	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO vehicle_locations (vehicle_id, latitude, longitude, timestamp) VALUES ($1, $2, $3, $4)`,
-		loc.VehicleID, loc.Location.Lat, loc.Location.Lon, loc.Location.Timestamp,
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO vehicle_locations (`+locationColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) ON CONFLICT (vehicle_id, timestamp) DO NOTHING`,
+		locationArgs(loc)...,
 	)
+	if err != nil {
+		return rejectedLocation(err)
+	}
+	return requireAffected(res, domain.ErrDuplicateLocation)
+}
+
+// InsertBatch writes the locations with multi-row inserts in a single
+// transaction, so either all of them are stored or none are. Locations whose
+// vehicle and timestamp are already stored are skipped. If the database
+// refuses the data of any location, domain.ErrLocationRejected is returned.
+func (r *LocationRepo) InsertBatch(ctx context.Context, locs []domain.VehicleLocation) error {
+	if len(locs) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for start := 0; start < len(locs); start += maxBatchRows {
+		chunk := locs[start:min(start+maxBatchRows, len(locs))]
+
+		var sb strings.Builder
+		sb.WriteString(`INSERT INTO vehicle_locations (` + locationColumns + `) VALUES `)
+		args := make([]any, 0, len(chunk)*locationParams)
+		for i := range chunk {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteByte('(')
+			for j := range locationParams {
+				if j > 0 {
+					sb.WriteString(", ")
+				}
+				fmt.Fprintf(&sb, "$%d", i*locationParams+j+1)
+			}
+			sb.WriteByte(')')
+			args = append(args, locationArgs(&chunk[i])...)
+		}
+		sb.WriteString(` ON CONFLICT (vehicle_id, timestamp) DO NOTHING`)
+
+		if _, err := tx.ExecContext(ctx, sb.String(), args...); err != nil {
+			return rejectedLocation(err)
+		}
+	}
+	return rejectedLocation(tx.Commit())
+}
+
+// rejectedLocation wraps data exceptions and integrity constraint violations,
+// SQLSTATE classes 22 and 23, in domain.ErrLocationRejected. Other errors,
+// such as a lost connection, are returned as they are.
+func rejectedLocation(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "22", "23":
+			return fmt.Errorf("%w: %w", domain.ErrLocationRejected, err)
+		}
+	}
 	return err
 }
 
 func (r *LocationRepo) GetLatest(ctx context.Context, vehicleID string) (*domain.VehicleLocation, error) {
 	row := r.db.QueryRowContext(ctx,
-		`SELECT vehicle_id, latitude, longitude, timestamp FROM vehicle_locations WHERE vehicle_id = $1 ORDER BY timestamp DESC LIMIT 1`,
+		`SELECT `+locationColumns+` FROM vehicle_locations WHERE vehicle_id = $1 ORDER BY timestamp DESC LIMIT 1`,
 		vehicleID,
 	)
-
-	var vl domain.VehicleLocation
-	if err := row.Scan(&vl.VehicleID, &vl.Location.Lat, &vl.Location.Lon, &vl.Location.Timestamp); err != nil {
-		return nil, err
+	vl, err := scanLocation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrVehicleNotFound
 	}
-	return &vl, nil
+	return vl, err
 }
 
 func (r *LocationRepo) GetHistory(ctx context.Context, query *domain.HistoryQuery) ([]domain.VehicleLocation, error) {
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT vehicle_id, latitude, longitude, timestamp FROM vehicle_locations WHERE vehicle_id = $1 AND timestamp >= $2 AND timestamp <= $3 ORDER BY timestamp ASC`,
+		`SELECT `+locationColumns+` FROM vehicle_locations WHERE vehicle_id = $1 AND timestamp >= $2 AND timestamp <= $3 ORDER BY timestamp ASC`,
 		query.VehicleID, query.Start, query.End,
 	)
 	if err != nil {
@@ -51,11 +127,11 @@ func (r *LocationRepo) GetHistory(ctx context.Context, query *domain.HistoryQuer
 
 	var results []domain.VehicleLocation
 	for rows.Next() {
-		var vl domain.VehicleLocation
-		if err := rows.Scan(&vl.VehicleID, &vl.Location.Lat, &vl.Location.Lon, &vl.Location.Timestamp); err != nil {
+		vl, err := scanLocation(rows)
+		if err != nil {
 			return nil, err
 		}
-		results = append(results, vl)
+		results = append(results, *vl)
 	}
 	return results, rows.Err()
 }
@@ -79,3 +155,26 @@ func (r *LocationRepo) GetAllVehicles(ctx context.Context) ([]domain.Vehicle, er
 	}
 	return results, rows.Err()
 }
+
+// locationArgs returns the values for locationColumns. Unreported telemetry
+// is a nil pointer, which is stored as NULL.
+func locationArgs(loc *domain.VehicleLocation) []any {
+	t := &loc.Telemetry
+	return []any{
+		loc.VehicleID, loc.Location.Lat, loc.Location.Lon, loc.Location.Timestamp,
+		t.Speed, t.Heading, t.Altitude, t.Accuracy, t.Satellites, t.Ignition, t.Odometer,
+	}
+}
+
+func scanLocation(s scanner) (*domain.VehicleLocation, error) {
+	var vl domain.VehicleLocation
+	t := &vl.Telemetry
+	err := s.Scan(
+		&vl.VehicleID, &vl.Location.Lat, &vl.Location.Lon, &vl.Location.Timestamp,
+		&t.Speed, &t.Heading, &t.Altitude, &t.Accuracy, &t.Satellites, &t.Ignition, &t.Odometer,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &vl, nil
+}
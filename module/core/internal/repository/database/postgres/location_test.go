@@ -2,10 +2,12 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 
 	"github.com/nandanugg/tj-test/module/core/domain"
 )
@@ -19,13 +21,15 @@ func TestInsert_Success(t *testing.T) {
 
 	ts := time.Unix(1715003456, 0)
 	mock.ExpectExec(`INSERT INTO vehicle_locations`).
-		WithArgs("B1234XYZ", -6.2088, 106.8456, ts).
+		WithArgs("B1234XYZ", -6.2088, 106.8456, ts, 42.5, 90.0, nil, 3.5, 9, true, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
+	speed, heading, accuracy, satellites, ignition := 42.5, 90.0, 3.5, 9, true
 	repo := NewLocationRepo(db)
 	err = repo.Insert(context.Background(), &domain.VehicleLocation{
 		VehicleID: "B1234XYZ",
 		Location:  domain.Location{Lat: -6.2088, Lon: 106.8456, Timestamp: ts},
+		Telemetry: domain.Telemetry{Speed: &speed, Heading: &heading, Accuracy: &accuracy, Satellites: &satellites, Ignition: &ignition},
 	})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -35,6 +39,28 @@ func TestInsert_Success(t *testing.T) {
 	}
 }
 
+func TestInsert_Duplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ts := time.Unix(1715003456, 0)
+	mock.ExpectExec(`INSERT INTO vehicle_locations (.+) ON CONFLICT \(vehicle_id, timestamp\) DO NOTHING`).
+		WithArgs("B1234XYZ", -6.2088, 106.8456, ts, nil, nil, nil, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewLocationRepo(db)
+	err = repo.Insert(context.Background(), &domain.VehicleLocation{
+		VehicleID: "B1234XYZ",
+		Location:  domain.Location{Lat: -6.2088, Lon: 106.8456, Timestamp: ts},
+	})
+	if !errors.Is(err, domain.ErrDuplicateLocation) {
+		t.Fatalf("expected ErrDuplicateLocation, got %v", err)
+	}
+}
+
 func TestInsert_Error(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -44,7 +70,7 @@ func TestInsert_Error(t *testing.T) {
 
 	ts := time.Unix(1715003456, 0)
 	mock.ExpectExec(`INSERT INTO vehicle_locations`).
-		WithArgs("B1234XYZ", -6.2088, 106.8456, ts).
+		WithArgs("B1234XYZ", -6.2088, 106.8456, ts, nil, nil, nil, nil, nil, nil, nil).
 		WillReturnError(sqlmock.ErrCancelled)
 
 	repo := NewLocationRepo(db)
@@ -57,6 +83,101 @@ func TestInsert_Error(t *testing.T) {
 	}
 }
 
+func TestInsertBatch_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ts := time.Unix(1715003456, 0)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO vehicle_locations \(vehicle_id, (.+), odometer\) VALUES \(\$1, (.+), \$11\), \(\$12, (.+), \$22\) ON CONFLICT \(vehicle_id, timestamp\) DO NOTHING$`).
+		WithArgs("B1234XYZ", -6.2088, 106.8456, ts, nil, nil, nil, nil, nil, nil, nil, "B5678ABC", -6.21, 106.85, ts.Add(time.Second), nil, nil, nil, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := NewLocationRepo(db)
+	err = repo.InsertBatch(context.Background(), []domain.VehicleLocation{
+		{VehicleID: "B1234XYZ", Location: domain.Location{Lat: -6.2088, Lon: 106.8456, Timestamp: ts}},
+		{VehicleID: "B5678ABC", Location: domain.Location{Lat: -6.21, Lon: 106.85, Timestamp: ts.Add(time.Second)}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInsertBatch_ChunksLargeBatches(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	locs := make([]domain.VehicleLocation, maxBatchRows+1)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO vehicle_locations`).WillReturnResult(sqlmock.NewResult(0, int64(maxBatchRows)))
+	mock.ExpectExec(`INSERT INTO vehicle_locations \(.+\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10, \$11\) ON CONFLICT (.+) DO NOTHING$`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewLocationRepo(db)
+	if err := repo.InsertBatch(context.Background(), locs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInsertBatch_RollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO vehicle_locations`).WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewLocationRepo(db)
+	err = repo.InsertBatch(context.Background(), []domain.VehicleLocation{{VehicleID: "B1234XYZ"}})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if errors.Is(err, domain.ErrLocationRejected) {
+		t.Error("expected a connection error not to be reported as rejected")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInsertBatch_Rejected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO vehicle_locations`).WillReturnError(&pq.Error{Code: "22003", Message: "value out of range"})
+	mock.ExpectRollback()
+
+	repo := NewLocationRepo(db)
+	err = repo.InsertBatch(context.Background(), []domain.VehicleLocation{{VehicleID: "B1234XYZ"}})
+	if !errors.Is(err, domain.ErrLocationRejected) {
+		t.Fatalf("expected ErrLocationRejected, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestGetLatest_Success(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -65,10 +186,10 @@ func TestGetLatest_Success(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	ts := time.Unix(1715003456, 0)
-	rows := sqlmock.NewRows([]string{"vehicle_id", "latitude", "longitude", "timestamp"}).
-		AddRow("B1234XYZ", -6.2088, 106.8456, ts)
+	rows := sqlmock.NewRows([]string{"vehicle_id", "latitude", "longitude", "timestamp", "speed", "heading", "altitude", "accuracy", "satellites", "ignition", "odometer"}).
+		AddRow("B1234XYZ", -6.2088, 106.8456, ts, 42.5, 90.0, 12.0, nil, int64(9), true, 10523.4)
 
-	mock.ExpectQuery(`SELECT vehicle_id, latitude, longitude, timestamp FROM vehicle_locations WHERE vehicle_id = (.+) ORDER BY timestamp DESC LIMIT 1`).
+	mock.ExpectQuery(`SELECT vehicle_id, latitude, longitude, timestamp, speed, heading, altitude, accuracy, satellites, ignition, odometer FROM vehicle_locations WHERE vehicle_id = (.+) ORDER BY timestamp DESC LIMIT 1`).
 		WithArgs("B1234XYZ").
 		WillReturnRows(rows)
 
@@ -86,6 +207,13 @@ func TestGetLatest_Success(t *testing.T) {
 	if !vl.Location.Timestamp.Equal(ts) {
 		t.Errorf("expected %v, got %v", ts, vl.Location.Timestamp)
 	}
+	tm := vl.Telemetry
+	if tm.Speed == nil || *tm.Speed != 42.5 || tm.Satellites == nil || *tm.Satellites != 9 || tm.Ignition == nil || !*tm.Ignition {
+		t.Errorf("unexpected telemetry: %+v", tm)
+	}
+	if tm.Accuracy != nil {
+		t.Errorf("expected NULL accuracy to scan as nil, got %v", *tm.Accuracy)
+	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatal(err)
 	}
@@ -98,15 +226,15 @@ func TestGetLatest_NotFound(t *testing.T) {
 	}
 	defer func() { _ = db.Close() }()
 
-	rows := sqlmock.NewRows([]string{"vehicle_id", "latitude", "longitude", "timestamp"})
-	mock.ExpectQuery(`SELECT vehicle_id, latitude, longitude, timestamp FROM vehicle_locations WHERE vehicle_id = (.+)`).
+	rows := sqlmock.NewRows([]string{"vehicle_id", "latitude", "longitude", "timestamp", "speed", "heading", "altitude", "accuracy", "satellites", "ignition", "odometer"})
+	mock.ExpectQuery(`SELECT vehicle_id, latitude, longitude, timestamp, speed, heading, altitude, accuracy, satellites, ignition, odometer FROM vehicle_locations WHERE vehicle_id = (.+)`).
 		WithArgs("UNKNOWN").
 		WillReturnRows(rows)
 
 	repo := NewLocationRepo(db)
 	_, err = repo.GetLatest(context.Background(), "UNKNOWN")
-	if err == nil {
-		t.Fatal("expected error")
+	if !errors.Is(err, domain.ErrVehicleNotFound) {
+		t.Fatalf("expected ErrVehicleNotFound, got %v", err)
 	}
 }
 
@@ -122,11 +250,11 @@ func TestGetHistory_Success(t *testing.T) {
 	start := time.Unix(1715000000, 0)
 	end := time.Unix(1715009999, 0)
 
-	rows := sqlmock.NewRows([]string{"vehicle_id", "latitude", "longitude", "timestamp"}).
-		AddRow("B1234XYZ", -6.2, 106.8, ts1).
-		AddRow("B1234XYZ", -6.3, 106.9, ts2)
+	rows := sqlmock.NewRows([]string{"vehicle_id", "latitude", "longitude", "timestamp", "speed", "heading", "altitude", "accuracy", "satellites", "ignition", "odometer"}).
+		AddRow("B1234XYZ", -6.2, 106.8, ts1, nil, nil, nil, nil, nil, nil, nil).
+		AddRow("B1234XYZ", -6.3, 106.9, ts2, nil, nil, nil, nil, nil, nil, nil)
 
-	mock.ExpectQuery(`SELECT vehicle_id, latitude, longitude, timestamp FROM vehicle_locations WHERE vehicle_id = (.+) AND timestamp >= (.+) AND timestamp <= (.+) ORDER BY timestamp ASC`).
+	mock.ExpectQuery(`SELECT vehicle_id, latitude, longitude, timestamp, speed, heading, altitude, accuracy, satellites, ignition, odometer FROM vehicle_locations WHERE vehicle_id = (.+) AND timestamp >= (.+) AND timestamp <= (.+) ORDER BY timestamp ASC`).
 		WithArgs("B1234XYZ", start, end).
 		WillReturnRows(rows)
 
@@ -148,6 +276,9 @@ func TestGetHistory_Success(t *testing.T) {
 	if results[1].Location.Lat != -6.3 {
 		t.Errorf("expected -6.3, got %f", results[1].Location.Lat)
 	}
+	if results[0].Telemetry != (domain.Telemetry{}) {
+		t.Errorf("expected no telemetry, got %+v", results[0].Telemetry)
+	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatal(err)
 	}
@@ -162,9 +293,9 @@ func TestGetHistory_Empty(t *testing.T) {
 
 	start := time.Unix(1715000000, 0)
 	end := time.Unix(1715009999, 0)
-	rows := sqlmock.NewRows([]string{"vehicle_id", "latitude", "longitude", "timestamp"})
+	rows := sqlmock.NewRows([]string{"vehicle_id", "latitude", "longitude", "timestamp", "speed", "heading", "altitude", "accuracy", "satellites", "ignition", "odometer"})
 
-	mock.ExpectQuery(`SELECT vehicle_id, latitude, longitude, timestamp FROM vehicle_locations`).
+	mock.ExpectQuery(`SELECT vehicle_id, latitude, longitude, timestamp, speed, heading, altitude, accuracy, satellites, ignition, odometer FROM vehicle_locations`).
 		WithArgs("B1234XYZ", start, end).
 		WillReturnRows(rows)
 
@@ -192,7 +323,7 @@ func TestGetHistory_QueryError(t *testing.T) {
 	start := time.Unix(1715000000, 0)
 	end := time.Unix(1715009999, 0)
 
-	mock.ExpectQuery(`SELECT vehicle_id, latitude, longitude, timestamp FROM vehicle_locations`).
+	mock.ExpectQuery(`SELECT vehicle_id, latitude, longitude, timestamp, speed, heading, altitude, accuracy, satellites, ignition, odometer FROM vehicle_locations`).
 		WithArgs("B1234XYZ", start, end).
 		WillReturnError(sqlmock.ErrCancelled)
 
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
+)
+
+var _ database.TrackerRepository = (*TrackerRepo)(nil)
+
+type TrackerRepo struct {
+	db *sql.DB
+}
+
+func NewTrackerRepo(db *sql.DB) *TrackerRepo {
+	return &TrackerRepo{db: db}
+}
+
+func (r *TrackerRepo) List(ctx context.Context) ([]domain.Tracker, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT imei, vehicle_id FROM trackers ORDER BY imei`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	results := []domain.Tracker{}
+	for rows.Next() {
+		var t domain.Tracker
+		if err := rows.Scan(&t.IMEI, &t.VehicleID); err != nil {
+			return nil, err
+		}
+		results = append(results, t)
+	}
+	return results, rows.Err()
+}
+
+func (r *TrackerRepo) Get(ctx context.Context, imei string) (*domain.Tracker, error) {
+	t := domain.Tracker{IMEI: imei}
+	err := r.db.QueryRowContext(ctx, `SELECT vehicle_id FROM trackers WHERE imei = $1`, imei).Scan(&t.VehicleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrTrackerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TrackerRepo) Upsert(ctx context.Context, t *domain.Tracker) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO trackers (imei, vehicle_id, updated_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (imei) DO UPDATE SET vehicle_id = EXCLUDED.vehicle_id, updated_at = EXCLUDED.updated_at`,
+		t.IMEI, t.VehicleID,
+	)
+	return err
+}
+
+func (r *TrackerRepo) Delete(ctx context.Context, imei string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM trackers WHERE imei = $1`, imei)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, domain.ErrTrackerNotFound)
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+func TestTrackerGet_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`SELECT vehicle_id FROM trackers WHERE imei = (.+)`).
+		WithArgs("356307042441013").
+		WillReturnRows(sqlmock.NewRows([]string{"vehicle_id"}).AddRow("B1234XYZ"))
+
+	repo := NewTrackerRepo(db)
+	tr, err := repo.Get(context.Background(), "356307042441013")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tr.IMEI != "356307042441013" || tr.VehicleID != "B1234XYZ" {
+		t.Errorf("unexpected tracker: %+v", tr)
+	}
+}
+
+func TestTrackerGet_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`SELECT vehicle_id FROM trackers WHERE imei = (.+)`).
+		WithArgs("356307042441013").
+		WillReturnError(sql.ErrNoRows)
+
+	repo := NewTrackerRepo(db)
+	if _, err := repo.Get(context.Background(), "356307042441013"); !errors.Is(err, domain.ErrTrackerNotFound) {
+		t.Fatalf("expected ErrTrackerNotFound, got %v", err)
+	}
+}
+
+func TestTrackerUpsert_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(`INSERT INTO trackers (.+) ON CONFLICT \(imei\) DO UPDATE`).
+		WithArgs("356307042441013", "B1234XYZ").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := NewTrackerRepo(db)
+	if err := repo.Upsert(context.Background(), &domain.Tracker{IMEI: "356307042441013", VehicleID: "B1234XYZ"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTrackerDelete_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(`DELETE FROM trackers WHERE imei = (.+)`).
+		WithArgs("356307042441013").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewTrackerRepo(db)
+	if err := repo.Delete(context.Background(), "356307042441013"); !errors.Is(err, domain.ErrTrackerNotFound) {
+		t.Fatalf("expected ErrTrackerNotFound, got %v", err)
+	}
+}
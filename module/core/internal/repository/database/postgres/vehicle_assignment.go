@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
+)
+
+var _ database.VehicleAssignmentRepository = (*VehicleAssignmentRepo)(nil)
+
+type VehicleAssignmentRepo struct {
+	db *sql.DB
+}
+
+func NewVehicleAssignmentRepo(db *sql.DB) *VehicleAssignmentRepo {
+	return &VehicleAssignmentRepo{db: db}
+}
+
+// Get returns the vehicle's assignment, or an empty one if the vehicle has
+// never been assigned to a group or route.
+func (r *VehicleAssignmentRepo) Get(ctx context.Context, vehicleID string) (*domain.VehicleAssignment, error) {
+	va := domain.VehicleAssignment{VehicleID: vehicleID}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT group_ids, route_ids FROM vehicle_assignments WHERE vehicle_id = $1`,
+		vehicleID,
+	).Scan(pq.Array(&va.GroupIDs), pq.Array(&va.RouteIDs))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return &va, nil
+}
+
+func (r *VehicleAssignmentRepo) Upsert(ctx context.Context, va *domain.VehicleAssignment) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO vehicle_assignments (vehicle_id, group_ids, route_ids, updated_at) VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (vehicle_id) DO UPDATE SET group_ids = EXCLUDED.group_ids, route_ids = EXCLUDED.route_ids, updated_at = EXCLUDED.updated_at`,
+		va.VehicleID, textArray(va.GroupIDs), textArray(va.RouteIDs),
+	)
+	return err
+}
+
+func (r *VehicleAssignmentRepo) Delete(ctx context.Context, vehicleID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM vehicle_assignments WHERE vehicle_id = $1`, vehicleID)
+	return err
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+func TestVehicleAssignmentGet_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`SELECT group_ids, route_ids FROM vehicle_assignments WHERE vehicle_id = (.+)`).
+		WithArgs("B1234XYZ").
+		WillReturnRows(sqlmock.NewRows([]string{"group_ids", "route_ids"}).AddRow("{articulated}", "{corridor-1,corridor-9}"))
+
+	repo := NewVehicleAssignmentRepo(db)
+	va, err := repo.Get(context.Background(), "B1234XYZ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if va.VehicleID != "B1234XYZ" || len(va.GroupIDs) != 1 || len(va.RouteIDs) != 2 || va.RouteIDs[1] != "corridor-9" {
+		t.Errorf("unexpected assignment: %+v", va)
+	}
+}
+
+func TestVehicleAssignmentGet_Unassigned(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`SELECT group_ids, route_ids FROM vehicle_assignments WHERE vehicle_id = (.+)`).
+		WithArgs("B9999ZZZ").
+		WillReturnError(sql.ErrNoRows)
+
+	repo := NewVehicleAssignmentRepo(db)
+	va, err := repo.Get(context.Background(), "B9999ZZZ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if va.VehicleID != "B9999ZZZ" || len(va.GroupIDs) != 0 || len(va.RouteIDs) != 0 {
+		t.Errorf("expected empty assignment, got %+v", va)
+	}
+}
+
+func TestVehicleAssignmentUpsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(`INSERT INTO vehicle_assignments (.+) ON CONFLICT \(vehicle_id\) DO UPDATE`).
+		WithArgs("B1234XYZ", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := NewVehicleAssignmentRepo(db)
+	err = repo.Upsert(context.Background(), &domain.VehicleAssignment{VehicleID: "B1234XYZ", RouteIDs: []string{"corridor-1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
+)
+
+var _ database.VisitReportRepository = (*VisitReportRepo)(nil)
+
+type VisitReportRepo struct {
+	db *sql.DB
+}
+
+func NewVisitReportRepo(db *sql.DB) *VisitReportRepo {
+	return &VisitReportRepo{db: db}
+}
+
+// Create stores the report and its visits in one transaction, filling in the
+// report's ID and creation time.
+func (r *VisitReportRepo) Create(ctx context.Context, report *domain.VisitReport) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO visit_reports (geofence_id, range_start, range_end, vehicles_scanned, locations_scanned) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		report.GeofenceID, report.Start, report.End, report.VehiclesScanned, report.LocationsScanned,
+	).Scan(&report.ID, &report.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if len(report.Visits) > 0 {
+		stmt, err := tx.PrepareContext(ctx,
+			`INSERT INTO visit_report_visits (report_id, vehicle_id, entered_at, exited_at, duration_seconds) VALUES ($1, $2, $3, $4, $5)`,
+		)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = stmt.Close() }()
+
+		for _, v := range report.Visits {
+			if _, err := stmt.ExecContext(ctx, report.ID, v.VehicleID, v.EnteredAt, nullTime(v.ExitedAt), int64(v.Duration/time.Second)); err != nil {
+				return fmt.Errorf("insert visit: %w", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *VisitReportRepo) Get(ctx context.Context, id int64) (*domain.VisitReport, error) {
+	report := domain.VisitReport{ID: id}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT geofence_id, range_start, range_end, vehicles_scanned, locations_scanned, created_at FROM visit_reports WHERE id = $1`,
+		id,
+	).Scan(&report.GeofenceID, &report.Start, &report.End, &report.VehiclesScanned, &report.LocationsScanned, &report.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrReportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT vehicle_id, entered_at, exited_at, duration_seconds FROM visit_report_visits WHERE report_id = $1 ORDER BY entered_at, vehicle_id`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	report.Visits = []domain.GeofenceVisit{}
+	for rows.Next() {
+		var (
+			v        = domain.GeofenceVisit{GeofenceID: report.GeofenceID}
+			exitedAt sql.NullTime
+			seconds  int64
+		)
+		if err := rows.Scan(&v.VehicleID, &v.EnteredAt, &exitedAt, &seconds); err != nil {
+			return nil, err
+		}
+		v.ExitedAt = exitedAt.Time
+		v.Duration = time.Duration(seconds) * time.Second
+		report.Visits = append(report.Visits, v)
+	}
+	return &report, rows.Err()
+}
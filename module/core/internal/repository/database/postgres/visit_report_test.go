@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+func TestVisitReportCreate_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	start := time.Unix(1715000000, 0)
+	end := time.Unix(1715086400, 0)
+	entered := time.Unix(1715003456, 0)
+	created := time.Unix(1715090000, 0)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO visit_reports (.+) RETURNING id, created_at`).
+		WithArgs("terminal", start, end, 2, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, created))
+	prep := mock.ExpectPrepare(`INSERT INTO visit_report_visits`)
+	prep.ExpectExec().
+		WithArgs(int64(7), "B1234XYZ", entered, sql.NullTime{Time: entered.Add(time.Hour), Valid: true}, int64(3600)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.ExpectExec().
+		WithArgs(int64(7), "B5678ABC", entered, sql.NullTime{}, int64(60)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewVisitReportRepo(db)
+	report := &domain.VisitReport{
+		GeofenceID:       "terminal",
+		Start:            start,
+		End:              end,
+		VehiclesScanned:  2,
+		LocationsScanned: 10,
+		Visits: []domain.GeofenceVisit{
+			{VehicleID: "B1234XYZ", GeofenceID: "terminal", EnteredAt: entered, ExitedAt: entered.Add(time.Hour), Duration: time.Hour},
+			{VehicleID: "B5678ABC", GeofenceID: "terminal", EnteredAt: entered, Duration: time.Minute},
+		},
+	}
+	if err := repo.Create(context.Background(), report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.ID != 7 || !report.CreatedAt.Equal(created) {
+		t.Errorf("expected id and created_at to be filled, got %+v", report)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVisitReportCreate_RollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO visit_reports`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+	mock.ExpectPrepare(`INSERT INTO visit_report_visits`).
+		ExpectExec().
+		WillReturnError(errors.New("disk full"))
+	mock.ExpectRollback()
+
+	repo := NewVisitReportRepo(db)
+	err = repo.Create(context.Background(), &domain.VisitReport{
+		GeofenceID: "terminal",
+		Visits:     []domain.GeofenceVisit{{VehicleID: "B1234XYZ"}},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVisitReportGet_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`SELECT (.+) FROM visit_reports WHERE id = (.+)`).
+		WithArgs(int64(99)).
+		WillReturnError(sql.ErrNoRows)
+
+	repo := NewVisitReportRepo(db)
+	if _, err := repo.Get(context.Background(), 99); !errors.Is(err, domain.ErrReportNotFound) {
+		t.Fatalf("expected ErrReportNotFound, got %v", err)
+	}
+}
+
+func TestVisitReportGet_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	start := time.Unix(1715000000, 0)
+	entered := time.Unix(1715003456, 0)
+	mock.ExpectQuery(`SELECT geofence_id, range_start, range_end, vehicles_scanned, locations_scanned, created_at FROM visit_reports WHERE id = (.+)`).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"geofence_id", "range_start", "range_end", "vehicles_scanned", "locations_scanned", "created_at"}).
+			AddRow("terminal", start, start.Add(24*time.Hour), 2, 10, start.Add(25*time.Hour)))
+	mock.ExpectQuery(`SELECT vehicle_id, entered_at, exited_at, duration_seconds FROM visit_report_visits WHERE report_id = (.+) ORDER BY entered_at, vehicle_id`).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"vehicle_id", "entered_at", "exited_at", "duration_seconds"}).
+			AddRow("B1234XYZ", entered, entered.Add(time.Hour), 3600).
+			AddRow("B5678ABC", entered, nil, 60))
+
+	repo := NewVisitReportRepo(db)
+	report, err := repo.Get(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.GeofenceID != "terminal" || report.VehiclesScanned != 2 || len(report.Visits) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if v := report.Visits[0]; v.GeofenceID != "terminal" || v.Duration != time.Hour || !v.ExitedAt.Equal(entered.Add(time.Hour)) {
+		t.Errorf("unexpected visit: %+v", v)
+	}
+	if !report.Visits[1].ExitedAt.IsZero() {
+		t.Errorf("expected open visit, got %+v", report.Visits[1])
+	}
+}
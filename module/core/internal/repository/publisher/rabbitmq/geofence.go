@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 
@@ -44,10 +45,19 @@ func NewGeofencePublisher(conn *amqp.Connection) (*GeofencePublisher, error) {
 }
 
 type alertMessage struct {
-	VehicleID string                `json:"vehicle_id"`
-	Event     domain.GeofenceEventType `json:"event"`
-	Location  alertLocation         `json:"location"`
-	Timestamp int64                 `json:"timestamp"`
+	VehicleID    string                   `json:"vehicle_id"`
+	Event        domain.GeofenceEventType `json:"event"`
+	Geofence     alertGeofence            `json:"geofence"`
+	Distance     float64                  `json:"distance"`
+	DwellSeconds int64                    `json:"dwell_seconds,omitempty"`
+	Location     alertLocation            `json:"location"`
+	Timestamp    int64                    `json:"timestamp"`
+}
+
+type alertGeofence struct {
+	ID   string   `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
 }
 
 type alertLocation struct {
@@ -56,17 +66,7 @@ type alertLocation struct {
 }
 
 func (p *GeofencePublisher) PublishAlert(ctx context.Context, alert *domain.GeofenceAlert) error {
-	msg := alertMessage{
-		VehicleID: alert.VehicleID,
-		Event:     alert.Event,
-		Location: alertLocation{
-			Latitude:  alert.Location.Lat,
-			Longitude: alert.Location.Lon,
-		},
-		Timestamp: alert.Timestamp,
-	}
-
-	body, err := json.Marshal(msg)
+	body, err := json.Marshal(toAlertMessage(alert))
 	if err != nil {
 		return fmt.Errorf("marshal alert: %w", err)
 	}
@@ -76,3 +76,27 @@ func (p *GeofencePublisher) PublishAlert(ctx context.Context, alert *domain.Geof
 		Body:        body,
 	})
 }
+
+func toAlertMessage(alert *domain.GeofenceAlert) alertMessage {
+	tags := alert.Geofence.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return alertMessage{
+		VehicleID: alert.VehicleID,
+		Event:     alert.Event,
+		Geofence: alertGeofence{
+			ID:   alert.Geofence.ID,
+			Name: alert.Geofence.Name,
+			Tags: tags,
+		},
+		Distance:     alert.Distance,
+		DwellSeconds: int64(alert.Dwell / time.Second),
+		Location: alertLocation{
+			Latitude:  alert.Location.Lat,
+			Longitude: alert.Location.Lon,
+		},
+		Timestamp: alert.Timestamp,
+	}
+}
@@ -0,0 +1,74 @@
+package rabbitmq
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+func TestToAlertMessage_IncludesGeofence(t *testing.T) {
+	alert := &domain.GeofenceAlert{
+		VehicleID: "B1234XYZ",
+		Event:     domain.GeofenceEntry,
+		Geofence: domain.Geofence{
+			ID:   "jakarta-center",
+			Name: "Jakarta Center",
+			Tags: []string{"terminal"},
+		},
+		Distance:  12.5,
+		Location:  domain.Location{Lat: -6.2088, Lon: 106.8456, Timestamp: time.Unix(1715003456, 0)},
+		Timestamp: 1715003456,
+	}
+
+	body, err := json.Marshal(toAlertMessage(alert))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	gf, ok := got["geofence"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected geofence object, got %s", body)
+	}
+	if gf["id"] != "jakarta-center" || gf["name"] != "Jakarta Center" {
+		t.Errorf("unexpected geofence: %v", gf)
+	}
+	if got["distance"] != 12.5 {
+		t.Errorf("expected distance 12.5, got %v", got["distance"])
+	}
+}
+
+func TestToAlertMessage_NilTagsEncodedAsEmptyArray(t *testing.T) {
+	body, err := json.Marshal(toAlertMessage(&domain.GeofenceAlert{Geofence: domain.Geofence{ID: "a"}}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var got struct {
+		Geofence struct {
+			Tags []string `json:"tags"`
+		} `json:"geofence"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Geofence.Tags == nil {
+		t.Errorf("expected tags to be [], got %s", body)
+	}
+}
+
+func TestToAlertMessage_DwellSeconds(t *testing.T) {
+	msg := toAlertMessage(&domain.GeofenceAlert{
+		Event: domain.GeofenceDwell,
+		Dwell: 12*time.Minute + 30*time.Second,
+	})
+	if msg.DwellSeconds != 750 {
+		t.Errorf("expected 750, got %d", msg.DwellSeconds)
+	}
+}
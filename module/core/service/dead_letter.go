@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
+)
+
+// DeadLetterService keeps inbound messages that were rejected so they can be
+// inspected and reprocessed.
+type DeadLetterService struct {
+	repo database.DeadLetterRepository
+}
+
+func NewDeadLetterService(repo database.DeadLetterRepository) *DeadLetterService {
+	return &DeadLetterService{repo: repo}
+}
+
+func (s *DeadLetterService) RecordDeadLetter(ctx context.Context, dl *domain.DeadLetter) error {
+	return s.repo.Insert(ctx, dl)
+}
+
+func (s *DeadLetterService) ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	return s.repo.List(ctx, limit)
+}
+
+func (s *DeadLetterService) GetDeadLetter(ctx context.Context, id int64) (*domain.DeadLetter, error) {
+	return s.repo.Get(ctx, id)
+}
+
+func (s *DeadLetterService) DeleteDeadLetter(ctx context.Context, id int64) error {
+	return s.repo.Delete(ctx, id)
+}
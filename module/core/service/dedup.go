@@ -0,0 +1,76 @@
+package service
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// recentFixesPerVehicle is how many of a vehicle's latest timestamps are kept
+// to recognise redeliveries. MQTT brokers redeliver unacknowledged QoS 1
+// messages shortly after a reconnect, so a short window is enough. After a
+// restart the window is empty; a redelivery of the vehicle's newest stored
+// fix is then recognised by its timestamp, and the unique index on
+// vehicle_locations keeps an older one from being stored twice, though it is
+// evaluated again if it is within LateTolerance.
+const recentFixesPerVehicle = 32
+
+// recentFixes remembers the most recent timestamps saved per vehicle so a
+// duplicate can be recognised before it is written, which the batched writer
+// could otherwise only discover after SaveLocation has returned.
+type recentFixes struct {
+	mu   sync.Mutex
+	seen map[string]*fixRing
+}
+
+type fixRing struct {
+	ts   [recentFixesPerVehicle]int64
+	n    int
+	next int
+}
+
+func newRecentFixes() *recentFixes {
+	return &recentFixes{seen: make(map[string]*fixRing)}
+}
+
+// add records the vehicle's timestamp and reports false if it was already
+// recorded.
+func (r *recentFixes) add(vehicleID string, ts time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ring, ok := r.seen[vehicleID]
+	if !ok {
+		ring = &fixRing{}
+		r.seen[vehicleID] = ring
+	}
+	key := ts.UnixNano()
+	for i := range ring.n {
+		if ring.ts[i] == key {
+			return false
+		}
+	}
+
+	ring.ts[ring.next] = key
+	ring.next = (ring.next + 1) % recentFixesPerVehicle
+	ring.n = min(ring.n+1, recentFixesPerVehicle)
+	return true
+}
+
+// remove forgets a timestamp whose save failed, so a redelivery of it is
+// not mistaken for a duplicate.
+func (r *recentFixes) remove(vehicleID string, ts time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ring, ok := r.seen[vehicleID]
+	if !ok {
+		return
+	}
+	key := ts.UnixNano()
+	for i := range ring.n {
+		if ring.ts[i] == key {
+			ring.ts[i] = math.MinInt64
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecentFixes_ForgetsOldest(t *testing.T) {
+	r := newRecentFixes()
+	base := time.Unix(1715000000, 0)
+	for i := range recentFixesPerVehicle + 1 {
+		if !r.add("B1234XYZ", base.Add(time.Duration(i)*time.Second)) {
+			t.Fatalf("fix %d reported as duplicate", i)
+		}
+	}
+
+	if r.add("B1234XYZ", base.Add(recentFixesPerVehicle*time.Second)) {
+		t.Error("expected the latest fix to still be remembered")
+	}
+	if !r.add("B1234XYZ", base) {
+		t.Error("expected the oldest fix to have been forgotten")
+	}
+}
+
+func TestRecentFixes_Remove(t *testing.T) {
+	r := newRecentFixes()
+	ts := time.Unix(1715000000, 0)
+	r.add("B1234XYZ", ts)
+	r.remove("B1234XYZ", ts)
+	r.remove("UNKNOWN", ts)
+
+	if !r.add("B1234XYZ", ts) {
+		t.Error("expected a removed fix to be accepted again")
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+// GeofenceOptions controls how location fixes near a geofence boundary are
+// turned into confirmed transitions.
+type GeofenceOptions struct {
+	// EnterBuffer is how many meters inside the boundary a fix must be to count
+	// as inside.
+	EnterBuffer float64
+	// ExitBuffer is how many meters outside the boundary a fix must be to count
+	// as outside. Fixes between the two buffers keep the current state.
+	ExitBuffer float64
+	// MinFixes is the number of consecutive fixes on the new side required to
+	// confirm a transition. Values below 1 are treated as 1.
+	MinFixes int
+	// MinDuration is how long the first of those fixes must precede the
+	// confirming one.
+	MinDuration time.Duration
+}
+
+// evaluate advances a vehicle's state for one geofence given a new location.
+// Schedules are checked against the location's own timestamp, so delayed and
+// replayed fixes are judged by when they were recorded.
+// It returns nil when the state is unchanged, otherwise the new state and the
+// event it triggers (empty when the change is silent, e.g. a transition that
+// is still pending). prev is nil for a vehicle never seen near the geofence.
+func evaluate(gf *domain.Geofence, prev *domain.GeofenceState, vl *domain.VehicleLocation, opts GeofenceOptions) (*domain.GeofenceState, domain.GeofenceEventType) {
+	ts := vl.Location.Timestamp
+	wasInside := prev != nil && prev.Inside
+
+	observed := wasInside
+	if !scheduleActive(gf.Schedule, ts) {
+		// an inactive geofence is treated as if the vehicle were outside it
+		observed = false
+	} else {
+		d := boundaryDistance(gf, vl.Location.Lat, vl.Location.Lon)
+		switch {
+		case d <= -opts.EnterBuffer:
+			observed = true
+		case d > opts.ExitBuffer:
+			observed = false
+		}
+	}
+
+	if observed != wasInside {
+		next := &domain.GeofenceState{VehicleID: vl.VehicleID, GeofenceID: gf.ID}
+		if prev != nil {
+			*next = *prev
+		}
+		if next.PendingFixes == 0 {
+			next.PendingSince = ts
+		}
+		next.PendingFixes++
+		next.UpdatedAt = ts
+
+		if next.PendingFixes < max(opts.MinFixes, 1) || ts.Sub(next.PendingSince) < opts.MinDuration {
+			return next, ""
+		}
+
+		since := next.PendingSince
+		next.PendingFixes = 0
+		next.PendingSince = time.Time{}
+		next.DwellAlerted = false
+		next.Inside = observed
+		if observed {
+			next.EnteredAt = since
+			return next, domain.GeofenceEntry
+		}
+		return next, domain.GeofenceExit
+	}
+
+	var next *domain.GeofenceState
+	if prev != nil && prev.PendingFixes > 0 {
+		// the vehicle came back before the transition was confirmed
+		reset := *prev
+		reset.PendingFixes = 0
+		reset.PendingSince = time.Time{}
+		reset.UpdatedAt = ts
+		next = &reset
+	}
+
+	if wasInside && gf.DwellThreshold > 0 && !prev.DwellAlerted && ts.Sub(prev.EnteredAt) >= gf.DwellThreshold {
+		if next == nil {
+			copied := *prev
+			next = &copied
+		}
+		next.DwellAlerted = true
+		next.UpdatedAt = ts
+		return next, domain.GeofenceDwell
+	}
+	return next, ""
+}
+
+// transitionAt returns when a transition confirmed by the fix at ts was first
+// observed: the start of its pending streak, or ts itself when no earlier fix
+// was pending.
+func transitionAt(prev *domain.GeofenceState, ts time.Time) time.Time {
+	if prev != nil && prev.PendingFixes > 0 {
+		return prev.PendingSince
+	}
+	return ts
+}
+
+func newAlert(gf *domain.Geofence, st *domain.GeofenceState, event domain.GeofenceEventType, vl *domain.VehicleLocation) *domain.GeofenceAlert {
+	alert := &domain.GeofenceAlert{
+		VehicleID: vl.VehicleID,
+		Event:     event,
+		Geofence:  *gf,
+		Distance:  centerDistance(gf, vl.Location.Lat, vl.Location.Lon),
+		Location:  vl.Location,
+		Timestamp: vl.Location.Timestamp.Unix(),
+	}
+	if event == domain.GeofenceDwell {
+		alert.Dwell = vl.Location.Timestamp.Sub(st.EnteredAt)
+	}
+	return alert
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+// fixAt returns a location the given number of meters north of the test
+// fence centre (-6.2088, 106.8456), offset seconds after a fixed start.
+func fixAt(meters float64, offset time.Duration) *domain.VehicleLocation {
+	return &domain.VehicleLocation{
+		VehicleID: "B1234XYZ",
+		Location: domain.Location{
+			Lat:       -6.2088 + metersToDegrees(meters),
+			Lon:       106.8456,
+			Timestamp: time.Unix(1715003456, 0).Add(offset),
+		},
+	}
+}
+
+// replay runs the fixes through evaluate and returns the events emitted.
+func replay(gf *domain.Geofence, opts GeofenceOptions, fixes ...*domain.VehicleLocation) ([]domain.GeofenceEventType, *domain.GeofenceState) {
+	var (
+		st     *domain.GeofenceState
+		events []domain.GeofenceEventType
+	)
+	for _, vl := range fixes {
+		next, event := evaluate(gf, st, vl, opts)
+		if next != nil {
+			st = next
+		}
+		if event != "" {
+			events = append(events, event)
+		}
+	}
+	return events, st
+}
+
+func TestEvaluate_ExitBufferIgnoresBoundaryJitter(t *testing.T) {
+	gf := circleFence("a", -6.2088, 106.8456, 50)
+	opts := GeofenceOptions{ExitBuffer: 10}
+
+	events, st := replay(&gf, opts,
+		fixAt(0, 0),
+		fixAt(55, time.Second),   // 5m outside, within exit buffer
+		fixAt(48, 2*time.Second), // back inside
+		fixAt(58, 3*time.Second), // 8m outside, within exit buffer
+	)
+	if len(events) != 1 || events[0] != domain.GeofenceEntry {
+		t.Fatalf("expected only entry, got %v", events)
+	}
+	if !st.Inside {
+		t.Fatal("expected vehicle to still be inside")
+	}
+
+	events, _ = replay(&gf, opts, fixAt(0, 0), fixAt(65, time.Second))
+	if len(events) != 2 || events[1] != domain.GeofenceExit {
+		t.Fatalf("expected exit beyond buffer, got %v", events)
+	}
+}
+
+func TestEvaluate_EnterBufferRequiresDepth(t *testing.T) {
+	gf := circleFence("a", -6.2088, 106.8456, 50)
+	opts := GeofenceOptions{EnterBuffer: 10}
+
+	events, _ := replay(&gf, opts, fixAt(45, 0))
+	if len(events) != 0 {
+		t.Fatalf("expected no entry 5m inside with 10m enter buffer, got %v", events)
+	}
+
+	events, _ = replay(&gf, opts, fixAt(45, 0), fixAt(35, time.Second))
+	if len(events) != 1 || events[0] != domain.GeofenceEntry {
+		t.Fatalf("expected entry 15m inside, got %v", events)
+	}
+}
+
+func TestEvaluate_MinFixes(t *testing.T) {
+	gf := circleFence("a", -6.2088, 106.8456, 50)
+	opts := GeofenceOptions{MinFixes: 3}
+
+	events, st := replay(&gf, opts,
+		fixAt(0, 0),
+		fixAt(0, time.Second),
+		fixAt(200, 2*time.Second), // breaks the streak
+		fixAt(0, 3*time.Second),
+		fixAt(0, 4*time.Second),
+	)
+	if len(events) != 0 {
+		t.Fatalf("expected no confirmed entry, got %v", events)
+	}
+	if st.PendingFixes != 2 {
+		t.Fatalf("expected 2 pending fixes, got %d", st.PendingFixes)
+	}
+
+	events, st = replay(&gf, opts, fixAt(0, 0), fixAt(0, time.Second), fixAt(0, 2*time.Second))
+	if len(events) != 1 || events[0] != domain.GeofenceEntry {
+		t.Fatalf("expected entry on third fix, got %v", events)
+	}
+	if !st.EnteredAt.Equal(time.Unix(1715003456, 0)) {
+		t.Errorf("expected entered_at at first fix, got %v", st.EnteredAt)
+	}
+	if st.PendingFixes != 0 || !st.PendingSince.IsZero() {
+		t.Errorf("expected pending to be cleared, got %+v", st)
+	}
+}
+
+func TestEvaluate_MinDuration(t *testing.T) {
+	gf := circleFence("a", -6.2088, 106.8456, 50)
+	opts := GeofenceOptions{MinDuration: 30 * time.Second}
+
+	events, _ := replay(&gf, opts, fixAt(0, 0), fixAt(0, 10*time.Second), fixAt(0, 20*time.Second))
+	if len(events) != 0 {
+		t.Fatalf("expected no entry before 30s, got %v", events)
+	}
+
+	events, _ = replay(&gf, opts, fixAt(0, 0), fixAt(0, 10*time.Second), fixAt(0, 30*time.Second))
+	if len(events) != 1 || events[0] != domain.GeofenceEntry {
+		t.Fatalf("expected entry at 30s, got %v", events)
+	}
+}
+
+func TestEvaluate_DebouncedExit(t *testing.T) {
+	gf := circleFence("a", -6.2088, 106.8456, 50)
+	opts := GeofenceOptions{MinFixes: 2}
+
+	events, st := replay(&gf, opts,
+		fixAt(0, 0),
+		fixAt(0, time.Second),
+		fixAt(200, 2*time.Second), // single outlier
+		fixAt(0, 3*time.Second),
+	)
+	if len(events) != 1 || events[0] != domain.GeofenceEntry {
+		t.Fatalf("expected single outlier not to cause exit, got %v", events)
+	}
+	if !st.Inside || st.PendingFixes != 0 {
+		t.Fatalf("expected inside with no pending transition, got %+v", st)
+	}
+}
+
+func TestEvaluate_ScheduleUsesFixTimestamp(t *testing.T) {
+	gf := circleFence("a", -6.2088, 106.8456, 50)
+	gf.Schedule = &domain.Schedule{
+		Timezone: "UTC",
+		Windows:  []domain.ScheduleWindow{{Start: 14 * time.Hour, End: 15 * time.Hour}},
+	}
+
+	// fixAt starts at 13:50:56 UTC, before the window opens
+	events, _ := replay(&gf, GeofenceOptions{},
+		fixAt(0, 0),
+		fixAt(0, 5*time.Minute),
+		fixAt(0, 10*time.Minute), // 14:00:56, window open
+		fixAt(0, 69*time.Minute), // 14:59:56
+		fixAt(0, 70*time.Minute), // 15:00:56, window closed
+	)
+	want := []domain.GeofenceEventType{domain.GeofenceEntry, domain.GeofenceExit}
+	if len(events) != 2 || events[0] != want[0] || events[1] != want[1] {
+		t.Fatalf("expected %v, got %v", want, events)
+	}
+}
@@ -2,52 +2,549 @@ package service
 
 import (
 	"context"
-	"math"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
 	"github.com/nandanugg/tj-test/module/core/internal/repository/publisher"
 )
 
-const earthRadiusMeters = 6371000
-
 type GeofenceService struct {
-	publisher publisher.GeofencePublisher
-	geofences []domain.GeoPoint
+	publisher  publisher.GeofencePublisher
+	repo       database.GeofenceRepository
+	stateRepo  database.GeofenceStateRepository
+	assignRepo database.VehicleAssignmentRepository
+	visitRepo  database.GeofenceVisitRepository
+	opts       GeofenceOptions
+
+	// mu guards the fields below. It is never held across repository or
+	// publisher calls, so a slow database does not stall every vehicle.
+	mu        sync.Mutex
+	geofences []domain.Geofence
+	index     *geofenceIndex
+	// states caches persisted geofence states, keyed by vehicle ID then geofence ID.
+	// A vehicle is loaded from stateRepo the first time it is seen.
+	states map[string]map[string]*domain.GeofenceState
+	// assignments caches each vehicle's group and route membership, loaded
+	// from assignRepo the first time the vehicle is seen.
+	assignments map[string]*domain.VehicleAssignment
+	// vehicleLocks serialise CheckAndAlert per vehicle while it evaluates,
+	// publishes and persists the vehicle's transitions.
+	vehicleLocks map[string]*sync.Mutex
 }
 
-func NewGeofenceService(pub publisher.GeofencePublisher, geofences []domain.GeoPoint) *GeofenceService {
+func NewGeofenceService(pub publisher.GeofencePublisher, repo database.GeofenceRepository, stateRepo database.GeofenceStateRepository, assignRepo database.VehicleAssignmentRepository, visitRepo database.GeofenceVisitRepository, opts GeofenceOptions) *GeofenceService {
 	return &GeofenceService{
-		publisher: pub,
-		geofences: geofences,
+		publisher:    pub,
+		repo:         repo,
+		stateRepo:    stateRepo,
+		assignRepo:   assignRepo,
+		visitRepo:    visitRepo,
+		opts:         opts,
+		index:        newGeofenceIndex(nil),
+		states:       make(map[string]map[string]*domain.GeofenceState),
+		assignments:  make(map[string]*domain.VehicleAssignment),
+		vehicleLocks: make(map[string]*sync.Mutex),
+	}
+}
+
+// Reload replaces the in-memory geofence set with the one in the repository.
+func (s *GeofenceService) Reload(ctx context.Context) error {
+	geofences, err := s.repo.List(ctx)
+	if err != nil {
+		return fmt.Errorf("load geofences: %w", err)
+	}
+
+	s.mu.Lock()
+	s.setGeofences(geofences)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *GeofenceService) ListGeofences(ctx context.Context) ([]domain.Geofence, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *GeofenceService) GetGeofence(ctx context.Context, id string) (*domain.Geofence, error) {
+	return s.repo.Get(ctx, id)
+}
+
+func (s *GeofenceService) CreateGeofence(ctx context.Context, gf *domain.Geofence) error {
+	if err := s.repo.Create(ctx, gf); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.putGeofences(*gf)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *GeofenceService) UpdateGeofence(ctx context.Context, gf *domain.Geofence) error {
+	if err := s.repo.Update(ctx, gf); err != nil {
+		return err
 	}
+
+	s.mu.Lock()
+	s.putGeofences(*gf)
+	s.mu.Unlock()
+	return nil
+}
+
+// ImportGeofences creates each geofence, or updates it if one with the same ID
+// exists. Imported files carry no assignment or schedule, so an update keeps
+// the existing ones. Geofences written before an error are kept.
+func (s *GeofenceService) ImportGeofences(ctx context.Context, geofences []domain.Geofence) (created, updated int, err error) {
+	s.mu.Lock()
+	existing := make(map[string]domain.Geofence, len(geofences))
+	for _, gf := range geofences {
+		if i, ok := s.index.byID[gf.ID]; ok && gf.ID != "" {
+			existing[gf.ID] = s.geofences[i]
+		}
+	}
+	s.mu.Unlock()
+
+	imported := make([]domain.Geofence, 0, len(geofences))
+	defer func() {
+		s.mu.Lock()
+		s.putGeofences(imported...)
+		s.mu.Unlock()
+	}()
+
+	for _, gf := range geofences {
+		if prev, ok := existing[gf.ID]; ok {
+			gf.Assignment = prev.Assignment
+			gf.Schedule = prev.Schedule
+			if err := s.repo.Update(ctx, &gf); err != nil {
+				return created, updated, fmt.Errorf("update geofence %s: %w", gf.ID, err)
+			}
+			updated++
+		} else {
+			if err := s.repo.Create(ctx, &gf); err != nil {
+				return created, updated, fmt.Errorf("create geofence %s: %w", gf.ID, err)
+			}
+			created++
+		}
+		imported = append(imported, gf)
+	}
+	return created, updated, nil
+}
+
+// DeleteGeofence removes the geofence and forgets every vehicle's state for it,
+// so a geofence later recreated under the same ID starts from outside.
+func (s *GeofenceService) DeleteGeofence(ctx context.Context, id string) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.index.byID[id]; ok {
+		s.setGeofences(append(s.geofences[:i:i], s.geofences[i+1:]...))
+	}
+	for _, states := range s.states {
+		delete(states, id)
+	}
+	return nil
 }
 
+// VehiclesInside returns the vehicles currently inside the geofence, earliest
+// entry first.
+func (s *GeofenceService) VehiclesInside(ctx context.Context, geofenceID string) ([]domain.GeofencePresence, error) {
+	s.mu.Lock()
+	i, ok := s.index.byID[geofenceID]
+	var gf domain.Geofence
+	if ok {
+		gf = s.geofences[i]
+	}
+	s.mu.Unlock()
+	if !ok {
+		return nil, domain.ErrGeofenceNotFound
+	}
+
+	states, err := s.stateRepo.ListInside(ctx, geofenceID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]domain.GeofencePresence, len(states))
+	for i, st := range states {
+		results[i] = domain.GeofencePresence{VehicleID: st.VehicleID, Geofence: gf, EnteredAt: st.EnteredAt}
+	}
+	return results, nil
+}
+
+// GeofenceVisits returns the completed visits to the geofence that started
+// between start and end, earliest entry first.
+func (s *GeofenceService) GeofenceVisits(ctx context.Context, geofenceID string, start, end time.Time) ([]domain.GeofenceVisit, error) {
+	s.mu.Lock()
+	_, ok := s.index.byID[geofenceID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, domain.ErrGeofenceNotFound
+	}
+	return s.visitRepo.List(ctx, &domain.VisitQuery{GeofenceID: geofenceID, Start: start, End: end})
+}
+
+// VehicleVisits returns the vehicle's completed geofence visits that started
+// between start and end, earliest entry first.
+func (s *GeofenceService) VehicleVisits(ctx context.Context, vehicleID string, start, end time.Time) ([]domain.GeofenceVisit, error) {
+	return s.visitRepo.List(ctx, &domain.VisitQuery{VehicleID: vehicleID, Start: start, End: end})
+}
+
+// GeofencesContaining returns the geofences the vehicle is currently inside,
+// earliest entry first.
+func (s *GeofenceService) GeofencesContaining(ctx context.Context, vehicleID string) ([]domain.GeofencePresence, error) {
+	states, err := s.stateRepo.GetByVehicle(ctx, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	var results []domain.GeofencePresence
+	for _, st := range states {
+		i, ok := s.index.byID[st.GeofenceID]
+		if !st.Inside || !ok {
+			continue
+		}
+		results = append(results, domain.GeofencePresence{VehicleID: vehicleID, Geofence: s.geofences[i], EnteredAt: st.EnteredAt})
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(results, func(a, b int) bool {
+		if !results[a].EnteredAt.Equal(results[b].EnteredAt) {
+			return results[a].EnteredAt.Before(results[b].EnteredAt)
+		}
+		return results[a].Geofence.ID < results[b].Geofence.ID
+	})
+	return results, nil
+}
+
+func (s *GeofenceService) GetVehicleAssignment(ctx context.Context, vehicleID string) (*domain.VehicleAssignment, error) {
+	va, err := s.vehicleAssignment(ctx, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	cp := *va
+	return &cp, nil
+}
+
+func (s *GeofenceService) SetVehicleAssignment(ctx context.Context, va *domain.VehicleAssignment) error {
+	if err := s.assignRepo.Upsert(ctx, va); err != nil {
+		return err
+	}
+
+	cp := *va
+	s.mu.Lock()
+	s.assignments[va.VehicleID] = &cp
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *GeofenceService) DeleteVehicleAssignment(ctx context.Context, vehicleID string) error {
+	if err := s.assignRepo.Delete(ctx, vehicleID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.assignments[vehicleID] = &domain.VehicleAssignment{VehicleID: vehicleID}
+	s.mu.Unlock()
+	return nil
+}
+
+// CheckAndAlert publishes a geofence_entry when the vehicle moves from outside
+// to inside a geofence, a geofence_exit when it moves back out, and a single
+// geofence_dwell once it has stayed inside longer than the geofence's dwell
+// threshold. Transitions are subject to the service's hysteresis buffers and
+// debounce rules. Pings that do not change the vehicle's state are silent.
+// Every confirmed exit is also stored as a visit. Leaving a corridor the
+// vehicle is assigned to is published as a route_deviation instead of a
+// geofence_exit.
 func (s *GeofenceService) CheckAndAlert(ctx context.Context, vl *domain.VehicleLocation) error {
-	for _, gf := range s.geofences {
-		dist := haversine(vl.Location.Lat, vl.Location.Lon, gf.Lat, gf.Lon)
-		if dist <= gf.Radius {
-			alert := &domain.GeofenceAlert{
-				VehicleID: vl.VehicleID,
-				Event:     domain.GeofenceEntry,
-				Location:  vl.Location,
-				Timestamp: vl.Location.Timestamp.Unix(),
-			}
-			if err := s.publisher.PublishAlert(ctx, alert); err != nil {
+	lock := s.vehicleLock(vl.VehicleID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	states, err := s.vehicleStates(ctx, vl.VehicleID)
+	if err != nil {
+		return err
+	}
+	va, err := s.vehicleAssignment(ctx, vl.VehicleID)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range s.transitions(va, states, vl) {
+		if t.alert != nil {
+			if err := s.publisher.PublishAlert(ctx, t.alert); err != nil {
 				return err
 			}
 		}
+		if t.visit != nil {
+			if err := s.visitRepo.Insert(ctx, t.visit); err != nil {
+				return fmt.Errorf("save geofence visit: %w", err)
+			}
+		}
+
+		if err := s.stateRepo.Upsert(ctx, t.next); err != nil {
+			return fmt.Errorf("save geofence state: %w", err)
+		}
+		s.mu.Lock()
+		// a geofence deleted in the meantime has had its states forgotten
+		if _, ok := s.index.byID[t.next.GeofenceID]; ok {
+			states[t.next.GeofenceID] = t.next
+		}
+		s.mu.Unlock()
 	}
 	return nil
 }
 
-func haversine(lat1, lon1, lat2, lon2 float64) float64 {
-	dLat := toRad(lat2 - lat1)
-	dLon := toRad(lon2 - lon1)
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
-	return earthRadiusMeters * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+// transition is a change of a vehicle's state for one geofence, with the alert
+// to publish and the visit it completes, if any.
+type transition struct {
+	next  *domain.GeofenceState
+	alert *domain.GeofenceAlert
+	visit *domain.GeofenceVisit
+}
+
+// transitions evaluates the location against the vehicle's evaluation set
+// and returns the state changes it causes, leaving states untouched.
+func (s *GeofenceService) transitions(va *domain.VehicleAssignment, states map[string]*domain.GeofenceState, vl *domain.VehicleLocation) []transition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ts []transition
+	for _, gf := range s.evaluationSet(va, states, vl.Location.Lat, vl.Location.Lon) {
+		next, event := evaluate(gf, states[gf.ID], vl, s.opts)
+		if next == nil {
+			continue
+		}
+
+		t := transition{next: next}
+		if event == domain.GeofenceExit {
+			event = exitEvent(gf, va)
+			t.visit = completedVisit(states[gf.ID], vl.Location.Timestamp)
+		}
+		if event != "" {
+			t.alert = newAlert(gf, next, event, vl)
+		}
+		ts = append(ts, t)
+	}
+	return ts
+}
+
+// Simulate replays a track through the current geofences as if the vehicle
+// had never been seen before, and reports what each point would trigger.
+// Nothing is published or persisted. An empty vehicleID checks the track
+// against every geofence regardless of assignment.
+func (s *GeofenceService) Simulate(ctx context.Context, vehicleID string, track []domain.Location) ([]domain.SimulationStep, error) {
+	var va *domain.VehicleAssignment
+	if vehicleID != "" {
+		var err error
+		if va, err = s.vehicleAssignment(ctx, vehicleID); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make(map[string]*domain.GeofenceState)
+	steps := make([]domain.SimulationStep, len(track))
+	for i, loc := range track {
+		vl := &domain.VehicleLocation{VehicleID: vehicleID, Location: loc}
+		step := domain.SimulationStep{Location: loc, Inside: []string{}}
+
+		for _, j := range s.index.candidates(loc.Lat, loc.Lon) {
+			gf := &s.geofences[j]
+			if appliesTo(gf, va) && contains(gf, loc.Lat, loc.Lon) {
+				step.Inside = append(step.Inside, gf.ID)
+			}
+		}
+
+		for _, gf := range s.evaluationSet(va, states, loc.Lat, loc.Lon) {
+			next, event := evaluate(gf, states[gf.ID], vl, s.opts)
+			if next == nil {
+				continue
+			}
+			if event == domain.GeofenceExit {
+				event = exitEvent(gf, va)
+			}
+			if event != "" {
+				step.Alerts = append(step.Alerts, *newAlert(gf, next, event, vl))
+			}
+			states[gf.ID] = next
+		}
+		steps[i] = step
+	}
+	return steps, nil
+}
+
+func (s *GeofenceService) setGeofences(geofences []domain.Geofence) {
+	s.geofences = geofences
+	s.index = newGeofenceIndex(geofences)
+}
+
+// putGeofences replaces cached geofences by ID, appending new ones, and
+// rebuilds the index once.
+func (s *GeofenceService) putGeofences(geofences ...domain.Geofence) {
+	if len(geofences) == 0 {
+		return
+	}
+	for _, gf := range geofences {
+		if i, ok := s.index.byID[gf.ID]; ok {
+			s.geofences[i] = gf
+			continue
+		}
+		s.geofences = append(s.geofences, gf)
+		s.index.byID[gf.ID] = len(s.geofences) - 1
+	}
+	s.setGeofences(s.geofences)
+}
+
+// evaluationSet returns the geofences near the point that apply to the vehicle
+// plus every geofence the vehicle is inside or has a pending transition for,
+// so exits from distant fences are still seen and stale pending entries are
+// reset. Fences the vehicle is no longer assigned to stay in the set until it
+// leaves them, so every entry is closed by an exit.
+func (s *GeofenceService) evaluationSet(va *domain.VehicleAssignment, states map[string]*domain.GeofenceState, lat, lon float64) []*domain.Geofence {
+	candidates := s.index.candidates(lat, lon)
+	set := make([]*domain.Geofence, 0, len(candidates)+len(states))
+	seen := make(map[int]bool, len(candidates))
+	for _, i := range candidates {
+		if !appliesTo(&s.geofences[i], va) {
+			continue
+		}
+		seen[i] = true
+		set = append(set, &s.geofences[i])
+	}
+	var inside []int
+	for id, st := range states {
+		if !st.Inside && st.PendingFixes == 0 {
+			continue
+		}
+		if i, ok := s.index.byID[id]; ok && !seen[i] {
+			inside = append(inside, i)
+		}
+	}
+	sort.Ints(inside)
+	for _, i := range inside {
+		set = append(set, &s.geofences[i])
+	}
+	return set
+}
+
+// vehicleLock returns the lock that serialises CheckAndAlert for the vehicle.
+func (s *GeofenceService) vehicleLock(vehicleID string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.vehicleLocks[vehicleID]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.vehicleLocks[vehicleID] = lock
+	}
+	return lock
+}
+
+// vehicleStates returns the vehicle's cached states, loading them on first
+// use. The map is shared with the cache, so it must only be read or written
+// while holding mu.
+func (s *GeofenceService) vehicleStates(ctx context.Context, vehicleID string) (map[string]*domain.GeofenceState, error) {
+	s.mu.Lock()
+	states, ok := s.states[vehicleID]
+	s.mu.Unlock()
+	if ok {
+		return states, nil
+	}
+
+	persisted, err := s.stateRepo.GetByVehicle(ctx, vehicleID)
+	if err != nil {
+		return nil, fmt.Errorf("load geofence state: %w", err)
+	}
+
+	states = make(map[string]*domain.GeofenceState, len(persisted))
+	for i := range persisted {
+		states[persisted[i].GeofenceID] = &persisted[i]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.states[vehicleID]; ok {
+		return cached, nil
+	}
+	s.states[vehicleID] = states
+	return states, nil
 }
 
-func toRad(deg float64) float64 {
-	return deg * math.Pi / 180
+// vehicleAssignment returns the vehicle's cached assignment, loading it on
+// first use. An assignment set while it was loading is kept.
+func (s *GeofenceService) vehicleAssignment(ctx context.Context, vehicleID string) (*domain.VehicleAssignment, error) {
+	s.mu.Lock()
+	va, ok := s.assignments[vehicleID]
+	s.mu.Unlock()
+	if ok {
+		return va, nil
+	}
+
+	va, err := s.assignRepo.Get(ctx, vehicleID)
+	if err != nil {
+		return nil, fmt.Errorf("load vehicle assignment: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.assignments[vehicleID]; ok {
+		return cached, nil
+	}
+	s.assignments[vehicleID] = va
+	return va, nil
+}
+
+// completedVisit builds the visit closed by an exit confirmed at ts. prev is
+// the state before the exit, so it still holds the entry time.
+func completedVisit(prev *domain.GeofenceState, ts time.Time) *domain.GeofenceVisit {
+	exitedAt := transitionAt(prev, ts)
+	return &domain.GeofenceVisit{
+		VehicleID:  prev.VehicleID,
+		GeofenceID: prev.GeofenceID,
+		EnteredAt:  prev.EnteredAt,
+		ExitedAt:   exitedAt,
+		Duration:   exitedAt.Sub(prev.EnteredAt),
+	}
+}
+
+// exitEvent returns the event for a vehicle leaving gf: route_deviation for
+// a corridor scoped to the vehicle, geofence_exit otherwise. A corridor that
+// applies to every vehicle has no assigned vehicles to deviate.
+func exitEvent(gf *domain.Geofence, va *domain.VehicleAssignment) domain.GeofenceEventType {
+	if gf.Shape == domain.ShapeCorridor && !gf.Assignment.IsGlobal() && va != nil && appliesTo(gf, va) {
+		return domain.RouteDeviation
+	}
+	return domain.GeofenceExit
+}
+
+// appliesTo reports whether gf is global or assigned to the vehicle directly
+// or through one of its groups or routes. A nil assignment matches every
+// geofence.
+func appliesTo(gf *domain.Geofence, va *domain.VehicleAssignment) bool {
+	a := gf.Assignment
+	if a.IsGlobal() || va == nil {
+		return true
+	}
+	return slices.Contains(a.VehicleIDs, va.VehicleID) ||
+		containsAny(a.GroupIDs, va.GroupIDs) ||
+		containsAny(a.RouteIDs, va.RouteIDs)
+}
+
+func containsAny(set, values []string) bool {
+	for _, v := range values {
+		if slices.Contains(set, v) {
+			return true
+		}
+	}
+	return false
 }
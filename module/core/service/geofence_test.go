@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 	"time"
 
@@ -22,12 +23,155 @@ func (m *mockGeofencePublisher) PublishAlert(ctx context.Context, alert *domain.
 	return nil
 }
 
+type mockGeofenceStateRepo struct {
+	getByVehicleFn func(ctx context.Context, vehicleID string) ([]domain.GeofenceState, error)
+	upsertFn       func(ctx context.Context, state *domain.GeofenceState) error
+	upserts        []domain.GeofenceState
+}
+
+func (m *mockGeofenceStateRepo) GetByVehicle(ctx context.Context, vehicleID string) ([]domain.GeofenceState, error) {
+	if m.getByVehicleFn != nil {
+		return m.getByVehicleFn(ctx, vehicleID)
+	}
+	return nil, nil
+}
+
+// ListInside replays the recorded upserts so only each vehicle's latest state
+// counts.
+func (m *mockGeofenceStateRepo) ListInside(_ context.Context, geofenceID string) ([]domain.GeofenceState, error) {
+	var vehicles []string
+	latest := make(map[string]domain.GeofenceState)
+	for _, st := range m.upserts {
+		if st.GeofenceID != geofenceID {
+			continue
+		}
+		if _, ok := latest[st.VehicleID]; !ok {
+			vehicles = append(vehicles, st.VehicleID)
+		}
+		latest[st.VehicleID] = st
+	}
+
+	var results []domain.GeofenceState
+	for _, id := range vehicles {
+		if latest[id].Inside {
+			results = append(results, latest[id])
+		}
+	}
+	return results, nil
+}
+
+func (m *mockGeofenceStateRepo) Upsert(ctx context.Context, state *domain.GeofenceState) error {
+	m.upserts = append(m.upserts, *state)
+	if m.upsertFn != nil {
+		return m.upsertFn(ctx, state)
+	}
+	return nil
+}
+
+type mockGeofenceRepo struct {
+	listFn   func(ctx context.Context) ([]domain.Geofence, error)
+	getFn    func(ctx context.Context, id string) (*domain.Geofence, error)
+	createFn func(ctx context.Context, gf *domain.Geofence) error
+	updateFn func(ctx context.Context, gf *domain.Geofence) error
+	deleteFn func(ctx context.Context, id string) error
+}
+
+func (m *mockGeofenceRepo) List(ctx context.Context) ([]domain.Geofence, error) {
+	return m.listFn(ctx)
+}
+
+func (m *mockGeofenceRepo) Get(ctx context.Context, id string) (*domain.Geofence, error) {
+	return m.getFn(ctx, id)
+}
+
+func (m *mockGeofenceRepo) Create(ctx context.Context, gf *domain.Geofence) error {
+	return m.createFn(ctx, gf)
+}
+
+func (m *mockGeofenceRepo) Update(ctx context.Context, gf *domain.Geofence) error {
+	return m.updateFn(ctx, gf)
+}
+
+func (m *mockGeofenceRepo) Delete(ctx context.Context, id string) error {
+	return m.deleteFn(ctx, id)
+}
+
+type mockVehicleAssignmentRepo struct {
+	assignments map[string]domain.VehicleAssignment
+	getCalls    int
+}
+
+func (m *mockVehicleAssignmentRepo) Get(_ context.Context, vehicleID string) (*domain.VehicleAssignment, error) {
+	m.getCalls++
+	va, ok := m.assignments[vehicleID]
+	if !ok {
+		va = domain.VehicleAssignment{VehicleID: vehicleID}
+	}
+	return &va, nil
+}
+
+func (m *mockVehicleAssignmentRepo) Upsert(_ context.Context, va *domain.VehicleAssignment) error {
+	if m.assignments == nil {
+		m.assignments = make(map[string]domain.VehicleAssignment)
+	}
+	m.assignments[va.VehicleID] = *va
+	return nil
+}
+
+func (m *mockVehicleAssignmentRepo) Delete(_ context.Context, vehicleID string) error {
+	delete(m.assignments, vehicleID)
+	return nil
+}
+
+type mockGeofenceVisitRepo struct {
+	insertFn func(ctx context.Context, visit *domain.GeofenceVisit) error
+	visits   []domain.GeofenceVisit
+	queries  []domain.VisitQuery
+}
+
+func (m *mockGeofenceVisitRepo) Insert(ctx context.Context, visit *domain.GeofenceVisit) error {
+	if m.insertFn != nil {
+		if err := m.insertFn(ctx, visit); err != nil {
+			return err
+		}
+	}
+	m.visits = append(m.visits, *visit)
+	return nil
+}
+
+func (m *mockGeofenceVisitRepo) List(_ context.Context, query *domain.VisitQuery) ([]domain.GeofenceVisit, error) {
+	m.queries = append(m.queries, *query)
+	return m.visits, nil
+}
+
+func newGeofenceService(t *testing.T, pub *mockGeofencePublisher, stateRepo *mockGeofenceStateRepo, geofences []domain.Geofence) *GeofenceService {
+	t.Helper()
+	repo := &mockGeofenceRepo{
+		listFn: func(_ context.Context) ([]domain.Geofence, error) {
+			return geofences, nil
+		},
+	}
+	svc := NewGeofenceService(pub, repo, stateRepo, &mockVehicleAssignmentRepo{}, &mockGeofenceVisitRepo{}, GeofenceOptions{})
+	if err := svc.Reload(context.Background()); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	return svc
+}
+
+func circleFence(id string, lat, lon, radius float64) domain.Geofence {
+	return domain.Geofence{
+		ID:     id,
+		Shape:  domain.ShapeCircle,
+		Circle: &domain.GeoPoint{Lat: lat, Lon: lon, Radius: radius},
+	}
+}
+
 func TestCheckAndAlert_InsideGeofence(t *testing.T) {
 	pub := &mockGeofencePublisher{}
-	geofences := []domain.GeoPoint{
-		{Lat: -6.2088, Lon: 106.8456, Radius: 50},
+	geofences := []domain.Geofence{
+		circleFence("a", -6.2088, 106.8456, 50),
 	}
-	svc := NewGeofenceService(pub, geofences)
+	svc := newGeofenceService(t, pub, &mockGeofenceStateRepo{}, geofences)
 
 	// exact same point — distance is 0, within 50m
 	vl := &domain.VehicleLocation{
@@ -53,14 +197,20 @@ func TestCheckAndAlert_InsideGeofence(t *testing.T) {
 	if alert.Event != domain.GeofenceEntry {
 		t.Errorf("expected geofence_entry, got %s", alert.Event)
 	}
+	if alert.Geofence.ID != "a" {
+		t.Errorf("expected geofence a, got %s", alert.Geofence.ID)
+	}
+	if alert.Distance != 0 {
+		t.Errorf("expected distance 0, got %f", alert.Distance)
+	}
 }
 
 func TestCheckAndAlert_OutsideGeofence(t *testing.T) {
 	pub := &mockGeofencePublisher{}
-	geofences := []domain.GeoPoint{
-		{Lat: -6.2088, Lon: 106.8456, Radius: 50},
+	geofences := []domain.Geofence{
+		circleFence("a", -6.2088, 106.8456, 50),
 	}
-	svc := NewGeofenceService(pub, geofences)
+	svc := newGeofenceService(t, pub, &mockGeofenceStateRepo{}, geofences)
 
 	// far away point
 	vl := &domain.VehicleLocation{
@@ -83,12 +233,12 @@ func TestCheckAndAlert_OutsideGeofence(t *testing.T) {
 
 func TestCheckAndAlert_MultipleGeofences(t *testing.T) {
 	pub := &mockGeofencePublisher{}
-	geofences := []domain.GeoPoint{
-		{Lat: -6.2088, Lon: 106.8456, Radius: 50},
-		{Lat: -6.2088, Lon: 106.8456, Radius: 100}, // overlapping
-		{Lat: -7.0, Lon: 107.0, Radius: 50},        // far away
+	geofences := []domain.Geofence{
+		circleFence("a", -6.2088, 106.8456, 50),
+		circleFence("b", -6.2088, 106.8456, 100), // overlapping
+		circleFence("c", -7.0, 107.0, 50),        // far away
 	}
-	svc := NewGeofenceService(pub, geofences)
+	svc := newGeofenceService(t, pub, &mockGeofenceStateRepo{}, geofences)
 
 	vl := &domain.VehicleLocation{
 		VehicleID: "B1234XYZ",
@@ -114,10 +264,10 @@ func TestCheckAndAlert_PublishError(t *testing.T) {
 			return errors.New("rabbitmq down")
 		},
 	}
-	geofences := []domain.GeoPoint{
-		{Lat: -6.2088, Lon: 106.8456, Radius: 50},
+	geofences := []domain.Geofence{
+		circleFence("a", -6.2088, 106.8456, 50),
 	}
-	svc := NewGeofenceService(pub, geofences)
+	svc := newGeofenceService(t, pub, &mockGeofenceStateRepo{}, geofences)
 
 	vl := &domain.VehicleLocation{
 		VehicleID: "B1234XYZ",
@@ -134,9 +284,52 @@ func TestCheckAndAlert_PublishError(t *testing.T) {
 	}
 }
 
+func TestCheckAndAlert_SlowPublishDoesNotBlockOtherVehicles(t *testing.T) {
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	pub := &mockGeofencePublisher{
+		publishAlertFn: func(_ context.Context, alert *domain.GeofenceAlert) error {
+			if alert.VehicleID == "slow" {
+				close(blocked)
+				<-release
+			}
+			return nil
+		},
+	}
+	svc := newGeofenceService(t, pub, &mockGeofenceStateRepo{}, []domain.Geofence{circleFence("a", -6.2088, 106.8456, 50)})
+	loc := domain.Location{Lat: -6.2088, Lon: 106.8456, Timestamp: time.Unix(1715003456, 0)}
+
+	slow := make(chan error, 1)
+	go func() {
+		slow <- svc.CheckAndAlert(context.Background(), &domain.VehicleLocation{VehicleID: "slow", Location: loc})
+	}()
+	<-blocked
+
+	fast := make(chan error, 1)
+	go func() {
+		fast <- svc.CheckAndAlert(context.Background(), &domain.VehicleLocation{VehicleID: "fast", Location: loc})
+	}()
+	select {
+	case err := <-fast:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected another vehicle to be checked while a publish is in flight")
+	}
+
+	close(release)
+	if err := <-slow; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pub.calls) != 2 {
+		t.Errorf("expected 2 alerts, got %d", len(pub.calls))
+	}
+}
+
 func TestCheckAndAlert_NoGeofences(t *testing.T) {
 	pub := &mockGeofencePublisher{}
-	svc := NewGeofenceService(pub, nil)
+	svc := newGeofenceService(t, pub, &mockGeofenceStateRepo{}, nil)
 
 	vl := &domain.VehicleLocation{
 		VehicleID: "B1234XYZ",
@@ -156,6 +349,800 @@ func TestCheckAndAlert_NoGeofences(t *testing.T) {
 	}
 }
 
+func TestCheckAndAlert_RepeatedPingsInside_SingleEntry(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	repo := &mockGeofenceStateRepo{}
+	geofences := []domain.Geofence{
+		circleFence("a", -6.2088, 106.8456, 50),
+	}
+	svc := newGeofenceService(t, pub, repo, geofences)
+
+	for i := 0; i < 5; i++ {
+		vl := &domain.VehicleLocation{
+			VehicleID: "B1234XYZ",
+			Location: domain.Location{
+				Lat:       -6.2088,
+				Lon:       106.8456,
+				Timestamp: time.Unix(1715003456+int64(i), 0),
+			},
+		}
+		if err := svc.CheckAndAlert(context.Background(), vl); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(pub.calls) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(pub.calls))
+	}
+	if len(repo.upserts) != 1 {
+		t.Fatalf("expected 1 state write, got %d", len(repo.upserts))
+	}
+}
+
+func TestCheckAndAlert_EntryThenExit(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	repo := &mockGeofenceStateRepo{}
+	geofences := []domain.Geofence{
+		circleFence("a", -6.2088, 106.8456, 50),
+	}
+	svc := newGeofenceService(t, pub, repo, geofences)
+
+	points := []domain.Location{
+		{Lat: -7.0, Lon: 107.0, Timestamp: time.Unix(1715003400, 0)},
+		{Lat: -6.2088, Lon: 106.8456, Timestamp: time.Unix(1715003456, 0)},
+		{Lat: -7.0, Lon: 107.0, Timestamp: time.Unix(1715003500, 0)},
+	}
+	for _, loc := range points {
+		vl := &domain.VehicleLocation{VehicleID: "B1234XYZ", Location: loc}
+		if err := svc.CheckAndAlert(context.Background(), vl); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(pub.calls) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(pub.calls))
+	}
+	if pub.calls[0].Event != domain.GeofenceEntry {
+		t.Errorf("expected geofence_entry, got %s", pub.calls[0].Event)
+	}
+	if pub.calls[1].Event != domain.GeofenceExit {
+		t.Errorf("expected geofence_exit, got %s", pub.calls[1].Event)
+	}
+
+	last := repo.upserts[len(repo.upserts)-1]
+	if last.Inside {
+		t.Error("expected persisted state to be outside")
+	}
+	if !last.EnteredAt.Equal(time.Unix(1715003456, 0)) {
+		t.Errorf("expected entered_at to be kept from entry, got %v", last.EnteredAt)
+	}
+}
+
+func TestCheckAndAlert_PersistedStateSurvivesRestart(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	repo := &mockGeofenceStateRepo{
+		getByVehicleFn: func(_ context.Context, vehicleID string) ([]domain.GeofenceState, error) {
+			return []domain.GeofenceState{
+				{VehicleID: vehicleID, GeofenceID: "a", Inside: true, EnteredAt: time.Unix(1715000000, 0)},
+			}, nil
+		},
+	}
+	geofences := []domain.Geofence{
+		circleFence("a", -6.2088, 106.8456, 50),
+	}
+	svc := newGeofenceService(t, pub, repo, geofences)
+
+	vl := &domain.VehicleLocation{
+		VehicleID: "B1234XYZ",
+		Location: domain.Location{
+			Lat:       -6.2088,
+			Lon:       106.8456,
+			Timestamp: time.Unix(1715003456, 0),
+		},
+	}
+
+	if err := svc.CheckAndAlert(context.Background(), vl); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pub.calls) != 0 {
+		t.Fatalf("expected 0 alerts for vehicle already inside, got %d", len(pub.calls))
+	}
+}
+
+func TestCheckAndAlert_StateLoadError(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	repo := &mockGeofenceStateRepo{
+		getByVehicleFn: func(_ context.Context, _ string) ([]domain.GeofenceState, error) {
+			return nil, errors.New("db error")
+		},
+	}
+	svc := newGeofenceService(t, pub, repo, nil)
+
+	err := svc.CheckAndAlert(context.Background(), &domain.VehicleLocation{VehicleID: "B1234XYZ"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCheckAndAlert_PublishError_StateNotPersisted(t *testing.T) {
+	pub := &mockGeofencePublisher{
+		publishAlertFn: func(_ context.Context, _ *domain.GeofenceAlert) error {
+			return errors.New("rabbitmq down")
+		},
+	}
+	repo := &mockGeofenceStateRepo{}
+	geofences := []domain.Geofence{
+		circleFence("a", -6.2088, 106.8456, 50),
+	}
+	svc := newGeofenceService(t, pub, repo, geofences)
+
+	vl := &domain.VehicleLocation{
+		VehicleID: "B1234XYZ",
+		Location: domain.Location{
+			Lat:       -6.2088,
+			Lon:       106.8456,
+			Timestamp: time.Unix(1715003456, 0),
+		},
+	}
+
+	_ = svc.CheckAndAlert(context.Background(), vl)
+	_ = svc.CheckAndAlert(context.Background(), vl)
+
+	if len(repo.upserts) != 0 {
+		t.Fatalf("expected no state writes, got %d", len(repo.upserts))
+	}
+	if len(pub.calls) != 2 {
+		t.Fatalf("expected entry to be retried, got %d publish attempts", len(pub.calls))
+	}
+}
+
+func TestCheckAndAlert_MixedCircleAndPolygon(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	geofences := []domain.Geofence{
+		circleFence("circle", -6.2088, 106.8456, 50),
+		{
+			ID:    "depot",
+			Shape: domain.ShapePolygon,
+			Polygons: []domain.Polygon{{{
+				{Lat: -6.21, Lon: 106.84},
+				{Lat: -6.21, Lon: 106.85},
+				{Lat: -6.20, Lon: 106.85},
+				{Lat: -6.20, Lon: 106.84},
+			}}},
+		},
+	}
+	svc := newGeofenceService(t, pub, &mockGeofenceStateRepo{}, geofences)
+
+	vl := &domain.VehicleLocation{
+		VehicleID: "B1234XYZ",
+		Location: domain.Location{
+			Lat:       -6.2088,
+			Lon:       106.8456,
+			Timestamp: time.Unix(1715003456, 0),
+		},
+	}
+
+	if err := svc.CheckAndAlert(context.Background(), vl); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pub.calls) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(pub.calls))
+	}
+}
+
+func TestReload_RepoError(t *testing.T) {
+	repo := &mockGeofenceRepo{
+		listFn: func(_ context.Context) ([]domain.Geofence, error) {
+			return nil, errors.New("db error")
+		},
+	}
+	svc := NewGeofenceService(&mockGeofencePublisher{}, repo, &mockGeofenceStateRepo{}, &mockVehicleAssignmentRepo{}, &mockGeofenceVisitRepo{}, GeofenceOptions{})
+
+	if err := svc.Reload(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCreateGeofence_AppliedWithoutReload(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	repo := &mockGeofenceRepo{
+		listFn: func(_ context.Context) ([]domain.Geofence, error) {
+			return nil, nil
+		},
+		createFn: func(_ context.Context, gf *domain.Geofence) error {
+			gf.ID = "new"
+			return nil
+		},
+	}
+	svc := NewGeofenceService(pub, repo, &mockGeofenceStateRepo{}, &mockVehicleAssignmentRepo{}, &mockGeofenceVisitRepo{}, GeofenceOptions{})
+
+	gf := circleFence("", -6.2088, 106.8456, 50)
+	if err := svc.CreateGeofence(context.Background(), &gf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	vl := &domain.VehicleLocation{
+		VehicleID: "B1234XYZ",
+		Location: domain.Location{
+			Lat:       -6.2088,
+			Lon:       106.8456,
+			Timestamp: time.Unix(1715003456, 0),
+		},
+	}
+	if err := svc.CheckAndAlert(context.Background(), vl); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pub.calls) != 1 {
+		t.Fatalf("expected 1 alert from created geofence, got %d", len(pub.calls))
+	}
+}
+
+func TestCreateGeofence_RepoError(t *testing.T) {
+	repo := &mockGeofenceRepo{
+		createFn: func(_ context.Context, _ *domain.Geofence) error {
+			return errors.New("db error")
+		},
+	}
+	svc := NewGeofenceService(&mockGeofencePublisher{}, repo, &mockGeofenceStateRepo{}, &mockVehicleAssignmentRepo{}, &mockGeofenceVisitRepo{}, GeofenceOptions{})
+
+	gf := circleFence("a", -6.2088, 106.8456, 50)
+	if err := svc.CreateGeofence(context.Background(), &gf); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(svc.index.fences) != 0 {
+		t.Fatalf("expected failed create not to be cached, got %d geofences", len(svc.index.fences))
+	}
+}
+
+func TestUpdateGeofence_ReplacesCached(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	svc := newGeofenceService(t, pub, &mockGeofenceStateRepo{}, []domain.Geofence{
+		circleFence("a", -7.0, 107.0, 50),
+	})
+	svc.repo.(*mockGeofenceRepo).updateFn = func(_ context.Context, _ *domain.Geofence) error {
+		return nil
+	}
+
+	moved := circleFence("a", -6.2088, 106.8456, 50)
+	if err := svc.UpdateGeofence(context.Background(), &moved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(svc.index.fences) != 1 || svc.index.fences[0].Circle.Lat != -6.2088 {
+		t.Fatalf("expected cached geofence to be replaced, got %+v", svc.index.fences)
+	}
+}
+
+func TestImportGeofences_CreatesAndUpdates(t *testing.T) {
+	existing := circleFence("a", -7.0, 107.0, 50)
+	existing.Assignment.RouteIDs = []string{"corridor-1"}
+	svc := newGeofenceService(t, &mockGeofencePublisher{}, &mockGeofenceStateRepo{}, []domain.Geofence{existing})
+
+	repo := svc.repo.(*mockGeofenceRepo)
+	var updates []domain.Geofence
+	repo.updateFn = func(_ context.Context, gf *domain.Geofence) error {
+		updates = append(updates, *gf)
+		return nil
+	}
+	repo.createFn = func(_ context.Context, gf *domain.Geofence) error {
+		if gf.ID == "" {
+			gf.ID = "generated"
+		}
+		return nil
+	}
+
+	created, updated, err := svc.ImportGeofences(context.Background(), []domain.Geofence{
+		circleFence("a", -6.2088, 106.8456, 80),
+		circleFence("", -6.3, 106.9, 50),
+		circleFence("b", -6.4, 106.9, 50),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created != 2 || updated != 1 {
+		t.Errorf("expected 2 created and 1 updated, got %d and %d", created, updated)
+	}
+	if len(updates) != 1 || len(updates[0].Assignment.RouteIDs) != 1 {
+		t.Errorf("expected update to keep the existing assignment, got %+v", updates)
+	}
+
+	if len(svc.index.fences) != 3 || svc.index.fences[0].Circle.Radius != 80 || svc.index.fences[1].ID != "generated" {
+		t.Fatalf("unexpected cached geofences: %+v", svc.index.fences)
+	}
+}
+
+func TestImportGeofences_KeepsWrittenOnError(t *testing.T) {
+	svc := newGeofenceService(t, &mockGeofencePublisher{}, &mockGeofenceStateRepo{}, nil)
+	svc.repo.(*mockGeofenceRepo).createFn = func(_ context.Context, gf *domain.Geofence) error {
+		if gf.ID == "bad" {
+			return errors.New("db down")
+		}
+		return nil
+	}
+
+	created, _, err := svc.ImportGeofences(context.Background(), []domain.Geofence{
+		circleFence("a", -6.2088, 106.8456, 50),
+		circleFence("bad", -6.3, 106.9, 50),
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if created != 1 || len(svc.index.fences) != 1 || svc.index.fences[0].ID != "a" {
+		t.Fatalf("expected the first geofence to be kept, got %d created and %+v", created, svc.index.fences)
+	}
+}
+
+func TestDeleteGeofence_DropsCachedState(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	svc := newGeofenceService(t, pub, &mockGeofenceStateRepo{}, []domain.Geofence{
+		circleFence("a", -6.2088, 106.8456, 50),
+	})
+	svc.repo.(*mockGeofenceRepo).deleteFn = func(_ context.Context, _ string) error {
+		return nil
+	}
+
+	vl := &domain.VehicleLocation{
+		VehicleID: "B1234XYZ",
+		Location: domain.Location{
+			Lat:       -6.2088,
+			Lon:       106.8456,
+			Timestamp: time.Unix(1715003456, 0),
+		},
+	}
+	if err := svc.CheckAndAlert(context.Background(), vl); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := svc.DeleteGeofence(context.Background(), "a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(svc.index.fences) != 0 {
+		t.Fatalf("expected geofence to be removed, got %d", len(svc.index.fences))
+	}
+	if _, ok := svc.states["B1234XYZ"]["a"]; ok {
+		t.Fatal("expected cached state for deleted geofence to be dropped")
+	}
+}
+
+func TestCheckAndAlert_DwellAlertOnce(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	repo := &mockGeofenceStateRepo{}
+	gf := circleFence("terminal", -6.2088, 106.8456, 50)
+	gf.DwellThreshold = 10 * time.Minute
+	svc := newGeofenceService(t, pub, repo, []domain.Geofence{gf})
+
+	// enter, stay 5m, 10m, 15m, leave, re-enter and stay 11m
+	steps := []struct {
+		offset time.Duration
+		inside bool
+	}{
+		{0, true},
+		{5 * time.Minute, true},
+		{10 * time.Minute, true},
+		{15 * time.Minute, true},
+		{16 * time.Minute, false},
+		{20 * time.Minute, true},
+		{31 * time.Minute, true},
+	}
+	start := time.Unix(1715003456, 0)
+	for _, step := range steps {
+		loc := domain.Location{Lat: -7.0, Lon: 107.0, Timestamp: start.Add(step.offset)}
+		if step.inside {
+			loc.Lat, loc.Lon = -6.2088, 106.8456
+		}
+		if err := svc.CheckAndAlert(context.Background(), &domain.VehicleLocation{VehicleID: "B1234XYZ", Location: loc}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	want := []domain.GeofenceEventType{
+		domain.GeofenceEntry,
+		domain.GeofenceDwell,
+		domain.GeofenceExit,
+		domain.GeofenceEntry,
+		domain.GeofenceDwell,
+	}
+	if len(pub.calls) != len(want) {
+		t.Fatalf("expected %d alerts, got %d", len(want), len(pub.calls))
+	}
+	for i, event := range want {
+		if pub.calls[i].Event != event {
+			t.Errorf("alert %d: expected %s, got %s", i, event, pub.calls[i].Event)
+		}
+	}
+	if pub.calls[1].Dwell != 10*time.Minute {
+		t.Errorf("expected first dwell of 10m, got %v", pub.calls[1].Dwell)
+	}
+	if pub.calls[4].Dwell != 11*time.Minute {
+		t.Errorf("expected second dwell of 11m, got %v", pub.calls[4].Dwell)
+	}
+}
+
+func TestCheckAndAlert_NoDwellWithoutThreshold(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	svc := newGeofenceService(t, pub, &mockGeofenceStateRepo{}, []domain.Geofence{
+		circleFence("a", -6.2088, 106.8456, 50),
+	})
+
+	start := time.Unix(1715003456, 0)
+	for _, offset := range []time.Duration{0, time.Hour, 24 * time.Hour} {
+		vl := &domain.VehicleLocation{
+			VehicleID: "B1234XYZ",
+			Location:  domain.Location{Lat: -6.2088, Lon: 106.8456, Timestamp: start.Add(offset)},
+		}
+		if err := svc.CheckAndAlert(context.Background(), vl); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(pub.calls) != 1 {
+		t.Fatalf("expected only the entry alert, got %d", len(pub.calls))
+	}
+}
+
+func TestCheckAndAlert_ScopedGeofences(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	global := circleFence("global", -6.2088, 106.8456, 50)
+	byVehicle := circleFence("vehicle", -6.2088, 106.8456, 50)
+	byVehicle.Assignment.VehicleIDs = []string{"B1234XYZ"}
+	byGroup := circleFence("group", -6.2088, 106.8456, 50)
+	byGroup.Assignment.GroupIDs = []string{"articulated"}
+	byRoute := circleFence("route", -6.2088, 106.8456, 50)
+	byRoute.Assignment.RouteIDs = []string{"corridor-1"}
+	svc := newGeofenceService(t, pub, &mockGeofenceStateRepo{}, []domain.Geofence{global, byVehicle, byGroup, byRoute})
+
+	ctx := context.Background()
+	if err := svc.SetVehicleAssignment(ctx, &domain.VehicleAssignment{VehicleID: "B5678ABC", GroupIDs: []string{"articulated"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetVehicleAssignment(ctx, &domain.VehicleAssignment{VehicleID: "B9012DEF", RouteIDs: []string{"corridor-1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"B1234XYZ": {"global", "vehicle"},
+		"B5678ABC": {"global", "group"},
+		"B9012DEF": {"global", "route"},
+		"B0000NEW": {"global"},
+	}
+	for vehicleID, fences := range want {
+		pub.calls = nil
+		vl := fixAt(0, 0)
+		vl.VehicleID = vehicleID
+		if err := svc.CheckAndAlert(ctx, vl); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var got []string
+		for _, alert := range pub.calls {
+			got = append(got, alert.Geofence.ID)
+		}
+		if !slices.Equal(got, fences) {
+			t.Errorf("%s: expected alerts for %v, got %v", vehicleID, fences, got)
+		}
+	}
+}
+
+func TestCheckAndAlert_CorridorDeviation(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	assigned := corridorFence("corridor-1", 30)
+	assigned.Assignment.RouteIDs = []string{"corridor-1"}
+	open := corridorFence("busway", 30)
+	svc := newGeofenceService(t, pub, &mockGeofenceStateRepo{}, []domain.Geofence{assigned, open})
+	visitRepo := svc.visitRepo.(*mockGeofenceVisitRepo)
+
+	ctx := context.Background()
+	if err := svc.SetVehicleAssignment(ctx, &domain.VehicleAssignment{VehicleID: "B1234XYZ", RouteIDs: []string{"corridor-1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, lat := range []float64{-6.2, -6.2 + metersToDegrees(100)} {
+		vl := &domain.VehicleLocation{
+			VehicleID: "B1234XYZ",
+			Location:  domain.Location{Lat: lat, Lon: 106.84, Timestamp: time.Unix(1715003456+int64(i)*60, 0)},
+		}
+		if err := svc.CheckAndAlert(ctx, vl); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	var got []string
+	for _, alert := range pub.calls {
+		got = append(got, alert.Geofence.ID+":"+string(alert.Event))
+	}
+	want := []string{
+		"corridor-1:geofence_entry",
+		"busway:geofence_entry",
+		"corridor-1:route_deviation",
+		"busway:geofence_exit",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(visitRepo.visits) != 2 {
+		t.Errorf("expected a visit for each corridor, got %d", len(visitRepo.visits))
+	}
+}
+
+func TestCheckAndAlert_UnassignedWhileInsideStillExits(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	gf := circleFence("route", -6.2088, 106.8456, 50)
+	gf.Assignment.RouteIDs = []string{"corridor-1"}
+	svc := newGeofenceService(t, pub, &mockGeofenceStateRepo{}, []domain.Geofence{gf})
+
+	ctx := context.Background()
+	if err := svc.SetVehicleAssignment(ctx, &domain.VehicleAssignment{VehicleID: "B1234XYZ", RouteIDs: []string{"corridor-1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CheckAndAlert(ctx, fixAt(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeleteVehicleAssignment(ctx, "B1234XYZ"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CheckAndAlert(ctx, fixAt(200, time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CheckAndAlert(ctx, fixAt(0, 2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pub.calls) != 2 || pub.calls[0].Event != domain.GeofenceEntry || pub.calls[1].Event != domain.GeofenceExit {
+		t.Fatalf("expected entry then exit only, got %d alerts", len(pub.calls))
+	}
+}
+
+func TestVehicleAssignment_LoadedOnce(t *testing.T) {
+	assignRepo := &mockVehicleAssignmentRepo{
+		assignments: map[string]domain.VehicleAssignment{
+			"B1234XYZ": {VehicleID: "B1234XYZ", GroupIDs: []string{"articulated"}},
+		},
+	}
+	repo := &mockGeofenceRepo{
+		listFn: func(_ context.Context) ([]domain.Geofence, error) { return nil, nil },
+	}
+	svc := NewGeofenceService(&mockGeofencePublisher{}, repo, &mockGeofenceStateRepo{}, assignRepo, &mockGeofenceVisitRepo{}, GeofenceOptions{})
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := svc.CheckAndAlert(ctx, fixAt(0, time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	va, err := svc.GetVehicleAssignment(ctx, "B1234XYZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(va.GroupIDs) != 1 || va.GroupIDs[0] != "articulated" {
+		t.Errorf("unexpected assignment: %+v", va)
+	}
+	if assignRepo.getCalls != 1 {
+		t.Errorf("expected assignment to be loaded once, got %d loads", assignRepo.getCalls)
+	}
+}
+
+func TestCheckAndAlert_ExitStoresVisit(t *testing.T) {
+	svc := newGeofenceService(t, &mockGeofencePublisher{}, &mockGeofenceStateRepo{}, []domain.Geofence{
+		circleFence("terminal", -6.2088, 106.8456, 50),
+	})
+	svc.opts = GeofenceOptions{MinFixes: 2}
+	visitRepo := svc.visitRepo.(*mockGeofenceVisitRepo)
+
+	ctx := context.Background()
+	for _, vl := range []*domain.VehicleLocation{
+		fixAt(0, 0),
+		fixAt(0, time.Minute),
+		fixAt(500, 10*time.Minute),
+		fixAt(500, 11*time.Minute),
+	} {
+		if err := svc.CheckAndAlert(ctx, vl); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(visitRepo.visits) != 1 {
+		t.Fatalf("expected 1 visit, got %d", len(visitRepo.visits))
+	}
+	got := visitRepo.visits[0]
+	want := domain.GeofenceVisit{
+		VehicleID:  "B1234XYZ",
+		GeofenceID: "terminal",
+		EnteredAt:  fixAt(0, 0).Location.Timestamp,
+		ExitedAt:   fixAt(0, 10*time.Minute).Location.Timestamp,
+		Duration:   10 * time.Minute,
+	}
+	if !got.EnteredAt.Equal(want.EnteredAt) || !got.ExitedAt.Equal(want.ExitedAt) || got.Duration != want.Duration ||
+		got.VehicleID != want.VehicleID || got.GeofenceID != want.GeofenceID {
+		t.Errorf("unexpected visit:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestCheckAndAlert_VisitError_StateNotPersisted(t *testing.T) {
+	stateRepo := &mockGeofenceStateRepo{}
+	svc := newGeofenceService(t, &mockGeofencePublisher{}, stateRepo, []domain.Geofence{
+		circleFence("terminal", -6.2088, 106.8456, 50),
+	})
+	svc.visitRepo.(*mockGeofenceVisitRepo).insertFn = func(_ context.Context, _ *domain.GeofenceVisit) error {
+		return errors.New("db down")
+	}
+
+	ctx := context.Background()
+	if err := svc.CheckAndAlert(ctx, fixAt(0, 0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.CheckAndAlert(ctx, fixAt(500, time.Minute)); err == nil {
+		t.Fatal("expected error")
+	}
+	if last := stateRepo.upserts[len(stateRepo.upserts)-1]; !last.Inside {
+		t.Error("expected the exit not to be persisted")
+	}
+}
+
+func TestGeofenceVisits(t *testing.T) {
+	svc := newGeofenceService(t, &mockGeofencePublisher{}, &mockGeofenceStateRepo{}, []domain.Geofence{
+		circleFence("terminal", -6.2088, 106.8456, 50),
+	})
+	visitRepo := svc.visitRepo.(*mockGeofenceVisitRepo)
+
+	ctx := context.Background()
+	start, end := time.Unix(1715000000, 0), time.Unix(1715086400, 0)
+	if _, err := svc.GeofenceVisits(ctx, "terminal", start, end); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.VehicleVisits(ctx, "B1234XYZ", start, end); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.GeofenceVisits(ctx, "missing", start, end); !errors.Is(err, domain.ErrGeofenceNotFound) {
+		t.Errorf("expected ErrGeofenceNotFound, got %v", err)
+	}
+
+	want := []domain.VisitQuery{
+		{GeofenceID: "terminal", Start: start, End: end},
+		{VehicleID: "B1234XYZ", Start: start, End: end},
+	}
+	if !slices.Equal(visitRepo.queries, want) {
+		t.Errorf("unexpected queries: %+v", visitRepo.queries)
+	}
+}
+
+func TestVehiclesInside(t *testing.T) {
+	stateRepo := &mockGeofenceStateRepo{}
+	svc := newGeofenceService(t, &mockGeofencePublisher{}, stateRepo, []domain.Geofence{
+		circleFence("terminal", -6.2088, 106.8456, 50),
+	})
+
+	ctx := context.Background()
+	for i, vehicleID := range []string{"B1234XYZ", "B5678ABC", "B9012DEF"} {
+		vl := fixAt(0, time.Duration(i)*time.Minute)
+		vl.VehicleID = vehicleID
+		if err := svc.CheckAndAlert(ctx, vl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	left := fixAt(500, 5*time.Minute)
+	left.VehicleID = "B5678ABC"
+	if err := svc.CheckAndAlert(ctx, left); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := svc.VehiclesInside(ctx, "terminal")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[0].VehicleID != "B1234XYZ" || results[1].VehicleID != "B9012DEF" {
+		t.Fatalf("unexpected occupants: %+v", results)
+	}
+	if results[1].Geofence.ID != "terminal" || !results[1].EnteredAt.Equal(fixAt(0, 2*time.Minute).Location.Timestamp) {
+		t.Errorf("unexpected presence: %+v", results[1])
+	}
+
+	if _, err := svc.VehiclesInside(ctx, "missing"); !errors.Is(err, domain.ErrGeofenceNotFound) {
+		t.Errorf("expected ErrGeofenceNotFound, got %v", err)
+	}
+}
+
+func TestGeofencesContaining(t *testing.T) {
+	entered := time.Unix(1715003456, 0)
+	stateRepo := &mockGeofenceStateRepo{
+		getByVehicleFn: func(_ context.Context, vehicleID string) ([]domain.GeofenceState, error) {
+			return []domain.GeofenceState{
+				{VehicleID: vehicleID, GeofenceID: "depot", Inside: true, EnteredAt: entered.Add(time.Minute)},
+				{VehicleID: vehicleID, GeofenceID: "terminal", Inside: true, EnteredAt: entered},
+				{VehicleID: vehicleID, GeofenceID: "school", Inside: false},
+				{VehicleID: vehicleID, GeofenceID: "deleted", Inside: true, EnteredAt: entered},
+			}, nil
+		},
+	}
+	svc := newGeofenceService(t, &mockGeofencePublisher{}, stateRepo, []domain.Geofence{
+		circleFence("depot", -6.2088, 106.8456, 50),
+		circleFence("school", -6.2088, 106.8456, 50),
+		circleFence("terminal", -6.2088, 106.8456, 500),
+	})
+
+	results, err := svc.GeofencesContaining(context.Background(), "B1234XYZ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[0].Geofence.ID != "terminal" || results[1].Geofence.ID != "depot" {
+		t.Fatalf("unexpected geofences: %+v", results)
+	}
+	if results[0].Geofence.Circle.Radius != 500 {
+		t.Errorf("expected geofence definition to be attached, got %+v", results[0].Geofence)
+	}
+}
+
+func TestSimulate_Track(t *testing.T) {
+	pub := &mockGeofencePublisher{}
+	stateRepo := &mockGeofenceStateRepo{}
+	terminal := circleFence("terminal", -6.2088, 106.8456, 50)
+	terminal.DwellThreshold = 5 * time.Minute
+	scoped := circleFence("scoped", -6.2088, 106.8456, 50)
+	scoped.Assignment.VehicleIDs = []string{"B9999ZZZ"}
+	svc := newGeofenceService(t, pub, stateRepo, []domain.Geofence{terminal, scoped})
+
+	var track []domain.Location
+	for _, vl := range []*domain.VehicleLocation{
+		fixAt(500, 0),
+		fixAt(0, time.Minute),
+		fixAt(0, 7*time.Minute),
+		fixAt(500, 8*time.Minute),
+	} {
+		track = append(track, vl.Location)
+	}
+
+	steps, err := svc.Simulate(context.Background(), "B1234XYZ", track)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(steps) != 4 {
+		t.Fatalf("expected 4 steps, got %d", len(steps))
+	}
+
+	want := []domain.GeofenceEventType{"", domain.GeofenceEntry, domain.GeofenceDwell, domain.GeofenceExit}
+	for i, step := range steps {
+		var got domain.GeofenceEventType
+		if len(step.Alerts) > 1 {
+			t.Fatalf("step %d: expected at most one alert, got %+v", i, step.Alerts)
+		}
+		if len(step.Alerts) == 1 {
+			got = step.Alerts[0].Event
+		}
+		if got != want[i] {
+			t.Errorf("step %d: expected %q, got %q", i, want[i], got)
+		}
+	}
+	if len(steps[1].Inside) != 1 || steps[1].Inside[0] != "terminal" || len(steps[3].Inside) != 0 {
+		t.Errorf("unexpected containment: %v, %v", steps[1].Inside, steps[3].Inside)
+	}
+	if steps[2].Alerts[0].Dwell != 6*time.Minute {
+		t.Errorf("expected 6m dwell, got %v", steps[2].Alerts[0].Dwell)
+	}
+
+	if len(pub.calls) != 0 || len(stateRepo.upserts) != 0 {
+		t.Errorf("expected simulation to publish and persist nothing, got %d alerts and %d upserts", len(pub.calls), len(stateRepo.upserts))
+	}
+}
+
+func TestSimulate_WithoutVehicleUsesAllGeofences(t *testing.T) {
+	scoped := circleFence("scoped", -6.2088, 106.8456, 50)
+	scoped.Assignment.RouteIDs = []string{"corridor-1"}
+	svc := newGeofenceService(t, &mockGeofencePublisher{}, &mockGeofenceStateRepo{}, []domain.Geofence{scoped})
+
+	steps, err := svc.Simulate(context.Background(), "", []domain.Location{fixAt(0, 0).Location})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(steps[0].Inside) != 1 || len(steps[0].Alerts) != 1 {
+		t.Errorf("expected scoped geofence to be simulated, got %+v", steps[0])
+	}
+
+	steps, err = svc.Simulate(context.Background(), "B1234XYZ", []domain.Location{fixAt(0, 0).Location})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(steps[0].Inside) != 0 || len(steps[0].Alerts) != 0 {
+		t.Errorf("expected unassigned vehicle to skip scoped geofence, got %+v", steps[0])
+	}
+}
+
 func TestHaversine(t *testing.T) {
 	// same point should be 0
 	d := haversine(-6.2088, 106.8456, -6.2088, 106.8456)
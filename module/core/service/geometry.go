@@ -0,0 +1,233 @@
+package service
+
+import (
+	"math"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+const earthRadiusMeters = 6371000
+
+func contains(gf *domain.Geofence, lat, lon float64) bool {
+	switch gf.Shape {
+	case domain.ShapeCircle:
+		if gf.Circle == nil {
+			return false
+		}
+		return haversine(lat, lon, gf.Circle.Lat, gf.Circle.Lon) <= gf.Circle.Radius
+	case domain.ShapePolygon:
+		for _, p := range gf.Polygons {
+			if polygonContains(p, lat, lon) {
+				return true
+			}
+		}
+	case domain.ShapeCorridor:
+		if gf.Corridor == nil {
+			return false
+		}
+		return lineDistance(gf.Corridor.Line, lat, lon) <= gf.Corridor.Buffer
+	}
+	return false
+}
+
+// boundaryDistance returns the distance in meters from the point to the
+// geofence boundary, negative when the point is inside.
+func boundaryDistance(gf *domain.Geofence, lat, lon float64) float64 {
+	switch gf.Shape {
+	case domain.ShapeCircle:
+		if gf.Circle == nil {
+			return math.Inf(1)
+		}
+		return haversine(lat, lon, gf.Circle.Lat, gf.Circle.Lon) - gf.Circle.Radius
+	case domain.ShapePolygon:
+		inside := false
+		nearest := math.Inf(1)
+		for _, p := range gf.Polygons {
+			if polygonContains(p, lat, lon) {
+				inside = true
+			}
+			for _, ring := range p {
+				for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+					nearest = math.Min(nearest, segmentDistance(lat, lon, ring[j], ring[i]))
+				}
+			}
+		}
+		if inside {
+			return -nearest
+		}
+		return nearest
+	case domain.ShapeCorridor:
+		if gf.Corridor == nil {
+			return math.Inf(1)
+		}
+		return lineDistance(gf.Corridor.Line, lat, lon) - gf.Corridor.Buffer
+	}
+	return math.Inf(1)
+}
+
+// lineDistance returns the distance in meters from the point to the nearest
+// segment of the polyline.
+func lineDistance(line []domain.Coordinate, lat, lon float64) float64 {
+	switch len(line) {
+	case 0:
+		return math.Inf(1)
+	case 1:
+		return segmentDistance(lat, lon, line[0], line[0])
+	}
+	nearest := math.Inf(1)
+	for i := 1; i < len(line); i++ {
+		nearest = math.Min(nearest, segmentDistance(lat, lon, line[i-1], line[i]))
+	}
+	return nearest
+}
+
+// segmentDistance returns the distance in meters from the point to the segment
+// a-b, using an equirectangular projection centred on the point. It is accurate
+// for the few-kilometre scales geofence edges work at.
+func segmentDistance(lat, lon float64, a, b domain.Coordinate) float64 {
+	ky := earthRadiusMeters * math.Pi / 180
+	kx := ky * math.Cos(toRad(lat))
+	ax, ay := (a.Lon-lon)*kx, (a.Lat-lat)*ky
+	bx, by := (b.Lon-lon)*kx, (b.Lat-lat)*ky
+
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/l))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+// bounds returns the geofence's bounding box in degrees. ok is false for a
+// fence with no usable geometry.
+func bounds(gf *domain.Geofence) (minLat, minLon, maxLat, maxLon float64, ok bool) {
+	switch gf.Shape {
+	case domain.ShapeCircle:
+		if gf.Circle == nil {
+			return 0, 0, 0, 0, false
+		}
+		dLat := metersToDegrees(gf.Circle.Radius)
+		dLon := 360.0
+		if c := math.Cos(toRad(gf.Circle.Lat)); c > 1e-9 {
+			dLon = math.Min(dLat/c, 360)
+		}
+		return gf.Circle.Lat - dLat, gf.Circle.Lon - dLon, gf.Circle.Lat + dLat, gf.Circle.Lon + dLon, true
+	case domain.ShapePolygon:
+		minLat, minLon = math.Inf(1), math.Inf(1)
+		maxLat, maxLon = math.Inf(-1), math.Inf(-1)
+		for _, p := range gf.Polygons {
+			if len(p) == 0 {
+				continue
+			}
+			for _, c := range p[0] {
+				minLat, maxLat = math.Min(minLat, c.Lat), math.Max(maxLat, c.Lat)
+				minLon, maxLon = math.Min(minLon, c.Lon), math.Max(maxLon, c.Lon)
+			}
+		}
+		return minLat, minLon, maxLat, maxLon, minLat <= maxLat
+	case domain.ShapeCorridor:
+		if gf.Corridor == nil || len(gf.Corridor.Line) == 0 {
+			return 0, 0, 0, 0, false
+		}
+		minLat, minLon = math.Inf(1), math.Inf(1)
+		maxLat, maxLon = math.Inf(-1), math.Inf(-1)
+		for _, c := range gf.Corridor.Line {
+			minLat, maxLat = math.Min(minLat, c.Lat), math.Max(maxLat, c.Lat)
+			minLon, maxLon = math.Min(minLon, c.Lon), math.Max(maxLon, c.Lon)
+		}
+		// pad by the buffer, widening longitude at the latitude furthest from
+		// the equator where a degree is shortest
+		dLat := metersToDegrees(gf.Corridor.Buffer)
+		dLon := 360.0
+		if c := math.Cos(toRad(math.Max(math.Abs(minLat), math.Abs(maxLat)))); c > 1e-9 {
+			dLon = math.Min(dLat/c, 360)
+		}
+		return minLat - dLat, minLon - dLon, maxLat + dLat, maxLon + dLon, true
+	}
+	return 0, 0, 0, 0, false
+}
+
+// metersToDegrees converts a north-south distance to degrees of latitude.
+func metersToDegrees(m float64) float64 {
+	return m / (earthRadiusMeters * math.Pi / 180)
+}
+
+// centerDistance returns the distance in meters from the point to the centre of
+// the geofence. Polygon centres are the mean of their outer ring vertices and a
+// corridor's centre is its centreline.
+func centerDistance(gf *domain.Geofence, lat, lon float64) float64 {
+	switch gf.Shape {
+	case domain.ShapeCircle:
+		if gf.Circle != nil {
+			return haversine(lat, lon, gf.Circle.Lat, gf.Circle.Lon)
+		}
+	case domain.ShapePolygon:
+		var sumLat, sumLon float64
+		var n int
+		for _, p := range gf.Polygons {
+			if len(p) == 0 {
+				continue
+			}
+			ring := p[0]
+			if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+				ring = ring[:len(ring)-1]
+			}
+			for _, c := range ring {
+				sumLat += c.Lat
+				sumLon += c.Lon
+				n++
+			}
+		}
+		if n > 0 {
+			return haversine(lat, lon, sumLat/float64(n), sumLon/float64(n))
+		}
+	case domain.ShapeCorridor:
+		if gf.Corridor != nil && len(gf.Corridor.Line) > 0 {
+			return lineDistance(gf.Corridor.Line, lat, lon)
+		}
+	}
+	return 0
+}
+
+// polygonContains reports whether the point lies inside the outer ring and
+// outside every hole.
+func polygonContains(p domain.Polygon, lat, lon float64) bool {
+	if len(p) == 0 || !ringContains(p[0], lat, lon) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if ringContains(hole, lat, lon) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains is an even-odd ray cast on the lon/lat plane. Rings may be
+// given open or closed (first vertex repeated at the end).
+func ringContains(ring []domain.Coordinate, lat, lon float64) bool {
+	if len(ring) < 3 {
+		return false
+	}
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > lat) != (b.Lat > lat) &&
+			lon < (b.Lon-a.Lon)*(lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			in = !in
+		}
+	}
+	return in
+}
+
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return earthRadiusMeters * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+func toRad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+func square(minLat, minLon, maxLat, maxLon float64) []domain.Coordinate {
+	return []domain.Coordinate{
+		{Lat: minLat, Lon: minLon},
+		{Lat: minLat, Lon: maxLon},
+		{Lat: maxLat, Lon: maxLon},
+		{Lat: maxLat, Lon: minLon},
+		{Lat: minLat, Lon: minLon},
+	}
+}
+
+// corridorFence runs east along latitude -6.2 then turns north at 106.85.
+func corridorFence(id string, buffer float64) domain.Geofence {
+	return domain.Geofence{
+		ID:    id,
+		Shape: domain.ShapeCorridor,
+		Corridor: &domain.Corridor{
+			Line:   []domain.Coordinate{{Lat: -6.2, Lon: 106.83}, {Lat: -6.2, Lon: 106.85}, {Lat: -6.18, Lon: 106.85}},
+			Buffer: buffer,
+		},
+	}
+}
+
+func TestContains_Circle(t *testing.T) {
+	gf := circleFence("a", -6.2088, 106.8456, 50)
+
+	if !contains(&gf, -6.2088, 106.8456) {
+		t.Error("expected centre to be inside")
+	}
+	if contains(&gf, -6.2100, 106.8456) {
+		t.Error("expected point ~133m away to be outside")
+	}
+}
+
+func TestContains_Polygon(t *testing.T) {
+	gf := domain.Geofence{
+		ID:       "depot",
+		Shape:    domain.ShapePolygon,
+		Polygons: []domain.Polygon{{square(-6.21, 106.84, -6.20, 106.85)}},
+	}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     bool
+	}{
+		{"inside", -6.205, 106.845, true},
+		{"west of polygon", -6.205, 106.83, false},
+		{"north of polygon", -6.19, 106.845, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contains(&gf, tt.lat, tt.lon); got != tt.want {
+				t.Errorf("contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContains_PolygonWithHole(t *testing.T) {
+	gf := domain.Geofence{
+		ID:    "terminal",
+		Shape: domain.ShapePolygon,
+		Polygons: []domain.Polygon{{
+			square(-6.21, 106.84, -6.20, 106.85),
+			square(-6.206, 106.844, -6.204, 106.846),
+		}},
+	}
+
+	if contains(&gf, -6.205, 106.845) {
+		t.Error("expected point in hole to be outside")
+	}
+	if !contains(&gf, -6.209, 106.841) {
+		t.Error("expected point between hole and boundary to be inside")
+	}
+}
+
+func TestContains_MultiPolygon(t *testing.T) {
+	gf := domain.Geofence{
+		ID:    "corridor-stops",
+		Shape: domain.ShapePolygon,
+		Polygons: []domain.Polygon{
+			{square(-6.21, 106.84, -6.20, 106.85)},
+			{square(-6.31, 106.94, -6.30, 106.95)},
+		},
+	}
+
+	if !contains(&gf, -6.305, 106.945) {
+		t.Error("expected point in second polygon to be inside")
+	}
+	if contains(&gf, -6.25, 106.90) {
+		t.Error("expected point between polygons to be outside")
+	}
+}
+
+func TestContains_OpenRing(t *testing.T) {
+	ring := square(-6.21, 106.84, -6.20, 106.85)
+	gf := domain.Geofence{
+		ID:       "open",
+		Shape:    domain.ShapePolygon,
+		Polygons: []domain.Polygon{{ring[:len(ring)-1]}},
+	}
+
+	if !contains(&gf, -6.205, 106.845) {
+		t.Error("expected open ring to behave like closed ring")
+	}
+}
+
+func TestContains_DegeneratePolygon(t *testing.T) {
+	gf := domain.Geofence{
+		ID:       "bad",
+		Shape:    domain.ShapePolygon,
+		Polygons: []domain.Polygon{{{{Lat: -6.2, Lon: 106.8}, {Lat: -6.3, Lon: 106.9}}}},
+	}
+
+	if contains(&gf, -6.25, 106.85) {
+		t.Error("expected ring with fewer than 3 vertices to contain nothing")
+	}
+}
+
+func TestCenterDistance(t *testing.T) {
+	circle := circleFence("a", -6.2088, 106.8456, 50)
+	if d := centerDistance(&circle, -6.2088, 106.8456); d != 0 {
+		t.Errorf("expected 0 at circle centre, got %f", d)
+	}
+
+	polygon := domain.Geofence{
+		ID:       "depot",
+		Shape:    domain.ShapePolygon,
+		Polygons: []domain.Polygon{{square(-6.21, 106.84, -6.20, 106.85)}},
+	}
+	if d := centerDistance(&polygon, -6.205, 106.845); d > 1 {
+		t.Errorf("expected ~0 at polygon centre, got %f", d)
+	}
+	if d := centerDistance(&polygon, -6.21, 106.845); d < 500 || d > 600 {
+		t.Errorf("expected ~556m from polygon centre to southern edge, got %f", d)
+	}
+}
+
+func TestBoundaryDistance_Circle(t *testing.T) {
+	gf := circleFence("a", -6.2088, 106.8456, 50)
+
+	if d := boundaryDistance(&gf, -6.2088, 106.8456); d != -50 {
+		t.Errorf("expected -50 at centre, got %f", d)
+	}
+	if d := boundaryDistance(&gf, -6.2088+metersToDegrees(80), 106.8456); d < 29.9 || d > 30.1 {
+		t.Errorf("expected ~30m outside, got %f", d)
+	}
+}
+
+func TestBoundaryDistance_Polygon(t *testing.T) {
+	gf := domain.Geofence{
+		ID:    "terminal",
+		Shape: domain.ShapePolygon,
+		Polygons: []domain.Polygon{{
+			square(-6.21, 106.84, -6.20, 106.85),
+			square(-6.206, 106.844, -6.204, 106.846),
+		}},
+	}
+
+	// 0.0005 degrees of latitude is ~55.6m
+	if d := boundaryDistance(&gf, -6.2095, 106.842); d > -55 || d < -56 {
+		t.Errorf("expected ~-55.6m near southern edge, got %f", d)
+	}
+	if d := boundaryDistance(&gf, -6.2105, 106.842); d < 55 || d > 56 {
+		t.Errorf("expected ~55.6m south of polygon, got %f", d)
+	}
+	// centre of the hole is outside, ~111m from the hole edges
+	if d := boundaryDistance(&gf, -6.205, 106.845); d < 110 || d > 112 {
+		t.Errorf("expected ~111m outside inside the hole, got %f", d)
+	}
+}
+
+func TestContains_Corridor(t *testing.T) {
+	gf := corridorFence("corridor-1", 30)
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     bool
+	}{
+		{"on the line", -6.2, 106.84, true},
+		{"within buffer of first leg", -6.2 + metersToDegrees(20), 106.84, true},
+		{"beyond buffer of first leg", -6.2 - metersToDegrees(40), 106.84, false},
+		{"within buffer of second leg", -6.19, 106.85 + metersToDegrees(25), true},
+		{"past the end of the line", -6.2, 106.83 - metersToDegrees(40), false},
+	}
+	for _, tt := range tests {
+		if got := contains(&gf, tt.lat, tt.lon); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestBoundaryDistance_Corridor(t *testing.T) {
+	gf := corridorFence("corridor-1", 30)
+
+	if d := boundaryDistance(&gf, -6.2, 106.84); d < -30.01 || d > -29.99 {
+		t.Errorf("expected -30 on the centreline, got %f", d)
+	}
+	if d := boundaryDistance(&gf, -6.2+metersToDegrees(100), 106.84); d < 69.9 || d > 70.1 {
+		t.Errorf("expected ~70m outside, got %f", d)
+	}
+	if d := centerDistance(&gf, -6.2+metersToDegrees(100), 106.84); d < 99.9 || d > 100.1 {
+		t.Errorf("expected ~100m from the centreline, got %f", d)
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"math"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+const (
+	// indexCellDegrees is the side of a grid cell, roughly 1.1km at the equator.
+	indexCellDegrees = 0.01
+	// maxIndexCells caps how many cells a single geofence is bucketed into.
+	// Larger fences are kept in a separate list that every lookup checks.
+	maxIndexCells = 4096
+)
+
+type gridCell struct {
+	x, y int32
+}
+
+// geofenceIndex buckets geofences into a fixed lat/lon grid by their bounding
+// boxes so a lookup only runs exact containment checks on nearby fences.
+// It is immutable once built; the service rebuilds it whenever fences change.
+type geofenceIndex struct {
+	fences []domain.Geofence
+	byID   map[string]int
+	cells  map[gridCell][]int
+	wide   []int
+}
+
+func newGeofenceIndex(fences []domain.Geofence) *geofenceIndex {
+	ix := &geofenceIndex{
+		fences: fences,
+		byID:   make(map[string]int, len(fences)),
+		cells:  make(map[gridCell][]int),
+	}
+
+	for i := range fences {
+		ix.byID[fences[i].ID] = i
+
+		minLat, minLon, maxLat, maxLon, ok := bounds(&fences[i])
+		if !ok {
+			continue
+		}
+
+		lo, hi := cellOf(minLat, minLon), cellOf(maxLat, maxLon)
+		if int64(hi.x-lo.x+1)*int64(hi.y-lo.y+1) > maxIndexCells {
+			ix.wide = append(ix.wide, i)
+			continue
+		}
+		for x := lo.x; x <= hi.x; x++ {
+			for y := lo.y; y <= hi.y; y++ {
+				c := gridCell{x: x, y: y}
+				ix.cells[c] = append(ix.cells[c], i)
+			}
+		}
+	}
+	return ix
+}
+
+// candidates returns, in ascending order, the indexes of every geofence whose
+// bounding box may contain the point.
+func (ix *geofenceIndex) candidates(lat, lon float64) []int {
+	cell := ix.cells[cellOf(lat, lon)]
+	if len(ix.wide) == 0 {
+		return cell
+	}
+
+	out := make([]int, 0, len(cell)+len(ix.wide))
+	i, j := 0, 0
+	for i < len(cell) && j < len(ix.wide) {
+		if cell[i] < ix.wide[j] {
+			out = append(out, cell[i])
+			i++
+		} else {
+			out = append(out, ix.wide[j])
+			j++
+		}
+	}
+	out = append(out, cell[i:]...)
+	return append(out, ix.wide[j:]...)
+}
+
+func cellOf(lat, lon float64) gridCell {
+	return gridCell{
+		x: int32(math.Floor(lon / indexCellDegrees)),
+		y: int32(math.Floor(lat / indexCellDegrees)),
+	}
+}
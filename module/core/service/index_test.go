@@ -0,0 +1,150 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+// randomFences scatters n circle, polygon and corridor geofences around
+// Jakarta.
+func randomFences(r *rand.Rand, n int) []domain.Geofence {
+	fences := make([]domain.Geofence, n)
+	for i := range fences {
+		id := fmt.Sprintf("gf-%d", i)
+		lat := -6.4 + r.Float64()*0.4
+		lon := 106.6 + r.Float64()*0.4
+		if i%4 == 0 {
+			d := 0.001 + r.Float64()*0.002
+			fences[i] = domain.Geofence{
+				ID:       id,
+				Shape:    domain.ShapePolygon,
+				Polygons: []domain.Polygon{{square(lat, lon, lat+d, lon+d)}},
+			}
+			continue
+		}
+		if i%4 == 1 {
+			fences[i] = domain.Geofence{
+				ID:    id,
+				Shape: domain.ShapeCorridor,
+				Corridor: &domain.Corridor{
+					Line:   []domain.Coordinate{{Lat: lat, Lon: lon}, {Lat: lat + r.Float64()*0.02, Lon: lon + r.Float64()*0.02}},
+					Buffer: 10 + r.Float64()*40,
+				},
+			}
+			continue
+		}
+		fences[i] = circleFence(id, lat, lon, 50+r.Float64()*250)
+	}
+	return fences
+}
+
+func linearContaining(fences []domain.Geofence, lat, lon float64) []int {
+	var out []int
+	for i := range fences {
+		if contains(&fences[i], lat, lon) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func indexedContaining(ix *geofenceIndex, lat, lon float64) []int {
+	var out []int
+	for _, i := range ix.candidates(lat, lon) {
+		if contains(&ix.fences[i], lat, lon) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func TestGeofenceIndex_MatchesLinearScan(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	fences := randomFences(r, 2000)
+	ix := newGeofenceIndex(fences)
+
+	for n := 0; n < 5000; n++ {
+		lat := -6.4 + r.Float64()*0.4
+		lon := 106.6 + r.Float64()*0.4
+		want := linearContaining(fences, lat, lon)
+		got := indexedContaining(ix, lat, lon)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("point (%f, %f): indexed %v, linear %v", lat, lon, got, want)
+		}
+	}
+}
+
+func TestGeofenceIndex_WideFenceAlwaysCandidate(t *testing.T) {
+	fences := []domain.Geofence{
+		circleFence("near", -6.2088, 106.8456, 50),
+		circleFence("province", -6.2, 106.8, 200000),
+	}
+	ix := newGeofenceIndex(fences)
+
+	if len(ix.wide) != 1 || ix.wide[0] != 1 {
+		t.Fatalf("expected province to be a wide fence, got %v", ix.wide)
+	}
+	got := ix.candidates(-6.2088, 106.8456)
+	if !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Errorf("expected [0 1], got %v", got)
+	}
+	got = ix.candidates(-6.9, 107.3)
+	if !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("expected [1], got %v", got)
+	}
+}
+
+func TestGeofenceIndex_FenceSpanningCells(t *testing.T) {
+	// straddles the cell boundary at longitude 106.85
+	fences := []domain.Geofence{circleFence("edge", -6.205, 106.85, 300)}
+	ix := newGeofenceIndex(fences)
+
+	if len(ix.candidates(-6.205, 106.8495)) != 1 || len(ix.candidates(-6.205, 106.8505)) != 1 {
+		t.Fatal("expected fence in both neighbouring cells")
+	}
+}
+
+func benchmarkPoints(r *rand.Rand, n int) [][2]float64 {
+	points := make([][2]float64, n)
+	for i := range points {
+		points[i] = [2]float64{-6.4 + r.Float64()*0.4, 106.6 + r.Float64()*0.4}
+	}
+	return points
+}
+
+func BenchmarkContaining_Linear(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	fences := randomFences(r, 5000)
+	points := benchmarkPoints(r, 1024)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := points[i%len(points)]
+		linearContaining(fences, p[0], p[1])
+	}
+}
+
+func BenchmarkContaining_Indexed(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	ix := newGeofenceIndex(randomFences(r, 5000))
+	points := benchmarkPoints(r, 1024)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := points[i%len(points)]
+		indexedContaining(ix, p[0], p[1])
+	}
+}
+
+func BenchmarkGeofenceIndex_Build(b *testing.B) {
+	fences := randomFences(rand.New(rand.NewSource(1)), 5000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newGeofenceIndex(fences)
+	}
+}
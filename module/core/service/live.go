@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
+)
+
+// livePositions tracks the newest timestamp seen for each vehicle to tell
+// live locations from late ones, such as fixes a bus buffered while offline
+// and uploads after reconnecting.
+type livePositions struct {
+	repo      database.LocationRepository
+	tolerance time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newLivePositions(repo database.LocationRepository, tolerance time.Duration) *livePositions {
+	return &livePositions{
+		repo:      repo,
+		tolerance: tolerance,
+		last:      make(map[string]time.Time),
+	}
+}
+
+// newest returns the vehicle's newest known timestamp. The first time a
+// vehicle is seen its newest stored location is looked up, so late uploads are
+// still recognised after a restart.
+func (p *livePositions) newest(ctx context.Context, vehicleID string) time.Time {
+	p.mu.Lock()
+	last, ok := p.last[vehicleID]
+	p.mu.Unlock()
+	if ok {
+		return last
+	}
+
+	stored := p.stored(ctx, vehicleID)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cur, ok := p.last[vehicleID]; ok && !cur.Before(stored) {
+		return cur
+	}
+	p.last[vehicleID] = stored
+	return stored
+}
+
+// isLive reports whether a location at ts is live: not older than the
+// vehicle's newest timestamp last by more than the tolerance.
+func (p *livePositions) isLive(last, ts time.Time) bool {
+	return !ts.Before(last.Add(-p.tolerance))
+}
+
+// advance makes ts the vehicle's newest timestamp if it is newer. A location
+// saved synchronously advances it only once stored, so a failed save that is
+// retried is still judged against the timestamps before it. A location queued
+// on the batched writer advances it optimistically once queued, so the
+// locations after it are judged against it before its batch is written; if
+// the writer drops it, retreat takes the advance back.
+func (p *livePositions) advance(vehicleID string, ts time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ts.After(p.last[vehicleID]) {
+		p.last[vehicleID] = ts
+	}
+}
+
+// retreat undoes the advance to ts of a location that was never stored. If ts
+// is still the vehicle's newest timestamp, it is forgotten and looked up
+// again from the stored locations the next time the vehicle is seen.
+func (p *livePositions) retreat(vehicleID string, ts time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if last, ok := p.last[vehicleID]; ok && last.Equal(ts) {
+		delete(p.last, vehicleID)
+	}
+}
+
+// stored returns the timestamp of the vehicle's newest stored location, or
+// the zero time if it has none or the lookup fails; a failed lookup treats
+// the location as live rather than holding up ingestion.
+func (p *livePositions) stored(ctx context.Context, vehicleID string) time.Time {
+	vl, err := p.repo.GetLatest(ctx, vehicleID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrVehicleNotFound) {
+			log.Printf("load latest location for %s: %v", vehicleID, err)
+		}
+		return time.Time{}
+	}
+	return vl.Location.Timestamp
+}
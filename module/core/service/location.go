@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"time"
 
 	"github.com/nandanugg/tj-test/module/core/domain"
 	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
@@ -9,14 +12,128 @@ import (
 
 type LocationService struct {
 	repo database.LocationRepository
+	// writer is nil when locations are written synchronously.
+	writer *locationWriter
+	recent *recentFixes
+	live   *livePositions
 }
 
-func NewLocationService(repo database.LocationRepository) *LocationService {
-	return &LocationService{repo: repo}
+func NewLocationService(repo database.LocationRepository, opts LocationOptions) *LocationService {
+	s := &LocationService{
+		repo:   repo,
+		recent: newRecentFixes(),
+		live:   newLivePositions(repo, opts.LateTolerance),
+	}
+	if opts.BatchSize > 1 {
+		// a location the writer drops was never stored, so a redelivery of
+		// it must not be mistaken for a duplicate, and it must not remain
+		// the live position
+		s.writer = newLocationWriter(repo, opts, func(vl *domain.VehicleLocation) {
+			s.recent.remove(vl.VehicleID, vl.Location.Timestamp)
+			s.live.retreat(vl.VehicleID, vl.Location.Timestamp)
+		})
+	}
+	return s
 }
 
-func (s *LocationService) SaveLocation(ctx context.Context, vl *domain.VehicleLocation) error {
-	return s.repo.Insert(ctx, vl)
+// SaveLocation stores the location, or queues it for the next batch when
+// batching is enabled. A queued location is not returned by GetLatest or
+// GetHistory until its batch has been written, but it becomes the vehicle's
+// live position as soon as it is queued; see livePositions.advance.
+//
+// live reports whether the location is the vehicle's current position. A late
+// location, older than one already seen by more than LateTolerance, is kept
+// as history only and must not drive live state such as geofence
+// transitions. It returns domain.ErrDuplicateLocation if the vehicle's
+// location at the same timestamp has already been saved.
+func (s *LocationService) SaveLocation(ctx context.Context, vl *domain.VehicleLocation) (live bool, err error) {
+	if !s.recent.add(vl.VehicleID, vl.Location.Timestamp) {
+		return false, domain.ErrDuplicateLocation
+	}
+	last := s.live.newest(ctx, vl.VehicleID)
+	if isNewest(last, vl.Location.Timestamp) {
+		return false, domain.ErrDuplicateLocation
+	}
+	live = s.live.isLive(last, vl.Location.Timestamp)
+
+	if s.writer != nil {
+		err = s.writer.add(ctx, vl)
+	} else {
+		err = s.repo.Insert(ctx, vl)
+	}
+	if err != nil {
+		if !errors.Is(err, domain.ErrDuplicateLocation) {
+			s.recent.remove(vl.VehicleID, vl.Location.Timestamp)
+		}
+		return false, err
+	}
+	s.live.advance(vl.VehicleID, vl.Location.Timestamp)
+	return live, nil
+}
+
+// SaveLocations stores a batch of locations uploaded together in one
+// transaction, bypassing the write buffer. The batch is sorted by timestamp
+// in place, and live reports for each location in that order whether it is
+// live, as for SaveLocation. Duplicates are skipped and reported as not live.
+func (s *LocationService) SaveLocations(ctx context.Context, vls []domain.VehicleLocation) (live []bool, err error) {
+	slices.SortStableFunc(vls, func(a, b domain.VehicleLocation) int {
+		return a.Location.Timestamp.Compare(b.Location.Timestamp)
+	})
+
+	// newest is each vehicle's newest timestamp as of the point being
+	// judged; it only becomes the live position once the batch is stored
+	live = make([]bool, len(vls))
+	fresh := make([]domain.VehicleLocation, 0, len(vls))
+	newest := make(map[string]time.Time)
+	for i := range vls {
+		vl := &vls[i]
+		if !s.recent.add(vl.VehicleID, vl.Location.Timestamp) {
+			continue
+		}
+		last, ok := newest[vl.VehicleID]
+		if !ok {
+			last = s.live.newest(ctx, vl.VehicleID)
+			if isNewest(last, vl.Location.Timestamp) {
+				continue
+			}
+		}
+		live[i] = s.live.isLive(last, vl.Location.Timestamp)
+		if live[i] && vl.Location.Timestamp.After(last) {
+			last = vl.Location.Timestamp
+		}
+		newest[vl.VehicleID] = last
+		fresh = append(fresh, *vl)
+	}
+	if len(fresh) == 0 {
+		return live, nil
+	}
+
+	if err := s.repo.InsertBatch(ctx, fresh); err != nil {
+		for _, vl := range fresh {
+			s.recent.remove(vl.VehicleID, vl.Location.Timestamp)
+		}
+		return nil, err
+	}
+	for vehicleID, ts := range newest {
+		s.live.advance(vehicleID, ts)
+	}
+	return live, nil
+}
+
+// isNewest reports whether ts is the vehicle's newest timestamp last, which
+// after a restart the recent fixes do not yet hold: a redelivery of the last
+// fix stored before the restart must not be evaluated again.
+func isNewest(last, ts time.Time) bool {
+	return !last.IsZero() && ts.Equal(last)
+}
+
+// Close writes any queued locations and stops accepting new ones. It returns
+// ctx's error if the queue has not drained by the time ctx is done.
+func (s *LocationService) Close(ctx context.Context) error {
+	if s.writer == nil {
+		return nil
+	}
+	return s.writer.close(ctx)
 }
 
 func (s *LocationService) GetLatest(ctx context.Context, vehicleID string) (*domain.VehicleLocation, error) {
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 
 type mockLocationRepo struct {
 	insertFn         func(ctx context.Context, loc *domain.VehicleLocation) error
+	insertBatchFn    func(ctx context.Context, locs []domain.VehicleLocation) error
 	getLatestFn      func(ctx context.Context, vehicleID string) (*domain.VehicleLocation, error)
 	getHistoryFn     func(ctx context.Context, query *domain.HistoryQuery) ([]domain.VehicleLocation, error)
 	getAllVehiclesFn func(ctx context.Context) ([]domain.Vehicle, error)
@@ -20,7 +22,14 @@ func (m *mockLocationRepo) Insert(ctx context.Context, loc *domain.VehicleLocati
 	return m.insertFn(ctx, loc)
 }
 
+func (m *mockLocationRepo) InsertBatch(ctx context.Context, locs []domain.VehicleLocation) error {
+	return m.insertBatchFn(ctx, locs)
+}
+
 func (m *mockLocationRepo) GetLatest(ctx context.Context, vehicleID string) (*domain.VehicleLocation, error) {
+	if m.getLatestFn == nil {
+		return nil, domain.ErrVehicleNotFound
+	}
 	return m.getLatestFn(ctx, vehicleID)
 }
 
@@ -41,7 +50,7 @@ func TestSaveLocation_Success(t *testing.T) {
 		},
 	}
 
-	svc := NewLocationService(repo)
+	svc := NewLocationService(repo, LocationOptions{})
 	vl := &domain.VehicleLocation{
 		VehicleID: "B1234XYZ",
 		Location: domain.Location{
@@ -51,7 +60,7 @@ func TestSaveLocation_Success(t *testing.T) {
 		},
 	}
 
-	err := svc.SaveLocation(context.Background(), vl)
+	_, err := svc.SaveLocation(context.Background(), vl)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -70,13 +79,283 @@ func TestSaveLocation_RepoError(t *testing.T) {
 		},
 	}
 
-	svc := NewLocationService(repo)
-	err := svc.SaveLocation(context.Background(), &domain.VehicleLocation{VehicleID: "X"})
+	svc := NewLocationService(repo, LocationOptions{})
+	_, err := svc.SaveLocation(context.Background(), &domain.VehicleLocation{VehicleID: "X"})
 	if err == nil {
 		t.Fatal("expected error")
 	}
 }
 
+func TestSaveLocation_Duplicate(t *testing.T) {
+	inserts := 0
+	repo := &mockLocationRepo{
+		insertFn: func(_ context.Context, _ *domain.VehicleLocation) error {
+			inserts++
+			return nil
+		},
+	}
+
+	svc := NewLocationService(repo, LocationOptions{})
+	vl := &domain.VehicleLocation{VehicleID: "B1234XYZ", Location: domain.Location{Timestamp: time.Unix(1715003456, 0)}}
+	if _, err := svc.SaveLocation(context.Background(), vl); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	redelivered := *vl
+	if _, err := svc.SaveLocation(context.Background(), &redelivered); !errors.Is(err, domain.ErrDuplicateLocation) {
+		t.Fatalf("expected ErrDuplicateLocation, got %v", err)
+	}
+	other := &domain.VehicleLocation{VehicleID: "B5678ABC", Location: vl.Location}
+	if _, err := svc.SaveLocation(context.Background(), other); err != nil {
+		t.Fatalf("same timestamp for another vehicle: %v", err)
+	}
+	if inserts != 2 {
+		t.Errorf("expected 2 inserts, got %d", inserts)
+	}
+}
+
+func TestSaveLocation_RetryAfterFailure(t *testing.T) {
+	fail := true
+	repo := &mockLocationRepo{
+		insertFn: func(_ context.Context, _ *domain.VehicleLocation) error {
+			if fail {
+				return errors.New("db error")
+			}
+			return nil
+		},
+	}
+
+	svc := NewLocationService(repo, LocationOptions{})
+	vl := &domain.VehicleLocation{VehicleID: "B1234XYZ", Location: domain.Location{Timestamp: time.Unix(1715003456, 0)}}
+	if _, err := svc.SaveLocation(context.Background(), vl); err == nil {
+		t.Fatal("expected error")
+	}
+	fail = false
+	if _, err := svc.SaveLocation(context.Background(), vl); err != nil {
+		t.Fatalf("expected the redelivery of a failed save to be stored, got %v", err)
+	}
+}
+
+func TestSaveLocation_DuplicateFromRepo(t *testing.T) {
+	repo := &mockLocationRepo{
+		insertFn: func(_ context.Context, _ *domain.VehicleLocation) error {
+			return domain.ErrDuplicateLocation
+		},
+	}
+
+	// the service has not seen it, but the database has, e.g. after a restart
+	svc := NewLocationService(repo, LocationOptions{})
+	_, err := svc.SaveLocation(context.Background(), &domain.VehicleLocation{VehicleID: "X", Location: domain.Location{Timestamp: time.Unix(1, 0)}})
+	if !errors.Is(err, domain.ErrDuplicateLocation) {
+		t.Fatalf("expected ErrDuplicateLocation, got %v", err)
+	}
+}
+
+func TestSaveLocation_Late(t *testing.T) {
+	repo := &mockLocationRepo{
+		insertFn: func(_ context.Context, _ *domain.VehicleLocation) error { return nil },
+	}
+	svc := NewLocationService(repo, LocationOptions{LateTolerance: 30 * time.Second})
+
+	base := time.Unix(1715003456, 0)
+	tests := []struct {
+		name string
+		ts   time.Time
+		live bool
+	}{
+		{"first", base, true},
+		{"newer", base.Add(time.Minute), true},
+		{"within tolerance", base.Add(40 * time.Second), true},
+		{"beyond tolerance", base.Add(29 * time.Second), false},
+		{"newest again", base.Add(2 * time.Minute), true},
+		{"buffered upload", base.Add(80 * time.Second), false},
+	}
+	for _, tt := range tests {
+		live, err := svc.SaveLocation(context.Background(), &domain.VehicleLocation{VehicleID: "B1234XYZ", Location: domain.Location{Timestamp: tt.ts}})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if live != tt.live {
+			t.Errorf("%s: expected live=%v, got %v", tt.name, tt.live, live)
+		}
+	}
+}
+
+func TestSaveLocation_LateAfterRestart(t *testing.T) {
+	stored := time.Unix(1715003456, 0)
+	repo := &mockLocationRepo{
+		insertFn: func(_ context.Context, _ *domain.VehicleLocation) error { return nil },
+		getLatestFn: func(_ context.Context, vehicleID string) (*domain.VehicleLocation, error) {
+			return &domain.VehicleLocation{VehicleID: vehicleID, Location: domain.Location{Timestamp: stored}}, nil
+		},
+	}
+	svc := NewLocationService(repo, LocationOptions{})
+
+	live, err := svc.SaveLocation(context.Background(), &domain.VehicleLocation{VehicleID: "B1234XYZ", Location: domain.Location{Timestamp: stored.Add(-time.Second)}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if live {
+		t.Error("expected a location older than the stored one to be late")
+	}
+}
+
+func TestSaveLocation_RedeliveryAfterRestart(t *testing.T) {
+	stored := time.Unix(1715003456, 0)
+	repo := &mockLocationRepo{
+		insertFn: func(_ context.Context, _ *domain.VehicleLocation) error {
+			t.Error("expected the redelivery not to be written")
+			return nil
+		},
+		insertBatchFn: func(_ context.Context, locs []domain.VehicleLocation) error {
+			t.Errorf("expected the redelivery not to be written, got %d locations", len(locs))
+			return nil
+		},
+		getLatestFn: func(_ context.Context, vehicleID string) (*domain.VehicleLocation, error) {
+			return &domain.VehicleLocation{VehicleID: vehicleID, Location: domain.Location{Timestamp: stored}}, nil
+		},
+	}
+
+	// a fresh service has no recent fixes, as after a restart
+	for _, opts := range []LocationOptions{{}, {BatchSize: 500}} {
+		svc := NewLocationService(repo, opts)
+		_, err := svc.SaveLocation(context.Background(), &domain.VehicleLocation{VehicleID: "B1234XYZ", Location: domain.Location{Timestamp: stored}})
+		if !errors.Is(err, domain.ErrDuplicateLocation) {
+			t.Errorf("batch size %d: expected ErrDuplicateLocation, got %v", opts.BatchSize, err)
+		}
+		if err := svc.Close(context.Background()); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+	}
+
+	svc := NewLocationService(repo, LocationOptions{})
+	live, err := svc.SaveLocations(context.Background(), []domain.VehicleLocation{{VehicleID: "B1234XYZ", Location: domain.Location{Timestamp: stored}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(live, []bool{false}) {
+		t.Errorf("expected the redelivered batch point to be skipped, got %v", live)
+	}
+}
+
+func TestSaveLocation_LatestLookupFails(t *testing.T) {
+	repo := &mockLocationRepo{
+		insertFn: func(_ context.Context, _ *domain.VehicleLocation) error { return nil },
+		getLatestFn: func(_ context.Context, _ string) (*domain.VehicleLocation, error) {
+			return nil, errors.New("db error")
+		},
+	}
+	svc := NewLocationService(repo, LocationOptions{})
+
+	live, err := svc.SaveLocation(context.Background(), &domain.VehicleLocation{VehicleID: "B1234XYZ", Location: domain.Location{Timestamp: time.Unix(1715003456, 0)}})
+	if err != nil || !live {
+		t.Fatalf("expected the location to be saved as live, got live=%v err=%v", live, err)
+	}
+}
+
+func TestSaveLocations_SortsAndSkipsDuplicates(t *testing.T) {
+	var inserted []domain.VehicleLocation
+	repo := &mockLocationRepo{
+		insertFn: func(_ context.Context, _ *domain.VehicleLocation) error { return nil },
+		insertBatchFn: func(_ context.Context, locs []domain.VehicleLocation) error {
+			inserted = locs
+			return nil
+		},
+	}
+	svc := NewLocationService(repo, LocationOptions{})
+
+	base := time.Unix(1715003456, 0)
+	at := func(sec int) domain.VehicleLocation {
+		return domain.VehicleLocation{VehicleID: "B1234XYZ", Location: domain.Location{Timestamp: base.Add(time.Duration(sec) * time.Second)}}
+	}
+	// a live fix at +20s arrived before the buffered batch
+	if _, err := svc.SaveLocation(context.Background(), &domain.VehicleLocation{VehicleID: "B1234XYZ", Location: at(20).Location}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	batch := []domain.VehicleLocation{at(30), at(10), at(20), at(40), at(10)}
+	live, err := svc.SaveLocations(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var order []int64
+	for _, vl := range batch {
+		order = append(order, vl.Location.Timestamp.Unix()-base.Unix())
+	}
+	if want := []int64{10, 10, 20, 30, 40}; !slices.Equal(order, want) {
+		t.Errorf("expected batch sorted to %v, got %v", want, order)
+	}
+	if want := []bool{false, false, false, true, true}; !slices.Equal(live, want) {
+		t.Errorf("expected live %v, got %v", want, live)
+	}
+	if len(inserted) != 3 {
+		t.Errorf("expected the late fix and the two new ones to be inserted, got %d", len(inserted))
+	}
+}
+
+func TestSaveLocations_RepoError(t *testing.T) {
+	fail := true
+	repo := &mockLocationRepo{
+		insertBatchFn: func(_ context.Context, _ []domain.VehicleLocation) error {
+			if fail {
+				return errors.New("db error")
+			}
+			return nil
+		},
+	}
+	svc := NewLocationService(repo, LocationOptions{})
+
+	batch := []domain.VehicleLocation{{VehicleID: "B1234XYZ", Location: domain.Location{Timestamp: time.Unix(1715003456, 0)}}}
+	if _, err := svc.SaveLocations(context.Background(), batch); err == nil {
+		t.Fatal("expected error")
+	}
+	fail = false
+	live, err := svc.SaveLocations(context.Background(), batch)
+	if err != nil || !live[0] {
+		t.Fatalf("expected the retried batch to be stored as live, got %v, %v", live, err)
+	}
+}
+
+func TestSaveLocations_RetryAfterFailedInsertIsLive(t *testing.T) {
+	attempts := 0
+	repo := &mockLocationRepo{
+		insertBatchFn: func(_ context.Context, _ []domain.VehicleLocation) error {
+			attempts++
+			if attempts == 1 {
+				return errors.New("db error")
+			}
+			return nil
+		},
+	}
+	svc := NewLocationService(repo, LocationOptions{LateTolerance: 30 * time.Second})
+
+	// the points are further apart than the tolerance, so the first would be
+	// late if the failed insert had advanced the vehicle to the second
+	batch := func() []domain.VehicleLocation {
+		return []domain.VehicleLocation{
+			{VehicleID: "B1234XYZ", Location: domain.Location{Timestamp: time.Unix(1715003456, 0)}},
+			{VehicleID: "B1234XYZ", Location: domain.Location{Timestamp: time.Unix(1715003756, 0)}},
+		}
+	}
+	if _, err := svc.SaveLocations(context.Background(), batch()); err == nil {
+		t.Fatal("expected error")
+	}
+	live, err := svc.SaveLocations(context.Background(), batch())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(live, []bool{true, true}) {
+		t.Errorf("expected the retried batch to be live, got %v", live)
+	}
+
+	// once stored, the batch moves the vehicle forward
+	late := &domain.VehicleLocation{VehicleID: "B1234XYZ", Location: domain.Location{Timestamp: time.Unix(1715003500, 0)}}
+	repo.insertFn = func(context.Context, *domain.VehicleLocation) error { return nil }
+	if live, err := svc.SaveLocation(context.Background(), late); err != nil || live {
+		t.Errorf("expected a location older than the batch to be late, got %v, %v", live, err)
+	}
+}
+
 func TestGetLatest_Success(t *testing.T) {
 	ts := time.Unix(1715003456, 0)
 	repo := &mockLocationRepo{
@@ -88,7 +367,7 @@ func TestGetLatest_Success(t *testing.T) {
 		},
 	}
 
-	svc := NewLocationService(repo)
+	svc := NewLocationService(repo, LocationOptions{})
 	result, err := svc.GetLatest(context.Background(), "B1234XYZ")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -108,7 +387,7 @@ func TestGetLatest_NotFound(t *testing.T) {
 		},
 	}
 
-	svc := NewLocationService(repo)
+	svc := NewLocationService(repo, LocationOptions{})
 	_, err := svc.GetLatest(context.Background(), "UNKNOWN")
 	if err == nil {
 		t.Fatal("expected error")
@@ -127,7 +406,7 @@ func TestGetHistory_Success(t *testing.T) {
 		},
 	}
 
-	svc := NewLocationService(repo)
+	svc := NewLocationService(repo, LocationOptions{})
 	query := &domain.HistoryQuery{
 		VehicleID: "B1234XYZ",
 		Start:     time.Unix(1715000000, 0),
@@ -150,7 +429,7 @@ func TestGetHistory_RepoError(t *testing.T) {
 		},
 	}
 
-	svc := NewLocationService(repo)
+	svc := NewLocationService(repo, LocationOptions{})
 	_, err := svc.GetHistory(context.Background(), &domain.HistoryQuery{VehicleID: "X"})
 	if err == nil {
 		t.Fatal("expected error")
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/queue"
+	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
+)
+
+// ErrLocationWriterClosed is returned by SaveLocation after Close.
+var ErrLocationWriterClosed = errors.New("location writer closed")
+
+const (
+	defaultFlushInterval = time.Second
+	// flushTimeout bounds a single insert so a stuck database cannot stall
+	// the writer forever.
+	flushTimeout = 30 * time.Second
+)
+
+// flushBackoff is the wait before the first retry of a failed insert; it
+// doubles for each further retry up to maxFlushBackoff.
+var (
+	flushBackoff    = 100 * time.Millisecond
+	maxFlushBackoff = 10 * time.Second
+)
+
+// locationWriterMetrics is served under "location_writer" by expvar:
+// queue_depth is the number of locations accepted but not yet written,
+// written and failed count locations, batches counts batch inserts attempted,
+// retries counts inserts retried while the database was unavailable,
+// fallbacks counts batches that were inserted row by row after the database
+// rejected them and flush_ms_total is the time spent flushing.
+var locationWriterMetrics = expvar.NewMap("location_writer")
+
+// LocationOptions controls how locations are written to the repository.
+type LocationOptions struct {
+	// BatchSize is the most locations written by one insert. Values below 2
+	// write each location synchronously as it is saved.
+	BatchSize int
+	// FlushInterval is the longest a buffered location waits before its batch
+	// is written, even if the batch is not full. Defaults to one second.
+	FlushInterval time.Duration
+	// QueueSize bounds how many locations may wait to be written; SaveLocation
+	// blocks while the queue is full. Defaults to four batches.
+	QueueSize int
+	// LateTolerance is how far a location may be older than the vehicle's
+	// newest one and still count as live. Older locations are stored as
+	// history only.
+	LateTolerance time.Duration
+}
+
+// locationWriter buffers locations and writes them in batches from a single
+// goroutine, flushing when a batch fills up or FlushInterval passes.
+type locationWriter struct {
+	repo database.LocationRepository
+	opts LocationOptions
+	// dropped is called for each location that could not be written.
+	dropped func(vl *domain.VehicleLocation)
+
+	queue *queue.Queue[domain.VehicleLocation]
+	done  chan struct{}
+	// ctx is cancelled once close gives up waiting, which ends the retries
+	// of an unavailable database.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newLocationWriter(repo database.LocationRepository, opts LocationOptions, dropped func(vl *domain.VehicleLocation)) *locationWriter {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultFlushInterval
+	}
+	if opts.QueueSize < opts.BatchSize {
+		opts.QueueSize = opts.BatchSize * 4
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &locationWriter{
+		repo:    repo,
+		opts:    opts,
+		dropped: dropped,
+		queue:   queue.New[domain.VehicleLocation](opts.QueueSize),
+		done:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	go w.run()
+	return w
+}
+
+func (w *locationWriter) add(ctx context.Context, vl *domain.VehicleLocation) error {
+	if err := w.queue.Push(ctx, *vl); err != nil {
+		if errors.Is(err, queue.ErrClosed) {
+			return ErrLocationWriterClosed
+		}
+		return err
+	}
+	locationWriterMetrics.Add("queue_depth", 1)
+	return nil
+}
+
+// close stops accepting locations and waits until everything already queued
+// has been written, or ctx is done. Locations still waiting for an
+// unavailable database are then dropped.
+func (w *locationWriter) close(ctx context.Context) error {
+	w.queue.Close()
+	if err := queue.Wait(ctx, w.done); err != nil {
+		w.cancel()
+		return err
+	}
+	return nil
+}
+
+func (w *locationWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]domain.VehicleLocation, 0, w.opts.BatchSize)
+	for {
+		select {
+		case vl, ok := <-w.queue.C():
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, vl)
+			if len(batch) >= w.opts.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			w.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush writes the batch. While the database is unavailable the batch is
+// retried with backoff until it is written or close gives up; meanwhile the
+// queue fills up and SaveLocation blocks, so locations already accepted are
+// not lost to a database restart. If the database rejects the batch, for
+// example because one row violates a constraint, its rows are inserted one by
+// one so only the rows rejected on their own are dropped.
+func (w *locationWriter) flush(batch []domain.VehicleLocation) {
+	if len(batch) == 0 {
+		return
+	}
+
+	start := time.Now()
+	defer func() {
+		locationWriterMetrics.Add("flush_ms_total", time.Since(start).Milliseconds())
+		locationWriterMetrics.Add("queue_depth", -int64(len(batch)))
+	}()
+
+	err := w.write(len(batch), func(ctx context.Context) error {
+		locationWriterMetrics.Add("batches", 1)
+		return w.repo.InsertBatch(ctx, batch)
+	})
+	if err == nil {
+		locationWriterMetrics.Add("written", int64(len(batch)))
+		return
+	}
+	if !errors.Is(err, domain.ErrLocationRejected) {
+		for i := range batch {
+			w.drop(&batch[i], err)
+		}
+		return
+	}
+
+	log.Printf("write %d locations, inserting one by one: %v", len(batch), err)
+	locationWriterMetrics.Add("fallbacks", 1)
+	for i := range batch {
+		vl := &batch[i]
+		// a duplicate is already stored and does not count as a failure
+		err := w.write(1, func(ctx context.Context) error {
+			if err := w.repo.Insert(ctx, vl); err != nil && !errors.Is(err, domain.ErrDuplicateLocation) {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			w.drop(vl, err)
+			continue
+		}
+		locationWriterMetrics.Add("written", 1)
+	}
+}
+
+// write calls insert until it succeeds or the database rejects the data,
+// waiting with backoff between attempts. It gives up with the last error once
+// close has given up waiting.
+func (w *locationWriter) write(n int, insert func(ctx context.Context) error) error {
+	backoff := flushBackoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(w.ctx, flushTimeout)
+		err := insert(ctx)
+		cancel()
+		if err == nil || errors.Is(err, domain.ErrLocationRejected) || w.ctx.Err() != nil {
+			return err
+		}
+
+		log.Printf("write %d locations (attempt %d), retrying in %v: %v", n, attempt, backoff, err)
+		locationWriterMetrics.Add("retries", 1)
+		select {
+		case <-time.After(backoff):
+		case <-w.ctx.Done():
+			return err
+		}
+		backoff = min(backoff*2, maxFlushBackoff)
+	}
+}
+
+func (w *locationWriter) drop(vl *domain.VehicleLocation, err error) {
+	locationWriterMetrics.Add("failed", 1)
+	log.Printf("write location for %s at %d, dropped: %v", vl.VehicleID, vl.Location.Timestamp.Unix(), err)
+	w.dropped(vl)
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+// batchRecorder collects the batches passed to InsertBatch and the rows
+// passed to Insert. err fails every insert, or only the first failures
+// inserts if failures is set; rowErrs fail the batch and the single insert of
+// the vehicles they name.
+type batchRecorder struct {
+	mu       sync.Mutex
+	batches  [][]string
+	rows     []string
+	err      error
+	failures int
+	rowErrs  map[string]error
+}
+
+// fail returns the error of the next insert; b.mu must be held.
+func (b *batchRecorder) fail() error {
+	err := b.err
+	if b.failures > 0 {
+		b.failures--
+		if b.failures == 0 {
+			b.err = nil
+		}
+	}
+	return err
+}
+
+func (b *batchRecorder) repo() *mockLocationRepo {
+	return &mockLocationRepo{
+		insertBatchFn: func(_ context.Context, locs []domain.VehicleLocation) error {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			var ids []string
+			for _, vl := range locs {
+				ids = append(ids, vl.VehicleID)
+			}
+			b.batches = append(b.batches, ids)
+			if err := b.fail(); err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if err := b.rowErrs[id]; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		insertFn: func(_ context.Context, vl *domain.VehicleLocation) error {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if err := b.fail(); err != nil {
+				return err
+			}
+			if err := b.rowErrs[vl.VehicleID]; err != nil {
+				return err
+			}
+			b.rows = append(b.rows, vl.VehicleID)
+			return nil
+		},
+	}
+}
+
+func (b *batchRecorder) snapshot() [][]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.batches)
+}
+
+// fastFlushRetries shortens the backoff between insert retries for the test.
+func fastFlushRetries(t *testing.T) {
+	backoff, maxBackoff := flushBackoff, maxFlushBackoff
+	flushBackoff, maxFlushBackoff = time.Millisecond, 5*time.Millisecond
+	t.Cleanup(func() { flushBackoff, maxFlushBackoff = backoff, maxBackoff })
+}
+
+func saveAll(t *testing.T, svc *LocationService, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		if _, err := svc.SaveLocation(context.Background(), &domain.VehicleLocation{VehicleID: id}); err != nil {
+			t.Fatalf("save %s: %v", id, err)
+		}
+	}
+}
+
+func TestLocationWriter_FlushesFullBatches(t *testing.T) {
+	rec := &batchRecorder{}
+	svc := NewLocationService(rec.repo(), LocationOptions{BatchSize: 2, FlushInterval: time.Hour})
+
+	saveAll(t, svc, "a", "b", "c", "d", "e")
+	if err := svc.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	got := rec.snapshot()
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	if !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Errorf("expected batches %v, got %v", want, got)
+	}
+}
+
+func TestLocationWriter_FlushesOnInterval(t *testing.T) {
+	rec := &batchRecorder{}
+	svc := NewLocationService(rec.repo(), LocationOptions{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer func() { _ = svc.Close(context.Background()) }()
+
+	saveAll(t, svc, "a")
+
+	deadline := time.Now().Add(time.Second)
+	for len(rec.snapshot()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the partial batch to be flushed by the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLocationWriter_RejectsAfterClose(t *testing.T) {
+	rec := &batchRecorder{}
+	svc := NewLocationService(rec.repo(), LocationOptions{BatchSize: 10})
+
+	if err := svc.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := svc.Close(context.Background()); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+	_, err := svc.SaveLocation(context.Background(), &domain.VehicleLocation{VehicleID: "a"})
+	if !errors.Is(err, ErrLocationWriterClosed) {
+		t.Errorf("expected ErrLocationWriterClosed, got %v", err)
+	}
+}
+
+func TestLocationWriter_RetriesUntilDatabaseRecovers(t *testing.T) {
+	fastFlushRetries(t)
+	rec := &batchRecorder{err: errors.New("connection refused"), failures: 10}
+	svc := NewLocationService(rec.repo(), LocationOptions{BatchSize: 2, FlushInterval: time.Hour})
+
+	failedBefore := expvarInt("failed")
+	saveAll(t, svc, "a", "b", "c")
+	if err := svc.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	got := rec.snapshot()
+	if len(got) != 12 {
+		t.Errorf("expected 10 failed and 2 successful batches, got %d", len(got))
+	}
+	if want := [][]string{{"a", "b"}, {"c"}}; !slices.EqualFunc(got[len(got)-2:], want, slices.Equal[[]string]) {
+		t.Errorf("expected the last batches to be %v, got %v", want, got[len(got)-2:])
+	}
+	rec.mu.Lock()
+	rows := len(rec.rows)
+	rec.mu.Unlock()
+	if rows != 0 {
+		t.Errorf("expected no row by row fallback while the database is unavailable, got %d rows", rows)
+	}
+	if got := expvarInt("failed") - failedBefore; got != 0 {
+		t.Errorf("expected no failed locations, got %d", got)
+	}
+}
+
+func TestLocationWriter_DropsWhenCloseGivesUp(t *testing.T) {
+	fastFlushRetries(t)
+	rec := &batchRecorder{err: errors.New("connection refused")}
+	svc := NewLocationService(rec.repo(), LocationOptions{BatchSize: 2, FlushInterval: time.Hour})
+
+	failedBefore := expvarInt("failed")
+	saveAll(t, svc, "a", "b", "c")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := svc.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected close to give up at its deadline, got %v", err)
+	}
+	<-svc.writer.done
+
+	if got := expvarInt("failed") - failedBefore; got != 3 {
+		t.Errorf("expected 3 failed locations, got %d", got)
+	}
+	// dropped locations are forgotten, so a redelivery is saved again
+	if !svc.recent.add("a", time.Time{}) {
+		t.Error("expected a dropped location to be removed from the recent fixes")
+	}
+}
+
+func TestLocationWriter_FallsBackToSingleInserts(t *testing.T) {
+	rejected := fmt.Errorf("%w: value out of range", domain.ErrLocationRejected)
+	rec := &batchRecorder{rowErrs: map[string]error{"bad": rejected}}
+	svc := NewLocationService(rec.repo(), LocationOptions{BatchSize: 3, FlushInterval: time.Hour})
+
+	failedBefore := expvarInt("failed")
+	saveAll(t, svc, "a", "bad", "c")
+	if err := svc.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if n := len(rec.snapshot()); n != 1 {
+		t.Errorf("expected a rejected batch not to be retried, got %d attempts", n)
+	}
+	rec.mu.Lock()
+	rows := slices.Clone(rec.rows)
+	rec.mu.Unlock()
+	if want := []string{"a", "c"}; !slices.Equal(rows, want) {
+		t.Errorf("expected rows %v to be inserted one by one, got %v", want, rows)
+	}
+	if got := expvarInt("failed") - failedBefore; got != 1 {
+		t.Errorf("expected 1 failed location, got %d", got)
+	}
+	if svc.recent.add("a", time.Time{}) {
+		t.Error("expected a written location to stay in the recent fixes")
+	}
+	if !svc.recent.add("bad", time.Time{}) {
+		t.Error("expected the dropped location to be removed from the recent fixes")
+	}
+}
+
+func TestLocationWriter_DroppedLocationIsNotLivePosition(t *testing.T) {
+	rejected := fmt.Errorf("%w: value out of range", domain.ErrLocationRejected)
+	rec := &batchRecorder{rowErrs: map[string]error{"B1234XYZ": rejected}}
+	svc := NewLocationService(rec.repo(), LocationOptions{BatchSize: 2, FlushInterval: time.Hour})
+
+	ts := time.Unix(1715003456, 0)
+	if _, err := svc.SaveLocation(context.Background(), &domain.VehicleLocation{VehicleID: "B1234XYZ", Location: domain.Location{Timestamp: ts}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if got := svc.live.newest(context.Background(), "B1234XYZ"); !got.Equal(ts) {
+		t.Fatalf("expected the queued location to be the live position, got %v", got)
+	}
+	if err := svc.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// nothing is stored for the vehicle, so the dropped location must not
+	// stay its live position
+	if got := svc.live.newest(context.Background(), "B1234XYZ"); !got.IsZero() {
+		t.Errorf("expected the dropped location to be taken back, got %v", got)
+	}
+}
+
+func TestLocationWriter_SaveBlocksOnFullQueue(t *testing.T) {
+	release := make(chan struct{})
+	repo := &mockLocationRepo{
+		insertBatchFn: func(_ context.Context, _ []domain.VehicleLocation) error {
+			<-release
+			return nil
+		},
+	}
+	svc := NewLocationService(repo, LocationOptions{BatchSize: 2, QueueSize: 2, FlushInterval: time.Hour})
+
+	// the first batch is taken off the queue and blocks in InsertBatch, the
+	// next two fill the queue
+	saveAll(t, svc, "a", "b", "c", "d")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := svc.SaveLocation(ctx, &domain.VehicleLocation{VehicleID: "e"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected save to block until the deadline, got %v", err)
+	}
+
+	close(release)
+	if err := svc.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
+
+func TestLocationWriter_CloseWakesBlockedSave(t *testing.T) {
+	release := make(chan struct{})
+	repo := &mockLocationRepo{
+		insertBatchFn: func(_ context.Context, _ []domain.VehicleLocation) error {
+			<-release
+			return nil
+		},
+	}
+	svc := NewLocationService(repo, LocationOptions{BatchSize: 2, QueueSize: 2, FlushInterval: time.Hour})
+	defer close(release)
+
+	saveAll(t, svc, "a", "b", "c", "d")
+	saved := make(chan error, 1)
+	go func() {
+		_, err := svc.SaveLocation(context.Background(), &domain.VehicleLocation{VehicleID: "e"})
+		saved <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := svc.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected close to give up at its deadline, got %v", err)
+	}
+	select {
+	case err := <-saved:
+		if !errors.Is(err, ErrLocationWriterClosed) {
+			t.Errorf("expected ErrLocationWriterClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the blocked save to return once the writer closed")
+	}
+}
+
+func expvarInt(key string) int64 {
+	v := locationWriterMetrics.Get(key)
+	if v == nil {
+		return 0
+	}
+	return v.(interface{ Value() int64 }).Value()
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
+)
+
+// ReportService reconstructs geofence visits from stored location history.
+// It never publishes alerts or touches live geofence state.
+type ReportService struct {
+	locationRepo database.LocationRepository
+	geofenceRepo database.GeofenceRepository
+	assignRepo   database.VehicleAssignmentRepository
+	reportRepo   database.VisitReportRepository
+	opts         GeofenceOptions
+}
+
+func NewReportService(locationRepo database.LocationRepository, geofenceRepo database.GeofenceRepository, assignRepo database.VehicleAssignmentRepository, reportRepo database.VisitReportRepository, opts GeofenceOptions) *ReportService {
+	return &ReportService{
+		locationRepo: locationRepo,
+		geofenceRepo: geofenceRepo,
+		assignRepo:   assignRepo,
+		reportRepo:   reportRepo,
+		opts:         opts,
+	}
+}
+
+// RunVisitReport replays every vehicle's history between start and end
+// through the geofence with the live hysteresis, debounce and schedule rules,
+// and stores the resulting visits as a report. Vehicles the geofence is not
+// assigned to are skipped. A vehicle already inside at start is reported as
+// entering at its first fix in the range.
+func (s *ReportService) RunVisitReport(ctx context.Context, geofenceID string, start, end time.Time) (*domain.VisitReport, error) {
+	gf, err := s.geofenceRepo.Get(ctx, geofenceID)
+	if err != nil {
+		return nil, err
+	}
+
+	vehicles, err := s.locationRepo.GetAllVehicles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list vehicles: %w", err)
+	}
+
+	report := &domain.VisitReport{
+		GeofenceID: geofenceID,
+		Start:      start,
+		End:        end,
+		Visits:     []domain.GeofenceVisit{},
+	}
+	for _, v := range vehicles {
+		va, err := s.assignRepo.Get(ctx, v.VehicleID)
+		if err != nil {
+			return nil, fmt.Errorf("load vehicle assignment: %w", err)
+		}
+		if !appliesTo(gf, va) {
+			continue
+		}
+
+		history, err := s.locationRepo.GetHistory(ctx, &domain.HistoryQuery{VehicleID: v.VehicleID, Start: start, End: end})
+		if err != nil {
+			return nil, fmt.Errorf("load history for %s: %w", v.VehicleID, err)
+		}
+
+		report.VehiclesScanned++
+		report.LocationsScanned += len(history)
+		report.Visits = append(report.Visits, replayVisits(gf, history, s.opts)...)
+	}
+
+	if err := s.reportRepo.Create(ctx, report); err != nil {
+		return nil, fmt.Errorf("save visit report: %w", err)
+	}
+	return report, nil
+}
+
+func (s *ReportService) GetVisitReport(ctx context.Context, id int64) (*domain.VisitReport, error) {
+	return s.reportRepo.Get(ctx, id)
+}
+
+// replayVisits runs one vehicle's time-ordered history through evaluate from
+// a fresh state and pairs the confirmed entries and exits into visits.
+func replayVisits(gf *domain.Geofence, history []domain.VehicleLocation, opts GeofenceOptions) []domain.GeofenceVisit {
+	var (
+		visits []domain.GeofenceVisit
+		st     *domain.GeofenceState
+		open   *domain.GeofenceVisit
+	)
+	for i := range history {
+		vl := &history[i]
+		prev := st
+		next, event := evaluate(gf, prev, vl, opts)
+		if next != nil {
+			st = next
+		}
+
+		switch event {
+		case domain.GeofenceEntry:
+			open = &domain.GeofenceVisit{VehicleID: vl.VehicleID, GeofenceID: gf.ID, EnteredAt: st.EnteredAt}
+		case domain.GeofenceExit:
+			visits = append(visits, *completedVisit(prev, vl.Location.Timestamp))
+			open = nil
+		}
+	}
+
+	if open != nil {
+		open.Duration = history[len(history)-1].Location.Timestamp.Sub(open.EnteredAt)
+		visits = append(visits, *open)
+	}
+	return visits
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+type mockVisitReportRepo struct {
+	created []*domain.VisitReport
+	err     error
+}
+
+func (m *mockVisitReportRepo) Create(_ context.Context, report *domain.VisitReport) error {
+	if m.err != nil {
+		return m.err
+	}
+	report.ID = int64(len(m.created) + 1)
+	m.created = append(m.created, report)
+	return nil
+}
+
+func (m *mockVisitReportRepo) Get(_ context.Context, id int64) (*domain.VisitReport, error) {
+	if id < 1 || int(id) > len(m.created) {
+		return nil, domain.ErrReportNotFound
+	}
+	return m.created[id-1], nil
+}
+
+func vehicleTrack(vehicleID string, fixes ...*domain.VehicleLocation) []domain.VehicleLocation {
+	track := make([]domain.VehicleLocation, len(fixes))
+	for i, vl := range fixes {
+		track[i] = *vl
+		track[i].VehicleID = vehicleID
+	}
+	return track
+}
+
+func newReportService(t *testing.T, gf domain.Geofence, histories map[string][]domain.VehicleLocation, assignRepo *mockVehicleAssignmentRepo, reportRepo *mockVisitReportRepo) *ReportService {
+	t.Helper()
+	locationRepo := &mockLocationRepo{
+		getAllVehiclesFn: func(_ context.Context) ([]domain.Vehicle, error) {
+			var vehicles []domain.Vehicle
+			for _, id := range []string{"B1234XYZ", "B5678ABC", "B9012DEF"} {
+				if _, ok := histories[id]; ok {
+					vehicles = append(vehicles, domain.Vehicle{VehicleID: id})
+				}
+			}
+			return vehicles, nil
+		},
+		getHistoryFn: func(_ context.Context, query *domain.HistoryQuery) ([]domain.VehicleLocation, error) {
+			var results []domain.VehicleLocation
+			for _, vl := range histories[query.VehicleID] {
+				if !vl.Location.Timestamp.Before(query.Start) && !vl.Location.Timestamp.After(query.End) {
+					results = append(results, vl)
+				}
+			}
+			return results, nil
+		},
+	}
+	geofenceRepo := &mockGeofenceRepo{
+		getFn: func(_ context.Context, id string) (*domain.Geofence, error) {
+			if id != gf.ID {
+				return nil, domain.ErrGeofenceNotFound
+			}
+			return &gf, nil
+		},
+	}
+	return NewReportService(locationRepo, geofenceRepo, assignRepo, reportRepo, GeofenceOptions{MinFixes: 2})
+}
+
+func TestRunVisitReport(t *testing.T) {
+	gf := circleFence("terminal", -6.2088, 106.8456, 50)
+	histories := map[string][]domain.VehicleLocation{
+		// enters, leaves, comes back and is still inside at the end
+		"B1234XYZ": vehicleTrack("B1234XYZ",
+			fixAt(500, 0),
+			fixAt(0, time.Minute),
+			fixAt(0, 2*time.Minute),
+			fixAt(500, 10*time.Minute),
+			fixAt(500, 11*time.Minute),
+			fixAt(0, 20*time.Minute),
+			fixAt(0, 21*time.Minute),
+			fixAt(0, 25*time.Minute),
+		),
+		// only a single noisy fix inside, never confirmed
+		"B5678ABC": vehicleTrack("B5678ABC", fixAt(500, 0), fixAt(0, time.Minute), fixAt(500, 2*time.Minute)),
+	}
+	reportRepo := &mockVisitReportRepo{}
+	svc := newReportService(t, gf, histories, &mockVehicleAssignmentRepo{}, reportRepo)
+
+	start := fixAt(0, 0).Location.Timestamp
+	report, err := svc.RunVisitReport(context.Background(), "terminal", start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.ID != 1 || len(reportRepo.created) != 1 {
+		t.Fatalf("expected report to be stored, got %+v", report)
+	}
+	if report.VehiclesScanned != 2 || report.LocationsScanned != 11 {
+		t.Errorf("unexpected scan counts: %d vehicles, %d locations", report.VehiclesScanned, report.LocationsScanned)
+	}
+	if len(report.Visits) != 2 {
+		t.Fatalf("expected 2 visits, got %+v", report.Visits)
+	}
+
+	closed := report.Visits[0]
+	if !closed.EnteredAt.Equal(start.Add(time.Minute)) || !closed.ExitedAt.Equal(start.Add(10*time.Minute)) || closed.Duration != 9*time.Minute {
+		t.Errorf("unexpected closed visit: %+v", closed)
+	}
+	open := report.Visits[1]
+	if !open.ExitedAt.IsZero() || !open.EnteredAt.Equal(start.Add(20*time.Minute)) || open.Duration != 5*time.Minute {
+		t.Errorf("unexpected open visit: %+v", open)
+	}
+}
+
+func TestRunVisitReport_SkipsUnassignedVehicles(t *testing.T) {
+	gf := circleFence("terminal", -6.2088, 106.8456, 50)
+	gf.Assignment.GroupIDs = []string{"articulated"}
+	histories := map[string][]domain.VehicleLocation{
+		"B1234XYZ": vehicleTrack("B1234XYZ", fixAt(0, 0), fixAt(0, time.Minute)),
+		"B5678ABC": vehicleTrack("B5678ABC", fixAt(0, 0), fixAt(0, time.Minute)),
+	}
+	assignRepo := &mockVehicleAssignmentRepo{assignments: map[string]domain.VehicleAssignment{
+		"B5678ABC": {VehicleID: "B5678ABC", GroupIDs: []string{"articulated"}},
+	}}
+	svc := newReportService(t, gf, histories, assignRepo, &mockVisitReportRepo{})
+
+	start := fixAt(0, 0).Location.Timestamp
+	report, err := svc.RunVisitReport(context.Background(), "terminal", start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.VehiclesScanned != 1 || len(report.Visits) != 1 || report.Visits[0].VehicleID != "B5678ABC" {
+		t.Errorf("expected only the assigned vehicle, got %+v", report)
+	}
+}
+
+func TestRunVisitReport_Errors(t *testing.T) {
+	gf := circleFence("terminal", -6.2088, 106.8456, 50)
+	histories := map[string][]domain.VehicleLocation{"B1234XYZ": vehicleTrack("B1234XYZ", fixAt(0, 0))}
+	start := fixAt(0, 0).Location.Timestamp
+
+	svc := newReportService(t, gf, histories, &mockVehicleAssignmentRepo{}, &mockVisitReportRepo{})
+	if _, err := svc.RunVisitReport(context.Background(), "missing", start, start.Add(time.Hour)); !errors.Is(err, domain.ErrGeofenceNotFound) {
+		t.Errorf("expected ErrGeofenceNotFound, got %v", err)
+	}
+
+	svc = newReportService(t, gf, histories, &mockVehicleAssignmentRepo{}, &mockVisitReportRepo{err: errors.New("db down")})
+	if _, err := svc.RunVisitReport(context.Background(), "terminal", start, start.Add(time.Hour)); err == nil {
+		t.Error("expected error when the report cannot be stored")
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+// locations caches time zones by name so schedules do not reread tzdata on
+// every fix.
+var locations sync.Map
+
+// scheduleActive reports whether the schedule is active at t, judged in the
+// schedule's own time zone. A nil schedule is always active.
+func scheduleActive(sched *domain.Schedule, t time.Time) bool {
+	if sched == nil {
+		return true
+	}
+
+	local := t.In(location(sched.Timezone))
+	h, m, sec := local.Clock()
+	offset := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second
+	today := local.Weekday()
+	yesterday := (today + 6) % 7
+
+	for _, w := range sched.Windows {
+		if w.End > w.Start {
+			if onDay(w.Days, today) && offset >= w.Start && offset < w.End {
+				return true
+			}
+			continue
+		}
+		// the window wraps past midnight: its tail belongs to yesterday's window
+		if onDay(w.Days, today) && offset >= w.Start {
+			return true
+		}
+		if onDay(w.Days, yesterday) && offset < w.End {
+			return true
+		}
+	}
+	return false
+}
+
+func onDay(days []time.Weekday, day time.Weekday) bool {
+	return len(days) == 0 || slices.Contains(days, day)
+}
+
+// location resolves a time zone name, falling back to UTC for names that do
+// not load. Geofences are validated on write, so the fallback only guards
+// against rows edited outside the API.
+func location(name string) *time.Location {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		loc = time.UTC
+	}
+	locations.Store(name, loc)
+	return loc
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+)
+
+func TestScheduleActive(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	schoolZone := &domain.Schedule{
+		Timezone: "Asia/Jakarta",
+		Windows:  []domain.ScheduleWindow{{Days: weekdays, Start: 6 * time.Hour, End: 8 * time.Hour}},
+	}
+	curfew := &domain.Schedule{
+		Timezone: "Asia/Jakarta",
+		Windows:  []domain.ScheduleWindow{{Days: []time.Weekday{time.Friday}, Start: 22 * time.Hour, End: 5 * time.Hour}},
+	}
+
+	// 2024-05-06 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, jakarta)
+	}
+
+	tests := []struct {
+		name  string
+		sched *domain.Schedule
+		t     time.Time
+		want  bool
+	}{
+		{"no schedule", nil, at(6, 3, 0), true},
+		{"inside window", schoolZone, at(6, 7, 30), true},
+		{"window start inclusive", schoolZone, at(6, 6, 0), true},
+		{"window end exclusive", schoolZone, at(6, 8, 0), false},
+		{"wrong day", schoolZone, at(11, 7, 0), false},
+		{"judged in schedule timezone", schoolZone, time.Date(2024, 5, 6, 0, 30, 0, 0, time.UTC), true},
+		{"overnight before midnight", curfew, at(10, 23, 0), true},
+		{"overnight after midnight", curfew, at(11, 4, 59), true},
+		{"overnight ended", curfew, at(11, 5, 0), false},
+		{"overnight wrong start day", curfew, at(10, 4, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scheduleActive(tt.sched, tt.t); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
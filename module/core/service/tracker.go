@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+
+	"github.com/nandanugg/tj-test/module/core/domain"
+	"github.com/nandanugg/tj-test/module/core/internal/repository/database"
+)
+
+// TrackerService maps TCP GPS trackers to the vehicles they are installed in.
+type TrackerService struct {
+	repo database.TrackerRepository
+}
+
+func NewTrackerService(repo database.TrackerRepository) *TrackerService {
+	return &TrackerService{repo: repo}
+}
+
+// VehicleForIMEI returns the vehicle the tracker is installed in, or
+// domain.ErrTrackerNotFound for a tracker that has not been registered.
+func (s *TrackerService) VehicleForIMEI(ctx context.Context, imei string) (string, error) {
+	t, err := s.repo.Get(ctx, imei)
+	if err != nil {
+		return "", err
+	}
+	return t.VehicleID, nil
+}
+
+func (s *TrackerService) ListTrackers(ctx context.Context) ([]domain.Tracker, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *TrackerService) SetTracker(ctx context.Context, t *domain.Tracker) error {
+	return s.repo.Upsert(ctx, t)
+}
+
+func (s *TrackerService) DeleteTracker(ctx context.Context, imei string) error {
+	return s.repo.Delete(ctx, imei)
+}
@@ -0,0 +1,244 @@
+// Location payload for /fleet/vehicle/{vehicle_id}/location/protobuf. The
+// fields mirror the JSON payload described in the README and follow the same
+// validation rules.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: proto/location.proto
+
+package fleetv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Location struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Optional; must match the vehicle in the topic when set.
+	VehicleId string  `protobuf:"bytes,1,opt,name=vehicle_id,json=vehicleId,proto3" json:"vehicle_id,omitempty"`
+	Latitude  float64 `protobuf:"fixed64,2,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude float64 `protobuf:"fixed64,3,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	// Unix epoch seconds.
+	Timestamp  int64    `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Speed      *float64 `protobuf:"fixed64,5,opt,name=speed,proto3,oneof" json:"speed,omitempty"`
+	Heading    *float64 `protobuf:"fixed64,6,opt,name=heading,proto3,oneof" json:"heading,omitempty"`
+	Altitude   *float64 `protobuf:"fixed64,7,opt,name=altitude,proto3,oneof" json:"altitude,omitempty"`
+	Accuracy   *float64 `protobuf:"fixed64,8,opt,name=accuracy,proto3,oneof" json:"accuracy,omitempty"`
+	Satellites *int32   `protobuf:"varint,9,opt,name=satellites,proto3,oneof" json:"satellites,omitempty"`
+	Ignition   *bool    `protobuf:"varint,10,opt,name=ignition,proto3,oneof" json:"ignition,omitempty"`
+	Odometer   *float64 `protobuf:"fixed64,11,opt,name=odometer,proto3,oneof" json:"odometer,omitempty"`
+	// A message with points is a batch of buffered fixes; vehicle_id then
+	// applies to points that have none of their own and the other fields are
+	// ignored. Points cannot carry points of their own.
+	Points        []*Location `protobuf:"bytes,12,rep,name=points,proto3" json:"points,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Location) Reset() {
+	*x = Location{}
+	mi := &file_proto_location_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Location) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Location) ProtoMessage() {}
+
+func (x *Location) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_location_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Location.ProtoReflect.Descriptor instead.
+func (*Location) Descriptor() ([]byte, []int) {
+	return file_proto_location_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Location) GetVehicleId() string {
+	if x != nil {
+		return x.VehicleId
+	}
+	return ""
+}
+
+func (x *Location) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *Location) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *Location) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Location) GetSpeed() float64 {
+	if x != nil && x.Speed != nil {
+		return *x.Speed
+	}
+	return 0
+}
+
+func (x *Location) GetHeading() float64 {
+	if x != nil && x.Heading != nil {
+		return *x.Heading
+	}
+	return 0
+}
+
+func (x *Location) GetAltitude() float64 {
+	if x != nil && x.Altitude != nil {
+		return *x.Altitude
+	}
+	return 0
+}
+
+func (x *Location) GetAccuracy() float64 {
+	if x != nil && x.Accuracy != nil {
+		return *x.Accuracy
+	}
+	return 0
+}
+
+func (x *Location) GetSatellites() int32 {
+	if x != nil && x.Satellites != nil {
+		return *x.Satellites
+	}
+	return 0
+}
+
+func (x *Location) GetIgnition() bool {
+	if x != nil && x.Ignition != nil {
+		return *x.Ignition
+	}
+	return false
+}
+
+func (x *Location) GetOdometer() float64 {
+	if x != nil && x.Odometer != nil {
+		return *x.Odometer
+	}
+	return 0
+}
+
+func (x *Location) GetPoints() []*Location {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+var File_proto_location_proto protoreflect.FileDescriptor
+
+const file_proto_location_proto_rawDesc = "" +
+	"\n" +
+	"\x14proto/location.proto\x12\bfleet.v1\"\xe9\x03\n" +
+	"\bLocation\x12\x1d\n" +
+	"\n" +
+	"vehicle_id\x18\x01 \x01(\tR\tvehicleId\x12\x1a\n" +
+	"\blatitude\x18\x02 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x03 \x01(\x01R\tlongitude\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x12\x19\n" +
+	"\x05speed\x18\x05 \x01(\x01H\x00R\x05speed\x88\x01\x01\x12\x1d\n" +
+	"\aheading\x18\x06 \x01(\x01H\x01R\aheading\x88\x01\x01\x12\x1f\n" +
+	"\baltitude\x18\a \x01(\x01H\x02R\baltitude\x88\x01\x01\x12\x1f\n" +
+	"\baccuracy\x18\b \x01(\x01H\x03R\baccuracy\x88\x01\x01\x12#\n" +
+	"\n" +
+	"satellites\x18\t \x01(\x05H\x04R\n" +
+	"satellites\x88\x01\x01\x12\x1f\n" +
+	"\bignition\x18\n" +
+	" \x01(\bH\x05R\bignition\x88\x01\x01\x12\x1f\n" +
+	"\bodometer\x18\v \x01(\x01H\x06R\bodometer\x88\x01\x01\x12*\n" +
+	"\x06points\x18\f \x03(\v2\x12.fleet.v1.LocationR\x06pointsB\b\n" +
+	"\x06_speedB\n" +
+	"\n" +
+	"\b_headingB\v\n" +
+	"\t_altitudeB\v\n" +
+	"\t_accuracyB\r\n" +
+	"\v_satellitesB\v\n" +
+	"\t_ignitionB\v\n" +
+	"\t_odometerB,Z*github.com/nandanugg/tj-test/proto/fleetv1b\x06proto3"
+
+var (
+	file_proto_location_proto_rawDescOnce sync.Once
+	file_proto_location_proto_rawDescData []byte
+)
+
+func file_proto_location_proto_rawDescGZIP() []byte {
+	file_proto_location_proto_rawDescOnce.Do(func() {
+		file_proto_location_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_location_proto_rawDesc), len(file_proto_location_proto_rawDesc)))
+	})
+	return file_proto_location_proto_rawDescData
+}
+
+var file_proto_location_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_proto_location_proto_goTypes = []any{
+	(*Location)(nil), // 0: fleet.v1.Location
+}
+var file_proto_location_proto_depIdxs = []int32{
+	0, // 0: fleet.v1.Location.points:type_name -> fleet.v1.Location
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_proto_location_proto_init() }
+func file_proto_location_proto_init() {
+	if File_proto_location_proto != nil {
+		return
+	}
+	file_proto_location_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_location_proto_rawDesc), len(file_proto_location_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_proto_location_proto_goTypes,
+		DependencyIndexes: file_proto_location_proto_depIdxs,
+		MessageInfos:      file_proto_location_proto_msgTypes,
+	}.Build()
+	File_proto_location_proto = out.File
+	file_proto_location_proto_goTypes = nil
+	file_proto_location_proto_depIdxs = nil
+}